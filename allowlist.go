@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+// allowlistActivity is the activity name of the actions of the allowlist.
+const allowlistActivity = "Allowlist"
+
+// allowlistPolicy restricts the accounts of Users to the programs matching
+// Programs during Periods, e.g. to school apps during homework time, every
+// other process of these accounts being killed.
+type allowlistPolicy struct {
+	Users    []string                      `json:"users"`
+	Periods  map[time.Weekday][]timePeriod `json:"periods"`
+	Programs []string                      `json:"programs"`
+}
+
+func (p *allowlistPolicy) activeAt(now time.Time) bool {
+	return periodsActiveAt(p.Periods, now)
+}
+
+// periodsActiveAt tells whether now falls in one of periods, including the
+// ones of the day before crossing midnight.
+func periodsActiveAt(periods map[time.Weekday][]timePeriod, now time.Time) bool {
+	dayTime := now.Hour()*100 + now.Minute()
+	today, _ := splitAtMidnight(periods[now.Weekday()])
+	_, afterMidnight := splitAtMidnight(periods[(now.Weekday()+6)%7])
+	for _, period := range append(today, afterMidnight...) {
+		if dayTime >= period.Begin && dayTime < period.End {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *allowlistPolicy) restricts(user string) bool {
+	if user == "" {
+		return false
+	}
+	for _, account := range p.Users {
+		if accountMatches(user, account) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *allowlistPolicy) allows(path string) bool {
+	for _, pattern := range p.Programs {
+		if regex, err := regexp.Compile(pattern); err == nil && regex.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *allowlistPolicy) check() error {
+	for _, pattern := range p.Programs {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid allowlist pattern %q : %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+// controlAllowlist returns the action killing the processes of the
+// restricted accounts not allowed by the allowlist at now.
+func (c *dadController) controlAllowlist(processes []runningProcess, now time.Time) []enforcementAction {
+	if c.Allowlist == nil || !c.Allowlist.activeAt(now) {
+		return nil
+	}
+
+	var denied []runningProcess
+	for _, p := range processes {
+		if p.Pid == os.Getpid() || !c.Allowlist.restricts(p.User) || c.Allowlist.allows(p.Path) || c.isExempt(p, now) || c.isSuspended(p) {
+			continue
+		}
+		denied = append(denied, p)
+	}
+	if len(denied) == 0 {
+		return nil
+	}
+	fmt.Fprintf(logOutput, "/!\\ %d processes outside of the allowlist\n", len(denied))
+	return []enforcementAction{{Activity: allowlistActivity, Processes: denied, Action: actionKill, Reason: "Only allowed programs may run during this time range"}}
+}
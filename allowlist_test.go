@@ -0,0 +1,44 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAllowlistKillsOtherProgramsOfRestrictedAccounts(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 17, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenARunningProcessOfAccount("C:\\Office\\winword.exe", 1, `HOME-PC\Tom`).
+		GivenARunningProcessOfAccount("C:\\Discord.exe", 2, `HOME-PC\Tom`).
+		GivenARunningProcessOfAccount("C:\\Discord.exe", 3, `HOME-PC\Dad`).
+		GivenARunningProcess("C:\\Windows\\System32\\svchost.exe", 4)
+	ctx.controller.Allowlist = &allowlistPolicy{
+		Users:    []string{"tom"},
+		Periods:  map[time.Weekday][]timePeriod{time.Monday: {{Begin: 1630, End: 1800}}},
+		Programs: []string{`\\Office\\`},
+	}
+
+	ctx.WhenScanHappens()
+	if !reflect.DeepEqual(ctx.killedProcesses, []string{"2|C:\\Discord.exe"}) {
+		t.Errorf("only the programs of Tom outside of the allowlist should be killed, got %q", ctx.killedProcesses)
+	}
+}
+
+func TestAllowlistOnlyAppliesDuringItsPeriods(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 19, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenARunningProcessOfAccount("C:\\Discord.exe", 2, "tom")
+	ctx.controller.Allowlist = &allowlistPolicy{
+		Users:    []string{"tom"},
+		Periods:  map[time.Weekday][]timePeriod{time.Monday: {{Begin: 1630, End: 1800}}},
+		Programs: []string{`\\Office\\`},
+	}
+
+	ctx.WhenScanHappens()
+	if len(ctx.killedProcesses) != 0 {
+		t.Errorf("no process should be killed outside of the allowlist periods, got %q", ctx.killedProcesses)
+	}
+}
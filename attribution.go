@@ -0,0 +1,66 @@
+package main
+
+// Modes of attribution of the time spent in a process matching several
+// activities: each activity is credited the full interval, the interval is
+// split evenly between them or split according to the Weight of their rules.
+const (
+	sharedProcessFull     = "full"
+	sharedProcessSplit    = "split"
+	sharedProcessWeighted = "weighted"
+)
+
+func (a *activityRule) weight() float64 {
+	if a == nil || a.Weight <= 0 {
+		return 1
+	}
+	return a.Weight
+}
+
+// attributionShares returns, for each activity of rp, the share of the
+// sampling interval it is credited for the processes of user. An activity
+// with at least one process it does not share is credited in full.
+func (c *dadController) attributionShares(rp map[string][]runningProcess, user string) map[string]float64 {
+	shares := make(map[string]float64)
+
+	// activities each process of user is counted in
+	owners := make(map[string][]string)
+	for activity, processes := range rp {
+		for _, p := range processes {
+			if p.UserID == user {
+				owners[processKey(p)] = append(owners[processKey(p)], activity)
+			}
+		}
+	}
+
+	for activity, processes := range rp {
+		for _, p := range processes {
+			if p.UserID != user {
+				continue
+			}
+			share := c.shareOf(user, activity, owners[processKey(p)])
+			if share > shares[activity] {
+				shares[activity] = share
+			}
+		}
+	}
+	return shares
+}
+
+func (c *dadController) shareOf(user string, activity string, owners []string) float64 {
+	if len(owners) <= 1 {
+		return 1
+	}
+
+	switch c.SharedProcessAttribution {
+	case sharedProcessSplit:
+		return 1 / float64(len(owners))
+	case sharedProcessWeighted:
+		var total float64
+		for _, owner := range owners {
+			total += c.ruleOf(user, owner).weight()
+		}
+		return c.ruleOf(user, activity).weight() / total
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// auditEntry records a change made to the controller through its API.
+type auditEntry struct {
+	Time    time.Time `json:"time"`
+	Action  string    `json:"action"`
+	Details string    `json:"details,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Remote  string    `json:"remote,omitempty"`
+}
+
+func (c *dadController) setAuditLog(path string, policy rotationPolicy) {
+	if c.auditFile != nil && path == c.auditFile.path && policy == c.auditFile.policy {
+		return
+	}
+
+	if c.auditFile != nil {
+		c.auditFile.Close()
+		c.auditFile = nil
+	}
+	if path == "" {
+		return
+	}
+
+	auditFile, err := newRotatingFile(path, policy, time.Now)
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to open audit log : ", err)
+		return
+	}
+	c.auditFile = auditFile
+}
+
+// audit logs e and appends it as a JSON line to the audit log, if any.
+func (c *dadController) audit(e auditEntry) {
+	fmt.Fprintf(logOutput, "[Audit] %s %s (reason: %q)\n", e.Action, e.Details, e.Reason)
+	message := strings.TrimSpace(e.Action + " " + e.Details)
+	if e.Reason != "" {
+		message += " : " + e.Reason
+	}
+	c.recordEvent(message)
+	if c.auditFile == nil {
+		return
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to serialize audit entry : ", err)
+		return
+	}
+	if _, err := c.auditFile.Write(append(data, '\n')); err != nil {
+		fmt.Fprintln(logOutput, "Failure to write audit log : ", err)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// defaultStateBackups is the number of copies of the state file kept by
+// default, one per day.
+const defaultStateBackups = 7
+
+// stateBackuper is implemented by the state stores able to keep copies of
+// the state.
+type stateBackuper interface {
+	// Backup copies the saved state, keeping the given number of copies
+	Backup(copies int) error
+}
+
+func (c *dadController) stateBackups() int {
+	if c.StateBackups == 0 {
+		return defaultStateBackups
+	}
+	return c.StateBackups
+}
+
+// backUpState copies the state saved at the end of the previous day, when
+// the state store can and backups are not disabled.
+func (c *dadController) backUpState() {
+	b, ok := c.stateStore().(stateBackuper)
+	if !ok || c.stateBackups() < 0 {
+		return
+	}
+	if err := b.Backup(c.stateBackups()); err != nil {
+		fmt.Fprintln(logOutput, "Failure to back up state file : ", err)
+	}
+}
+
+func (s *fileStateStore) backupFile(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+// Backup copies the state file to path.1, shifting the older copies to
+// path.2, path.3, ... and dropping those above copies.
+func (s *fileStateStore) Backup(copies int) error {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	os.Remove(s.backupFile(copies))
+	for i := copies - 1; i >= 1; i-- {
+		if fileExists(s.backupFile(i)) {
+			if err := os.Rename(s.backupFile(i), s.backupFile(i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return replaceFile(s.backupFile(1), data)
+}
+
+// loadBackup returns the state of the most recent copy of the state file
+// which can be read, nil when there is none.
+func (s *fileStateStore) loadBackup() []byte {
+	for i := 1; fileExists(s.backupFile(i)); i++ {
+		data, err := ioutil.ReadFile(s.backupFile(i))
+		if err == nil {
+			data, err = readState(data)
+		}
+		if err != nil {
+			fmt.Fprintf(logOutput, "Failure to read state backup %s : %s\n", s.backupFile(i), err)
+			continue
+		}
+		fmt.Fprintf(logOutput, "Restoring the state from %s\n", s.backupFile(i))
+		return data
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateIsBackedUpOnDayChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAnActivityDuration("GTA", time.Duration(15)*time.Minute)
+	ctx.controller.StateBackups = 2
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+	ctx.controller.dumpState()
+	for i := 0; i < 3; i++ {
+		ctx.WhenDayChanges()
+		ctx.controller.dumpState()
+	}
+	for _, name := range []string{"dad-controller.state.1", "dad-controller.state.2"} {
+		if !fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s missing", name)
+		}
+	}
+	if fileExists(filepath.Join(dir, "dad-controller.state.3")) {
+		t.Error("more backups kept than configured")
+	}
+}
+
+func TestStateIsRestoredFromItsBackupWhenDeleted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAnActivityDuration("GTA", time.Duration(15)*time.Minute)
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+	ctx.controller.dumpState()
+	ctx.controller.backUpState()
+	os.Remove(ctx.controller.stateFile)
+
+	restarted := newDadController(time.Duration(1)*time.Minute, ctx.controller.GetTime)
+	restarted.stateFile = ctx.controller.stateFile
+	restarted.reloadStateIfExist()
+	if d := restarted.GetActivityDuration("GTA"); d != time.Duration(15)*time.Minute {
+		t.Errorf("GTA duration is %s after restoring the backup", d)
+	}
+}
+
+func TestStateIsRestoredFromItsBackupWhenTruncated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAnActivityDuration("GTA", time.Duration(15)*time.Minute)
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+	ctx.controller.dumpState()
+	ctx.controller.backUpState()
+
+	// cut before its checksum line, as a state file written before the
+	// checksum was added
+	data, err := ioutil.ReadFile(ctx.controller.stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(ctx.controller.stateFile, data[:len(data)/2], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newDadController(time.Duration(1)*time.Minute, ctx.controller.GetTime)
+	restarted.stateFile = ctx.controller.stateFile
+	restarted.reloadStateIfExist()
+	if d := restarted.GetActivityDuration("GTA"); d != time.Duration(15)*time.Minute {
+		t.Errorf("GTA duration is %s after restoring the backup", d)
+	}
+}
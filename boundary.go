@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// timer is what the controller needs of a *time.Timer, for tests to fake it.
+type timer interface {
+	Stop() bool
+}
+
+func afterFunc(d time.Duration, f func()) timer {
+	return time.AfterFunc(d, f)
+}
+
+// NextTransition returns the next time after now, on the same day, at which
+// a period of the schedule of activity for the processes of user begins or
+// ends.
+func (c *dadController) NextTransition(user string, activity string, now time.Time) (time.Time, bool) {
+	resolved := c.effectiveScheduleOf(user, activity, now)
+	if !resolved.Allowed {
+		return time.Time{}, false
+	}
+
+	periods := append(append([]timePeriod(nil), resolved.AllowedPeriods...), resolved.DenyPeriods...)
+	if resolved.SpendableWindow != nil {
+		periods = append(periods, *resolved.SpendableWindow)
+	}
+
+	var next time.Time
+	for _, p := range periods {
+		for _, hhmm := range []int{p.Begin, p.End} {
+			t := timeOfDay(now, hhmm)
+			if t.After(now) && (next.IsZero() || t.Before(next)) {
+				next = t
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// scheduleBoundaryCheck arms a one-shot timer re-evaluating the running
+// activities at the first transition of their schedules happening before
+// the next scan, so that a period ending at 21:00 is enforced at 21:00
+// rather than up to one sampling interval later. When the time left on one
+// of them runs out first, the timer scans then instead, crediting the time
+// elapsed since the last scan, so that a tight budget is enforced to the
+// second.
+//
+// A timer stopped too late to prevent its callback from running is told
+// apart by the generation it was armed at, so that it does nothing.
+func (c *dadController) scheduleBoundaryCheck(now time.Time) {
+	if c.boundaryTimer != nil {
+		c.boundaryTimer.Stop()
+		c.boundaryTimer = nil
+	}
+	c.boundaryGeneration++
+	generation := c.boundaryGeneration
+
+	nextScan := now.Add(time.Duration(c.SamplingInterval))
+	var boundary time.Time
+	for activity, rp := range c.running {
+		for _, p := range rp {
+			if t, found := c.NextTransition(p.UserID, activity, now); found && t.Before(nextScan) && (boundary.IsZero() || t.Before(boundary)) {
+				boundary = t
+			}
+		}
+	}
+	exhausted := !c.exhaustion.IsZero() && c.exhaustion.After(now) && c.exhaustion.Before(nextScan) && (boundary.IsZero() || c.exhaustion.Before(boundary))
+	if exhausted {
+		boundary = c.exhaustion
+	}
+	if boundary.IsZero() {
+		return
+	}
+
+	if exhausted {
+		fmt.Fprintf(logOutput, "Scanning when the time left runs out at %s\n", boundary.Format("15:04:05"))
+		c.boundaryTimer = c.AfterFunc(boundary.Sub(now), func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if generation != c.boundaryGeneration {
+				return
+			}
+			c.scannedAt = time.Now()
+			c.scan()
+			c.dumpState()
+		})
+		return
+	}
+
+	fmt.Fprintf(logOutput, "Re-evaluating running activities at %s\n", boundary.Format("15:04:05"))
+	c.boundaryTimer = c.AfterFunc(boundary.Sub(now), func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if generation != c.boundaryGeneration {
+			return
+		}
+		processes, err := c.listProcesses()
+		if err != nil {
+			fmt.Fprintln(logOutput, "Failure to list running processes : ", err)
+			return
+		}
+		c.enforceNow(processes)
+	})
+}
+
+// enforceNow decides and applies what to do with processes right now,
+// without crediting any time to the activities.
+func (c *dadController) enforceNow(processes []runningProcess) {
+	now := c.now()
+	c.running = c.getRunningProcessesPerActivity(processes)
+	c.applyActions(c.controlActivities(c.running, now))
+	c.scheduleBoundaryCheck(now)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// periodCounters are the time spent on each activity since the beginning of
+// a week or a month, per user identifier, the empty one counting the processes whose
+// owner is unknown.
+type periodCounters struct {
+	Since     time.Time                      `json:"since"`
+	Durations map[string]map[string]duration `json:"durations,omitempty"`
+}
+
+// startAt resets the counters when the period they count began before start.
+func (p *periodCounters) startAt(start time.Time) {
+	if !p.Since.Equal(start) {
+		p.Since = start
+		p.Durations = nil
+	}
+}
+
+func (p *periodCounters) add(user string, activity string, d duration) {
+	if p.Durations == nil {
+		p.Durations = make(map[string]map[string]duration)
+	}
+	if p.Durations[user] == nil {
+		p.Durations[user] = make(map[string]duration)
+	}
+	p.Durations[user][activity] += d
+}
+
+// usedSince returns the time spent by user on activity during the period
+// beginning at start.
+func (p *periodCounters) usedSince(start time.Time, user string, activity string) time.Duration {
+	if !p.Since.Equal(start) {
+		return 0
+	}
+	return time.Duration(p.Durations[user][activity])
+}
+
+// parseWeekday parses the name of a day, or its first three letters at
+// least, whatever the case.
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(name)
+	if len(name) >= 3 {
+		for d, n := range weekdayNames {
+			if strings.HasPrefix(n, name) {
+				return time.Weekday(d), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (c *dadController) weekStartDay() time.Weekday {
+	if d, ok := parseWeekday(c.WeekStart); ok {
+		return d
+	}
+	return time.Monday
+}
+
+// weekStart returns the beginning of the week of now.
+func (c *dadController) weekStart(now time.Time) time.Time {
+	offset := (int(now.Weekday()) - int(c.weekStartDay()) + 7) % 7
+	return time.Date(now.Year(), now.Month(), now.Day()-offset, 0, 0, 0, 0, now.Location())
+}
+
+// monthStart returns the beginning of the month of now.
+func monthStart(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+}
+
+// addPeriodUsage sets the time spent in ctx on the activity this week and
+// this month.
+func (c *dadController) addPeriodUsage(ctx *decisionContext) {
+	ctx.WeekUsed = c.WeeklyDuration.usedSince(c.weekStart(ctx.Now), ctx.User, ctx.Activity)
+	ctx.MonthUsed = c.MonthlyDuration.usedSince(monthStart(ctx.Now), ctx.User, ctx.Activity)
+}
+
+func weeklyDurationPolicy(ctx decisionContext) (action, string) {
+	if ctx.Rule != nil && ctx.Rule.MaxWeeklyDuration > 0 && ctx.WeekUsed > time.Duration(ctx.Rule.MaxWeeklyDuration) {
+		return actionKill, "Activity duration above threshold for this week"
+	}
+	return actionNone, ""
+}
+
+func monthlyDurationPolicy(ctx decisionContext) (action, string) {
+	if ctx.Rule != nil && ctx.Rule.MaxMonthlyDuration > 0 && ctx.MonthUsed > time.Duration(ctx.Rule.MaxMonthlyDuration) {
+		return actionKill, "Activity duration above threshold for this month"
+	}
+	return actionNone, ""
+}
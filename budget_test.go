@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeeklyDurationIsEnforced(t *testing.T) {
+	monday := time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)
+	ctx := NewTest(t).
+		GivenTimeIs(monday).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(2)*time.Hour).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].MaxWeeklyDuration = duration(time.Duration(10) * time.Hour)
+	ctx.controller.WeeklyDuration.startAt(time.Date(2024, time.October, 14, 0, 0, 0, 0, time.Local))
+	ctx.controller.WeeklyDuration.add("", "GTA", duration(time.Duration(10)*time.Hour))
+
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this week")
+}
+
+func TestWeeklyDurationIsResetAtTheBeginningOfTheWeek(t *testing.T) {
+	monday := time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)
+	ctx := NewTest(t).
+		GivenTimeIs(monday).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(2)*time.Hour).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].MaxWeeklyDuration = duration(time.Duration(10) * time.Hour)
+	ctx.controller.WeeklyDuration.startAt(time.Date(2024, time.October, 7, 0, 0, 0, 0, time.Local))
+	ctx.controller.WeeklyDuration.add("", "GTA", duration(time.Duration(10)*time.Hour))
+
+	ctx.WhenScanHappens()
+	if len(ctx.killedProcesses) != 0 {
+		t.Errorf("the time spent last week should not count, killed %q", ctx.killedProcesses)
+	}
+	if used := ctx.controller.WeeklyDuration.usedSince(ctx.controller.weekStart(monday), "", "GTA"); used != time.Minute {
+		t.Errorf("time spent this week is %s", used)
+	}
+}
+
+func TestWeekStartIsConfigurable(t *testing.T) {
+	ctrl := newDadController(time.Duration(1)*time.Minute, time.Now)
+	monday := time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)
+	if start := ctrl.weekStart(monday); !start.Equal(time.Date(2024, time.October, 14, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("week should start on monday by default, starts %s", start)
+	}
+	ctrl.WeekStart = "sunday"
+	if start := ctrl.weekStart(monday); !start.Equal(time.Date(2024, time.October, 13, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("week should start on sunday, starts %s", start)
+	}
+}
+
+func TestMonthlyDurationIsEnforcedUntilTheEndOfTheMonth(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 31, 23, 58, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Netflix", "Netflix.exe", time.Duration(3)*time.Hour).
+		GivenARunningProcess("C:\\Netflix.exe", 1)
+	ctx.controller.Activities[0].MaxMonthlyDuration = duration(time.Duration(20) * time.Hour)
+	ctx.controller.MonthlyDuration.startAt(time.Date(2024, time.October, 1, 0, 0, 0, 0, time.Local))
+	ctx.controller.MonthlyDuration.add("", "Netflix", duration(time.Duration(20)*time.Hour))
+
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("Netflix", 1, "C:\\Netflix.exe", "Activity duration above threshold for this month")
+
+	ctx.killedProcesses = nil
+	ctx.WhenScanHappens()
+	if len(ctx.killedProcesses) != 0 {
+		t.Errorf("the time spent last month should not count, killed %q", ctx.killedProcesses)
+	}
+	if used := ctx.controller.MonthlyDuration.usedSince(time.Date(2024, time.November, 1, 0, 0, 0, 0, time.Local), "", "Netflix"); used != time.Minute {
+		t.Errorf("time spent this month is %s", used)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// closeThenKill asks the processes of activity to close, giving them
+// timeout to exit on their own, for instance to save a game, before they
+// are killed. Processes already asked to close are left alone until then.
+func (c *dadController) closeThenKill(activity string, rp []runningProcess, timeout time.Duration) {
+	if c.closing == nil {
+		c.closing = make(map[string]bool)
+	}
+
+	var closed []runningProcess
+	for _, p := range rp {
+		key := processKey(p)
+		if c.closing[key] {
+			continue
+		}
+		fmt.Fprintf(logOutput, "Closing process %d, %s\n", p.Pid, p.Path)
+		if err := c.Processes.Close(p); err != nil {
+			fmt.Fprintf(logOutput, "Failure to close process %d : %s\n", p.Pid, err)
+		}
+		c.closing[key] = true
+		closed = append(closed, p)
+	}
+	if len(closed) == 0 {
+		return
+	}
+
+	c.AfterFunc(timeout, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.killUnclosed(activity, closed)
+	})
+}
+
+// killUnclosed kills the processes still running once the time given to
+// them to close is over.
+func (c *dadController) killUnclosed(activity string, closed []runningProcess) {
+	for _, p := range closed {
+		delete(c.closing, processKey(p))
+	}
+
+	processes, err := c.listProcesses()
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to list running processes : ", err)
+		return
+	}
+	for _, p := range closed {
+		running, found := findProcess(processes, p.Pid)
+		if !found || running.Path != p.Path {
+			continue
+		}
+		fmt.Fprintf(logOutput, "Process %d (%s) of activity %s did not close, killing it\n", p.Pid, p.Path, activity)
+		if err := c.Processes.Kill(p, "SIGKILL"); err != nil {
+			fmt.Fprintf(logOutput, "Failure to kill process %d : %s\n", p.Pid, err)
+		}
+	}
+}
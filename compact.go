@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Compact schedules are an alternative to the weekday map of an activity
+// rule, for instance:
+//
+//	"allow": "mon-fri 16:00-18:00 max 1h; sat,sun 10:00-12:00 14:00-20:00 max 3h"
+//
+// Each entry separated by ';' lists days (single days, ranges or both
+// separated by ','), one or more HH:MM-HH:MM periods and the maximum
+// duration per day.
+
+type (
+	compactScheduleError struct {
+		Pos int
+		Msg string
+	}
+
+	compactToken struct {
+		pos  int
+		text string
+	}
+)
+
+var weekdayNames = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+func (e *compactScheduleError) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+func compactErrorf(pos int, format string, args ...interface{}) error {
+	return &compactScheduleError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// tokenizeCompactSchedule splits spec into words and ';' separators,
+// remembering the 1-based position of each token.
+func tokenizeCompactSchedule(spec string) []compactToken {
+	var tokens []compactToken
+	start := -1
+	for i, r := range spec {
+		if unicode.IsSpace(r) || r == ';' {
+			if start >= 0 {
+				tokens = append(tokens, compactToken{pos: start + 1, text: spec[start:i]})
+				start = -1
+			}
+			if r == ';' {
+				tokens = append(tokens, compactToken{pos: i + 1, text: ";"})
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, compactToken{pos: start + 1, text: spec[start:]})
+	}
+	return tokens
+}
+
+// parseCompactSchedule parses a compact schedule into per day schedules.
+func parseCompactSchedule(spec string) (map[time.Weekday]*schedule, error) {
+	schedules := make(map[time.Weekday]*schedule)
+	tokens := tokenizeCompactSchedule(spec)
+
+	for len(tokens) > 0 {
+		end := 0
+		for end < len(tokens) && tokens[end].text != ";" {
+			end++
+		}
+		entry := tokens[:end]
+		if end < len(tokens) {
+			tokens = tokens[end+1:]
+		} else {
+			tokens = nil
+		}
+		if len(entry) == 0 {
+			continue
+		}
+
+		days, err := parseCompactDays(entry[0])
+		if err != nil {
+			return nil, err
+		}
+
+		var periods []timePeriod
+		i := 1
+		for ; i < len(entry) && entry[i].text != "max"; i++ {
+			p, err := parseCompactPeriod(entry[i])
+			if err != nil {
+				return nil, err
+			}
+			periods = append(periods, p)
+		}
+		if len(periods) == 0 {
+			return nil, compactErrorf(entry[0].pos+len(entry[0].text), "expected a period such as 16:00-18:00 after %q", entry[0].text)
+		}
+		if i >= len(entry) {
+			last := entry[len(entry)-1]
+			return nil, compactErrorf(last.pos+len(last.text), "expected \"max <duration>\"")
+		}
+		if i+1 >= len(entry) {
+			return nil, compactErrorf(entry[i].pos+len(entry[i].text), "expected a duration after \"max\"")
+		}
+		maxDuration, err := time.ParseDuration(entry[i+1].text)
+		if err != nil || maxDuration < 0 {
+			return nil, compactErrorf(entry[i+1].pos, "invalid duration %q", entry[i+1].text)
+		}
+		if i+2 < len(entry) {
+			return nil, compactErrorf(entry[i+2].pos, "unexpected %q, entries must be separated by ';'", entry[i+2].text)
+		}
+
+		for _, d := range days {
+			if _, found := schedules[d]; found {
+				return nil, compactErrorf(entry[0].pos, "%s is scheduled more than once", d.String())
+			}
+			schedules[d] = &schedule{AllowedPeriods: append([]timePeriod(nil), periods...), MaxDuration: duration(maxDuration)}
+		}
+	}
+
+	if len(schedules) == 0 {
+		return nil, compactErrorf(1, "empty schedule")
+	}
+	return schedules, nil
+}
+
+func parseCompactWeekday(name string, pos int) (time.Weekday, error) {
+	if d, ok := parseWeekday(name); ok {
+		return d, nil
+	}
+	return 0, compactErrorf(pos, "unknown day %q", strings.ToLower(name))
+}
+
+// parseCompactDays parses "mon", "mon-fri", "sat,sun" or "mon-wed,fri".
+// Ranges may wrap around the week, e.g. "fri-mon".
+func parseCompactDays(tok compactToken) ([]time.Weekday, error) {
+	var days []time.Weekday
+	pos := tok.pos
+	for _, part := range strings.Split(tok.text, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		first, err := parseCompactWeekday(bounds[0], pos)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if len(bounds) == 2 {
+			last, err = parseCompactWeekday(bounds[1], pos+len(bounds[0])+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days = append(days, d)
+			if d == last {
+				break
+			}
+		}
+		pos += len(part) + 1
+	}
+	return days, nil
+}
+
+func parseCompactTime(text string, pos int) (int, error) {
+	parts := strings.SplitN(text, ":", 2)
+	if len(parts) != 2 {
+		return 0, compactErrorf(pos, "invalid time %q, expected HH:MM", text)
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil || hours < 0 || hours > 24 {
+		return 0, compactErrorf(pos, "invalid hours in %q", text)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || len(parts[1]) != 2 || minutes < 0 || minutes > 59 || (hours == 24 && minutes != 0) {
+		return 0, compactErrorf(pos+len(parts[0])+1, "invalid minutes in %q", text)
+	}
+	return hours*100 + minutes, nil
+}
+
+func parseCompactPeriod(tok compactToken) (timePeriod, error) {
+	bounds := strings.SplitN(tok.text, "-", 2)
+	if len(bounds) != 2 {
+		return timePeriod{}, compactErrorf(tok.pos, "invalid period %q, expected HH:MM-HH:MM", tok.text)
+	}
+	begin, err := parseCompactTime(bounds[0], tok.pos)
+	if err != nil {
+		return timePeriod{}, err
+	}
+	end, err := parseCompactTime(bounds[1], tok.pos+len(bounds[0])+1)
+	if err != nil {
+		return timePeriod{}, err
+	}
+	if begin == 2400 {
+		return timePeriod{}, compactErrorf(tok.pos, "a period cannot begin at 24:00")
+	}
+	// a period ending before it begins, e.g. 22:00-01:00, crosses midnight
+	if end == begin {
+		return timePeriod{}, compactErrorf(tok.pos, "period %q is empty", tok.text)
+	}
+	return timePeriod{Begin: begin, End: end}, nil
+}
+
+// expandAllow merges the compact schedule of the rule, if any, into its
+// weekday schedules.
+func (a *activityRule) expandAllow() error {
+	if a.Allow == "" {
+		return nil
+	}
+
+	schedules, err := parseCompactSchedule(a.Allow)
+	if err != nil {
+		return err
+	}
+	if a.AllowedSchedules == nil {
+		a.AllowedSchedules = make(map[time.Weekday]*schedule)
+	}
+	for day, s := range schedules {
+		existing := a.getOrCreateSchedule(day)
+		existing.AllowedPeriods = append(existing.AllowedPeriods, s.AllowedPeriods...)
+		existing.MaxDuration = s.MaxDuration
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCompactSchedule(t *testing.T) {
+	weekday := &schedule{AllowedPeriods: []timePeriod{{Begin: 1600, End: 1800}}, MaxDuration: duration(time.Hour)}
+	weekend := &schedule{AllowedPeriods: []timePeriod{{Begin: 1000, End: 1200}, {Begin: 1400, End: 2000}}, MaxDuration: duration(3 * time.Hour)}
+
+	tests := []struct {
+		spec     string
+		expected map[time.Weekday]*schedule
+	}{
+		{
+			spec: "mon-fri 16:00-18:00 max 1h; sat-sun 10:00-12:00 14:00-20:00 max 3h",
+			expected: map[time.Weekday]*schedule{
+				time.Monday: weekday, time.Tuesday: weekday, time.Wednesday: weekday, time.Thursday: weekday, time.Friday: weekday,
+				time.Saturday: weekend, time.Sunday: weekend,
+			},
+		},
+		{
+			spec: "Wednesday,sat 10:00-12:00 14:00-20:00 max 3h;",
+			expected: map[time.Weekday]*schedule{
+				time.Wednesday: weekend, time.Saturday: weekend,
+			},
+		},
+		{
+			spec: "fri-mon 16:00-18:00 max 1h",
+			expected: map[time.Weekday]*schedule{
+				time.Friday: weekday, time.Saturday: weekday, time.Sunday: weekday, time.Monday: weekday,
+			},
+		},
+		{
+			spec: "sun 00:00-24:00 max 2h30m",
+			expected: map[time.Weekday]*schedule{
+				time.Sunday: {AllowedPeriods: []timePeriod{{Begin: 0, End: 2400}}, MaxDuration: duration(150 * time.Minute)},
+			},
+		},
+		{
+			spec: "sat 22:00-01:00 max 1h",
+			expected: map[time.Weekday]*schedule{
+				time.Saturday: {AllowedPeriods: []timePeriod{{Begin: 2200, End: 100}}, MaxDuration: duration(time.Hour)},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		schedules, err := parseCompactSchedule(test.spec)
+		if err != nil {
+			t.Errorf("%q: %s", test.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(schedules, test.expected) {
+			t.Errorf("%q parsed as %v", test.spec, schedules)
+		}
+	}
+}
+
+func TestParseCompactScheduleErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		err  string
+	}{
+		{"", "position 1: empty schedule"},
+		{"mon-fry 16:00-18:00 max 1h", `position 5: unknown day "fry"`},
+		{"mon 16:00-18:75 max 1h", `position 14: invalid minutes in "18:75"`},
+		{"mon 18:00-18:00 max 1h", `position 5: period "18:00-18:00" is empty`},
+		{"mon 16:00-18:00", `position 16: expected "max <duration>"`},
+		{"mon 16:00-18:00 max", `position 20: expected a duration after "max"`},
+		{"mon 16:00-18:00 max 1 hour", `position 21: invalid duration "1"`},
+		{"mon 16:00-18:00 max 1h sat 10:00-12:00 max 1h", `position 24: unexpected "sat", entries must be separated by ';'`},
+		{"mon max 1h", `position 4: expected a period such as 16:00-18:00 after "mon"`},
+		{"mon-fri 16:00-18:00 max 1h; fri 10:00-12:00 max 1h", "position 29: Friday is scheduled more than once"},
+	}
+
+	for _, test := range tests {
+		_, err := parseCompactSchedule(test.spec)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: got error %v (expected %s)", test.spec, err, test.err)
+		}
+	}
+}
+
+func TestCompactScheduleIsMergedIntoActivityRule(t *testing.T) {
+	a := &activityRule{Name: "GTA", Allow: "sat-sun 10:00-20:00 max 3h"}
+	if err := a.expandAllow(); err != nil {
+		t.Fatal(err)
+	}
+	if len(a.AllowedSchedules) != 2 || a.AllowedSchedules[time.Saturday].MaxDuration != duration(3*time.Hour) {
+		t.Errorf("unexpected schedules %v", a.AllowedSchedules)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fragmentsDir returns the directory of the rule files of the configuration,
+// relative to dir, the directory of the configuration file.
+func (cfg *config) fragmentsDir(dir string) string {
+	if cfg.ConfigDir == "" || filepath.IsAbs(cfg.ConfigDir) {
+		return cfg.ConfigDir
+	}
+	return filepath.Join(dir, cfg.ConfigDir)
+}
+
+// fragments returns the rule files of the configuration, by name.
+func (cfg *config) fragments(dir string) ([]string, error) {
+	if cfg.ConfigDir == "" {
+		return nil, nil
+	}
+	return filepath.Glob(filepath.Join(cfg.fragmentsDir(dir), "*.json"))
+}
+
+// mergeFragments appends to the rules the rule held by each rule file of the
+// configuration, in the order of their names, returning the first error.
+func (cfg *config) mergeFragments(dir string) error {
+	files, err := cfg.fragments(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, readErr := ioutil.ReadFile(file)
+		if readErr != nil {
+			if err == nil {
+				err = readErr
+			}
+			continue
+		}
+		var a activityRule
+		if jsonErr := json.Unmarshal(data, &a); jsonErr != nil {
+			if err == nil {
+				err = fmt.Errorf("%s : %s", filepath.Base(file), locateJSONError(data, jsonErr))
+			}
+			continue
+		}
+		a.source = filepath.Base(file)
+		cfg.Activities = append(cfg.Activities, &a)
+	}
+	return err
+}
+
+// configModTime returns when the configuration file, its signature, or its
+// rule files or their directory, were last modified.
+func (c *dadController) configModTime() (time.Time, error) {
+	stat, err := os.Stat(c.configFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	latest := stat.ModTime()
+	if stat, err := os.Stat(signatureFile(c.configFile)); err == nil && stat.ModTime().After(latest) {
+		latest = stat.ModTime()
+	}
+	if c.ConfigDir == "" {
+		return latest, nil
+	}
+	dir := c.fragmentsDir(filepath.Dir(c.configFile))
+	files, _ := c.fragments(filepath.Dir(c.configFile))
+	for _, path := range append([]string{dir}, files...) {
+		if stat, err := os.Stat(path); err == nil && stat.ModTime().After(latest) {
+			latest = stat.ModTime()
+		}
+	}
+	return latest, nil
+}
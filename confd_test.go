@@ -0,0 +1,84 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func givenAConfigurationWithRuleFiles(t *testing.T, fragments map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "rules.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "dad-controller.json")
+	if err := ioutil.WriteFile(configFile, []byte(`{"samplingInterval": "1h", "configDir": "rules.d", "rules": [{"name": "GTA", "programs": ["gta"]}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range fragments {
+		if err := ioutil.WriteFile(filepath.Join(dir, "rules.d", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return configFile, func() { os.RemoveAll(dir) }
+}
+
+func TestRuleFilesAreMergedInTheOrderOfTheirNames(t *testing.T) {
+	configFile, cleanup := givenAConfigurationWithRuleFiles(t, map[string]string{
+		"minecraft.json": `{"name": "Minecraft", "programs": ["minecraft"]}`,
+		"fortnite.json":  `{"name": "Fortnite", "programs": ["fortnite"]}`,
+		"README.txt":     `not a rule`,
+	})
+	defer cleanup()
+
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, a := range cfg.Activities {
+		names = append(names, a.Name)
+	}
+	if strings.Join(names, ",") != "GTA,Fortnite,Minecraft" {
+		t.Errorf("rules are %v", names)
+	}
+}
+
+func TestInvalidRuleFileIsReportedByName(t *testing.T) {
+	configFile, cleanup := givenAConfigurationWithRuleFiles(t, map[string]string{
+		"fortnite.json":  `{"name": "Fortnite", "programs": ["fortnite"],}`,
+		"minecraft.json": `{"name": "Minecraft", "cooldown": "-1h", "programs": ["minecraft"]}`,
+	})
+	defer cleanup()
+
+	_, err := loadConfig(configFile)
+	if err == nil || !strings.Contains(err.Error(), "fortnite.json : line 1") || !strings.Contains(err.Error(), "minecraft.json (Minecraft) : cooldown") {
+		t.Errorf("expected the rule files to be named, got %v", err)
+	}
+}
+
+func TestAddedRuleFileIsLoaded(t *testing.T) {
+	configFile, cleanup := givenAConfigurationWithRuleFiles(t, nil)
+	defer cleanup()
+	ctrl := newDadControllerWithConfigFile(configFile)
+
+	dir := filepath.Join(filepath.Dir(configFile), "rules.d")
+	if err := ioutil.WriteFile(filepath.Join(dir, "fortnite.json"), []byte(`{"name": "Fortnite", "programs": ["fortnite"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(dir, later, later); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.reloadConfIfNeeded()
+
+	if ctrl.findActivityRule("Fortnite") == nil {
+		t.Errorf("rule file added not loaded, rules are %v", ctrl.Activities)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// parseConfig parses a configuration file and resolves it into the
+// configuration the controller enforces. On error, the returned
+// configuration holds whatever could be parsed, and the error is a
+// configErrors when several problems were found.
+func parseConfig(data []byte) (*config, error) {
+	return parseConfigIn(data, ".")
+}
+
+// parseConfigIn parses the configuration file in dir, along with its rule
+// files.
+func parseConfigIn(data []byte, dir string) (*config, error) {
+	var cfg config
+	data, err := migrateConfig(data)
+	if err == nil {
+		if err = json.Unmarshal(data, &cfg); err != nil {
+			err = locateJSONError(data, err)
+		}
+		var secrets struct {
+			PIN string `json:"pin"`
+		}
+		json.Unmarshal(data, &secrets)
+		cfg.PIN = secrets.PIN
+	}
+	if fragmentsErr := cfg.mergeFragments(dir); fragmentsErr != nil && err == nil {
+		err = fragmentsErr
+	}
+
+	if templateErr := cfg.expandTemplates(); templateErr != nil && err == nil {
+		err = templateErr
+	}
+	for _, a := range cfg.allRules() {
+		if expandErr := a.expandAllow(); expandErr != nil {
+			if err == nil {
+				err = fmt.Errorf("invalid compact schedule for activity [%s] : %s", a.Name, expandErr)
+			}
+			continue
+		}
+		a.Allow = ""
+	}
+	for _, a := range cfg.allRules() {
+		if _, ok := parseAction(a.Action); a.Action != "" && !ok && err == nil {
+			err = fmt.Errorf("unknown action %q for activity [%s], expected warn, kill, suspend, lock or logoff", a.Action, a.Name)
+		}
+		if patternErr := a.compilePatterns(); patternErr != nil && err == nil {
+			err = patternErr
+		}
+		if validityErr := a.checkValidity(); validityErr != nil && err == nil {
+			err = validityErr
+		}
+	}
+	if cfg.Holidays != nil {
+		if holidaysErr := cfg.Holidays.load(); holidaysErr != nil && err == nil {
+			err = holidaysErr
+		}
+	}
+	if cfg.Allowlist != nil {
+		if allowlistErr := cfg.Allowlist.check(); allowlistErr != nil && err == nil {
+			err = allowlistErr
+		}
+	}
+	if cfg.Curfew != nil {
+		if curfewErr := cfg.Curfew.check(); curfewErr != nil && err == nil {
+			err = curfewErr
+		}
+	}
+	if _, ok := parseWeekday(cfg.WeekStart); cfg.WeekStart != "" && !ok && err == nil {
+		err = fmt.Errorf("unknown weekStart %q, expected a day such as monday", cfg.WeekStart)
+	}
+	if timeZoneErr := cfg.loadTimeZone(); timeZoneErr != nil && err == nil {
+		err = timeZoneErr
+	}
+	if poolErr := cfg.checkPools(); poolErr != nil && err == nil {
+		err = poolErr
+	}
+	cfg.SamplingInterval = duration(clampSamplingInterval(time.Duration(cfg.SamplingInterval)))
+
+	switch cfg.PeriodOverlap {
+	case "", periodOverlapFirstMatch, periodOverlapMostGenerous, periodOverlapMostRestrictive:
+	default:
+		if err == nil {
+			err = fmt.Errorf("unknown periodOverlap %q, expected %s, %s or %s", cfg.PeriodOverlap, periodOverlapFirstMatch, periodOverlapMostGenerous, periodOverlapMostRestrictive)
+		}
+		cfg.PeriodOverlap = ""
+	}
+
+	switch cfg.SharedProcessAttribution {
+	case "", sharedProcessFull, sharedProcessSplit, sharedProcessWeighted:
+	default:
+		if err == nil {
+			err = fmt.Errorf("unknown sharedProcessAttribution %q, expected %s, %s or %s", cfg.SharedProcessAttribution, sharedProcessFull, sharedProcessSplit, sharedProcessWeighted)
+		}
+		cfg.SharedProcessAttribution = ""
+	}
+
+	switch cfg.RuleMatching {
+	case "", ruleMatchingAll, ruleMatchingFirst:
+	default:
+		if err == nil {
+			err = fmt.Errorf("unknown ruleMatching %q, expected %s or %s", cfg.RuleMatching, ruleMatchingAll, ruleMatchingFirst)
+		}
+		cfg.RuleMatching = ""
+	}
+
+	switch cfg.ResumePolicy {
+	case "", resumeCap, resumeDiscard:
+	default:
+		if err == nil {
+			err = fmt.Errorf("unknown resumePolicy %q, expected %s or %s", cfg.ResumePolicy, resumeCap, resumeDiscard)
+		}
+		cfg.ResumePolicy = ""
+	}
+
+	if _, providerErr := newProcessProvider(cfg.ProcessProvider); providerErr != nil {
+		if err == nil {
+			err = providerErr
+		}
+		cfg.ProcessProvider = ""
+	}
+
+	if problems := cfg.validate(); len(problems) > 0 {
+		if err != nil {
+			problems = append([]error{err}, problems...)
+		}
+		err = configErrors(problems)
+	}
+	return &cfg, err
+}
+
+func loadConfig(path string) (*config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseConfigIn(data, filepath.Dir(path))
+}
+
+// dumpConfig writes the resolved configuration of the file at path as
+// indented JSON.
+func dumpConfig(w io.Writer, path string) error {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// validateConfig checks the configuration file at path the way the
+// controller loads it, writing every problem found, and tells whether it is
+// valid.
+func validateConfig(w io.Writer, path string) bool {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		fmt.Fprintf(w, "%s is invalid :\n", path)
+		for _, problem := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(w, "  %s\n", problem)
+		}
+		return false
+	}
+	fmt.Fprintf(w, "%s is valid, %d rules\n", path, len(cfg.allRules()))
+	return true
+}
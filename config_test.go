@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDumpConfigPrintsResolvedConfiguration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dad-controller.json")
+	err = ioutil.WriteFile(path, []byte(`{
+		"samplingInterval": "1s",
+		"rules": [
+			{
+				"name": "GTA",
+				"programs": ["GTA.exe"],
+				"allow": "sat-sun 10:00-12:00 max 90m",
+				"schedules": {"3": {"maxDuration": 3600000000000, "allowedPeriods": [{"begin": 1400, "end": 1600}]}}
+			}
+		]
+	}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := dumpConfig(&out, path); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{
+    "samplingInterval": "5s",
+    "rules": [
+        {
+            "name": "GTA",
+            "programs": [
+                "GTA.exe"
+            ],
+            "schedules": {
+                "0": {
+                    "allowedPeriods": [
+                        {
+                            "begin": "10:00",
+                            "end": "12:00"
+                        }
+                    ],
+                    "maxDuration": "1h30m0s"
+                },
+                "3": {
+                    "allowedPeriods": [
+                        {
+                            "begin": "14:00",
+                            "end": "16:00"
+                        }
+                    ],
+                    "maxDuration": "1h0m0s"
+                },
+                "6": {
+                    "allowedPeriods": [
+                        {
+                            "begin": "10:00",
+                            "end": "12:00"
+                        }
+                    ],
+                    "maxDuration": "1h30m0s"
+                }
+            }
+        }
+    ],
+    "logRotation": {
+        "maxSize": 0,
+        "maxAge": "0s",
+        "maxFiles": 0
+    }
+}
+`
+	if out.String() != expected {
+		t.Errorf("dumped configuration is\n%s", out.String())
+	}
+}
+
+func TestDumpConfigReportsInvalidConfiguration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dad-controller.json")
+	ioutil.WriteFile(path, []byte(`{"rules": [{"name": "GTA", "allow": "mon 10:00"}]}`), 0644)
+
+	var out bytes.Buffer
+	if err := dumpConfig(&out, path); err == nil {
+		t.Errorf("invalid configuration dumped as\n%s", out.String())
+	}
+}
+
+func TestConfigurationIsLoadedOnceItCanBeRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "dad-controller.json")
+
+	ctrl := newDadControllerWithConfigFile(configFile)
+	if len(ctrl.Activities) != 0 {
+		t.Fatalf("rules loaded from a missing file: %v", ctrl.Activities)
+	}
+
+	if err := ioutil.WriteFile(configFile, []byte(`{"samplingInterval": "1h", "rules": [{"name": "GTA", "programs": ["gta"]}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.reloadConfIfNeeded()
+	if len(ctrl.Activities) != 1 || ctrl.Activities[0].Name != "GTA" {
+		t.Fatalf("configuration not loaded once written, got %v", ctrl.Activities)
+	}
+
+	if err := os.Remove(configFile); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.reloadConfIfNeeded()
+	if len(ctrl.Activities) != 1 {
+		t.Errorf("configuration dropped along with its file, got %v", ctrl.Activities)
+	}
+}
+
+func TestReloadedSamplingIntervalWakesTheScanLoopUp(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "dad-controller.json")
+	if err := ioutil.WriteFile(configFile, []byte(`{"samplingInterval": "1h", "rules": [{"name": "GTA", "programs": ["gta"]}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := newDadControllerWithConfigFile(configFile)
+
+	if err := ioutil.WriteFile(configFile, []byte(`{"samplingInterval": "1m", "rules": [{"name": "GTA", "programs": ["gta"]}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(configFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.reloadConf()
+
+	select {
+	case <-ctrl.samplingIntervalChanged:
+	default:
+		t.Error("the wait for the next scan was not woken up")
+	}
+}
+
+func TestValidateConfigReportsEveryProblem(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dad-controller.json")
+	ioutil.WriteFile(path, []byte(`{"rules": [{"name": "GTA", "programs": ["gta(.exe"], "cooldown": "-5m"}]}`), 0644)
+	var out bytes.Buffer
+	if validateConfig(&out, path) {
+		t.Fatalf("invalid configuration validated:\n%s", out.String())
+	}
+	for _, expected := range []string{"gta(.exe", "rules[0] (GTA) : cooldown : negative duration -5m0s"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("%q not reported in:\n%s", expected, out.String())
+		}
+	}
+
+	ioutil.WriteFile(path, []byte(`{"rules": [{"name": "GTA", "programs": ["gta"]}]}`), 0644)
+	out.Reset()
+	if !validateConfig(&out, path) {
+		t.Errorf("valid configuration reported as:\n%s", out.String())
+	}
+}
+
+func TestPINIsNotWrittenToTheStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cfg, err := parseConfig([]byte(`{"pin": "4321", "rules": [{"name": "GTA", "programs": ["gta"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PIN != "4321" {
+		t.Fatalf("PIN is %q", cfg.PIN)
+	}
+
+	ctrl := newDadController(time.Duration(1)*time.Minute, time.Now)
+	ctrl.config = *cfg
+	ctrl.stateFile = filepath.Join(dir, "dad-controller.state")
+	ctrl.dumpState()
+	data, err := ioutil.ReadFile(ctrl.stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "4321") || strings.Contains(string(data), `"pin"`) {
+		t.Errorf("PIN written to the state file:\n%s", data)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "time"
+
+// sampleCPU computes the CPU usage of each process since the previous
+// sample, as a fraction of one core. The usage of a process is unknown on
+// its first sample or when the provider does not report CPU times.
+func (c *dadController) sampleCPU(processes []runningProcess, now time.Time) {
+	elapsed := now.Sub(c.cpuSampledAt)
+	usage := make(map[string]float64)
+	times := make(map[string]time.Duration)
+	for _, p := range processes {
+		if p.CPUTime <= 0 {
+			continue
+		}
+		key := processKey(p)
+		times[key] = p.CPUTime
+		if previous, found := c.cpuTimes[key]; found && elapsed > 0 {
+			usage[key] = float64(p.CPUTime-previous) / float64(elapsed)
+		}
+	}
+	c.cpuTimes = times
+	c.cpuUsage = usage
+	c.cpuSampledAt = now
+}
+
+// activeProcesses returns the processes actually used, according to rule
+// a: using at least its minimum CPU, and owning the foreground window when it
+// only counts foreground time. Processes whose usage or foreground state is
+// unknown are deemed used.
+func (c *dadController) activeProcesses(a *activityRule, processes []runningProcess) []runningProcess {
+	if a == nil || (a.MinCPUUsage <= 0 && !a.ForegroundOnly) {
+		return processes
+	}
+	var active []runningProcess
+	for _, p := range processes {
+		if usage, known := c.cpuUsage[processKey(p)]; a.MinCPUUsage > 0 && known && usage < a.MinCPUUsage {
+			continue
+		}
+		if a.ForegroundOnly && c.foregroundKnown && !p.Foreground {
+			continue
+		}
+		active = append(active, p)
+	}
+	return active
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// curfewActivity is the activity name of the actions of the curfew.
+const curfewActivity = "Curfew"
+
+// curfewPolicy stops any use of the computer by the accounts other than the
+// parent ones during Periods, e.g. after bedtime, by locking their session
+// or logging them off, whatever they run.
+type curfewPolicy struct {
+	Periods map[time.Weekday][]timePeriod `json:"periods"`
+	// Action is lock, the default, or logoff
+	Action string `json:"action,omitempty"`
+}
+
+func (p *curfewPolicy) action() action {
+	if p.Action == actionLogoff.String() {
+		return actionLogoff
+	}
+	return actionLock
+}
+
+func (p *curfewPolicy) check() error {
+	switch p.Action {
+	case "", actionLock.String(), actionLogoff.String():
+		return nil
+	}
+	return fmt.Errorf("unknown curfew action %q, expected lock or logoff", p.Action)
+}
+
+// controlCurfew returns, during the curfew, the actions locking the session
+// of each account using the computer, or logging it off. The processes of
+// an account are killed when its session cannot be locked nor logged off.
+func (c *dadController) controlCurfew(processes []runningProcess, now time.Time) []enforcementAction {
+	if c.Curfew == nil || !periodsActiveAt(c.Curfew.Periods, now) {
+		return nil
+	}
+
+	var owned []runningProcess
+	for _, p := range processes {
+		if p.Pid == os.Getpid() || p.UserID == "" || c.isParentAccount(p.User) || c.isExempt(p, now) || c.isSuspended(p) {
+			continue
+		}
+		owned = append(owned, p)
+	}
+
+	var actions []enforcementAction
+	users, processesOf := processesPerUser(owned)
+	for _, user := range users {
+		fmt.Fprintf(logOutput, "/!\\ computer used by %s during the curfew\n", user)
+		actions = append(actions, enforcementAction{Activity: curfewActivity, User: user, Processes: processesOf[user], Action: c.Curfew.action(), Reason: "Bedtime, the computer cannot be used during this time range"})
+	}
+	return actions
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testCurfew(t *testing.T, now time.Time, curfewAction string, sessions []string) {
+	ctx := NewTest(t).
+		GivenTimeIs(now).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenARunningProcessOfAccount("C:\\Windows\\explorer.exe", 1, `HOME-PC\Tom`).
+		GivenARunningProcessOfAccount("C:\\Windows\\explorer.exe", 2, `HOME-PC\Dad`).
+		GivenARunningProcess("C:\\Windows\\System32\\svchost.exe", 3)
+	ctx.controller.ParentAccounts = []string{"dad"}
+	ctx.controller.Curfew = &curfewPolicy{
+		Periods: map[time.Weekday][]timePeriod{time.Monday: {{Begin: 2200, End: 700}}},
+		Action:  curfewAction,
+	}
+
+	ctx.WhenScanHappens()
+	if !reflect.DeepEqual(ctx.sessions, sessions) {
+		t.Errorf("at %s: expected sessions %q, got %q", now.Format("Mon 15:04"), sessions, ctx.sessions)
+	}
+}
+
+func TestCurfewLocksTheSessionsOfTheChildren(t *testing.T) {
+	testCurfew(t, time.Date(2024, time.October, 14, 21, 0, 0, 0, time.Local), "", nil)
+	testCurfew(t, time.Date(2024, time.October, 14, 23, 0, 0, 0, time.Local), "", []string{"lock|1"})
+	testCurfew(t, time.Date(2024, time.October, 15, 6, 0, 0, 0, time.Local), "logoff", []string{"logoff|1"})
+	testCurfew(t, time.Date(2024, time.October, 15, 8, 0, 0, 0, time.Local), "", nil)
+}
+
+func TestUnknownCurfewActionIsRejected(t *testing.T) {
+	if _, err := parseConfig([]byte(`{"curfew": {"periods": {"1": [{"begin": "22:00", "end": "07:00"}]}, "action": "kill"}}`)); err == nil {
+		t.Error("kill should not be a curfew action")
+	}
+}
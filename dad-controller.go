@@ -3,14 +3,37 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// minSamplingInterval is the shortest sampling interval accepted, scanning
+// processes more often than that would only burn CPU.
+const minSamplingInterval = 5 * time.Second
+
+// defaultMaxCreditedInterval is the most time credited by a scan by default,
+// unless the sampling interval is longer.
+const defaultMaxCreditedInterval = 5 * time.Minute
+
+// defaultMissingScansBeforeAlert is the number of consecutive scans a
+// required process may be missing before the parent is notified.
+const defaultMissingScansBeforeAlert = 3
+
+// logOutput receives everything the controller logs. It defaults to the
+// standard output and is switched to a rotating file when logFile is set.
+var logOutput io.Writer = os.Stdout
+
 type duration time.Duration
 
 func (d duration) MarshalJSON() ([]byte, error) {
@@ -42,107 +65,760 @@ type (
 	timePeriod struct {
 		Begin int `json:"begin"`
 		End   int `json:"end"`
+		// MaxDuration, when set, replaces the maximum duration of the day
+		// while the period governs
+		MaxDuration duration `json:"maxDuration,omitempty"`
 	}
 
 	schedule struct {
 		AllowedPeriods []timePeriod `json:"allowedPeriods"`
 		MaxDuration    duration     `json:"maxDuration"`
+		// SpendableWindow bounds when MaxDuration can be spent, the activity
+		// being allowed anytime within it when AllowedPeriods is empty
+		SpendableWindow *timePeriod `json:"spendableWindow,omitempty"`
+		// DenyPeriods block the activity whatever the allowed periods and
+		// the remaining duration, e.g. during school hours
+		DenyPeriods []timePeriod `json:"denyPeriods,omitempty"`
+		// WarnBefore are the times left, e.g. 15m, 5m and 1m, at which the
+		// user is warned that the activity is about to be stopped
+		WarnBefore []duration `json:"warnBefore,omitempty"`
+		// Template names the template the schedule is based on, whose
+		// fields it overrides by setting them
+		Template string `json:"template,omitempty"`
 	}
 
 	activityRule struct {
 		Name             string                     `json:"name"`
 		ProcessPatterns  []string                   `json:"programs"`
 		AllowedSchedules map[time.Weekday]*schedule `json:"schedules"`
+		Allow            string                     `json:"allow,omitempty"`
+		// RequirePresent rules are not enforced, the parent is notified
+		// instead when none of their programs is running
+		RequirePresent          bool `json:"requirePresent,omitempty"`
+		MissingScansBeforeAlert int  `json:"missingScansBeforeAlert,omitempty"`
+		// CreditWithinPeriods only credits the part of a sampling interval
+		// overlapping the allowed periods (or the spendable window)
+		CreditWithinPeriods bool `json:"creditWithinPeriods,omitempty"`
+		// KillSignal is the signal terminating the processes on Unix, e.g.
+		// SIGKILL or SIGSTOP, SIGTERM followed by SIGKILL by default
+		KillSignal string `json:"killSignal,omitempty"`
+		// CloseTimeout asks the processes to close (WM_CLOSE on Windows,
+		// SIGTERM elsewhere) and only kills them if they are still running
+		// once it has elapsed, instead of using KillSignal
+		CloseTimeout duration `json:"closeTimeout,omitempty"`
+		// Weight of the activity when the time spent in a process shared
+		// with other activities is split by weight, 1 by default
+		Weight float64 `json:"weight,omitempty"`
+		// Action is what is done with the processes of the activity once it
+		// is not allowed: warn, kill (the default), suspend, lock or logoff.
+		// WarnOnly and Suspend are the former warn and suspend actions.
+		Action string `json:"action,omitempty"`
+		// WarnOnly rules never kill, the parent is notified instead once
+		// they are violated on more than MaxWeeklyViolations days of a week
+		WarnOnly            bool `json:"warnOnly,omitempty"`
+		MaxWeeklyViolations int  `json:"maxWeeklyViolations,omitempty"`
+		// MinCPUUsage only counts the time during which the processes use at
+		// least this fraction of a CPU core, e.g. 0.05, so a game paused in
+		// the background is not counted
+		MinCPUUsage float64 `json:"minCpuUsage,omitempty"`
+		// ForegroundOnly only counts the time during which a process of the
+		// activity owns the foreground window, on the platforms telling it
+		ForegroundOnly bool `json:"foregroundOnly,omitempty"`
+		// Suspend rules freeze the processes instead of killing them, and
+		// resume them once the activity is allowed again
+		Suspend bool `json:"suspend,omitempty"`
+		// MatchCommandLine matches the programs patterns against the command
+		// line of the processes instead of their path, e.g. to tell the
+		// programs run by the same interpreter apart
+		MatchCommandLine bool `json:"matchCommandLine,omitempty"`
+		// KillTree also kills the descendants of the processes killed, e.g.
+		// the games started by a launcher
+		KillTree bool `json:"killTree,omitempty"`
+		// PatternType selects how programs are matched, regex by default,
+		// glob or exact, case insensitively on Windows unless CaseSensitive
+		// says otherwise
+		PatternType   string `json:"patternType,omitempty"`
+		CaseSensitive *bool  `json:"caseSensitive,omitempty"`
+
+		// TitlePatterns also map to the activity the processes having a
+		// window whose title matches one of them, e.g. browser games
+		TitlePatterns []string `json:"titlePatterns,omitempty"`
+		// Hashes and Publishers also map to the activity the processes whose
+		// executable has one of these SHA-256 hashes or is signed by one of
+		// these Authenticode publishers (Windows only), whatever its name
+		Hashes     []string `json:"hashes,omitempty"`
+		Publishers []string `json:"publishers,omitempty"`
+		// Pool names the budget pool whose maximum duration per day is
+		// shared with the other activities referencing it
+		Pool string `json:"pool,omitempty"`
+		// MaxWeeklyDuration caps the time spent on the activity per week,
+		// however it is spread over the days
+		MaxWeeklyDuration duration `json:"maxWeeklyDuration,omitempty"`
+		// MaxMonthlyDuration caps the time spent on the activity per
+		// calendar month
+		MaxMonthlyDuration duration `json:"maxMonthlyDuration,omitempty"`
+		// GracePeriod lets the activity run that long past its maximum
+		// durations, the user being warned, to save and quit on their own
+		GracePeriod duration `json:"gracePeriod,omitempty"`
+		// MaxSessionDuration caps the time spent on the activity in a single
+		// session, whatever is left of the day
+		MaxSessionDuration duration `json:"maxSessionDuration,omitempty"`
+		// Cooldown is the break the activity is blocked for once a session
+		// is over
+		Cooldown duration `json:"cooldown,omitempty"`
+		// RolloverCap carries the time left unused on a day over to the
+		// next one, up to this cap
+		RolloverCap duration `json:"rolloverCap,omitempty"`
+		// HolidaySchedule applies on the days of the holiday calendar,
+		// instead of the saturday schedule
+		HolidaySchedule *schedule `json:"holidaySchedule,omitempty"`
+		// DefaultSchedule applies on the weekdays without a schedule, which
+		// the activity is otherwise not allowed on. It may also be given as
+		// the "default" key of the schedules.
+		DefaultSchedule *schedule `json:"defaultSchedule,omitempty"`
+		// Overrides replace the schedule on single dates, e.g. 2024-12-24
+		Overrides map[string]*schedule `json:"overrides,omitempty"`
+		// Priority orders the rules when mapping the processes to them, the
+		// highest first
+		Priority int `json:"priority,omitempty"`
+		// Enabled switches the rule off when false, its processes being
+		// neither counted nor enforced. Rules are enabled by default.
+		Enabled *bool `json:"enabled,omitempty"`
+		// ValidFrom and ValidUntil are the first and last dates the rule
+		// applies on, e.g. 2024-12-24, unbounded when empty
+		ValidFrom  string `json:"validFrom,omitempty"`
+		ValidUntil string `json:"validUntil,omitempty"`
+
+		// source is the rule file the rule comes from, if any
+		source string
+
+		// patterns compiled when the configuration is loaded, along with
+		// the first invalid one
+		patterns      []*regexp.Regexp
+		titlePatterns []*regexp.Regexp
+		patternErr    error
+	}
+
+	// config is the content of the configuration file
+	config struct {
+		// Version is the version of the configuration file, see
+		// configVersion
+		Version          int             `json:"version,omitempty"`
+		SamplingInterval duration        `json:"samplingInterval"`
+		Activities       []*activityRule `json:"rules"`
+		LogFile          string          `json:"logFile,omitempty"`
+		LogRotation      rotationPolicy  `json:"logRotation"`
+		HTTPListen       string          `json:"httpListen,omitempty"`
+		// PIN is read from the "pin" key of the configuration file by
+		// parseConfig only, so that it is not written to the state file
+		PIN string `json:"-"`
+		// PeriodOverlap selects the period governing when the current time
+		// falls in several overlapping allowed periods
+		PeriodOverlap string `json:"periodOverlap,omitempty"`
+		// AuditLog records the changes made through the HTTP API, each of
+		// them requiring a reason when RequireReason is set
+		AuditLog      string `json:"auditLog,omitempty"`
+		RequireReason bool   `json:"requireReason,omitempty"`
+		// SharedProcessAttribution selects how the time spent in a process
+		// matching several activities is credited to them
+		SharedProcessAttribution string `json:"sharedProcessAttribution,omitempty"`
+		// Discovery reports the programs without rule running a lot,
+		// disabled when nil
+		Discovery *discoveryPolicy `json:"discovery,omitempty"`
+		// ProcessProvider is the name of the provider listing and killing
+		// processes, the native one of the platform by default
+		ProcessProvider string `json:"processProvider,omitempty"`
+		// WatchProcessStarts enforces the rules as soon as a matching
+		// process starts, on the platforms able to report process starts
+		WatchProcessStarts bool `json:"watchProcessStarts,omitempty"`
+		// ParentAccounts lists the accounts whose processes are neither
+		// counted nor enforced, e.g. "dad" or "HOME-PC\\Dad"
+		ParentAccounts []string `json:"parentAccounts,omitempty"`
+		// IdleTimeout stops counting the running activities once no input
+		// was received for this long. On Windows, only the input of the
+		// session of the controller is seen, it must not be set when
+		// running as a service
+		IdleTimeout duration `json:"idleTimeout,omitempty"`
+		// Pools are the budgets shared by several activities
+		Pools []*budgetPool `json:"pools,omitempty"`
+		// RuleMatching selects whether a process matching several rules is
+		// counted in all of them, the default, or only in the first one by
+		// priority
+		RuleMatching string `json:"ruleMatching,omitempty"`
+		// Allowlist only lets some accounts run the allowed programs during
+		// its periods, disabled when nil
+		Allowlist *allowlistPolicy `json:"allowlist,omitempty"`
+		// Curfew locks the computer for every account but the parent ones
+		// during its periods, disabled when nil
+		Curfew *curfewPolicy `json:"curfew,omitempty"`
+		// WeekStart is the day weekly counters are reset, monday by default
+		WeekStart string `json:"weekStart,omitempty"`
+		// Holidays are the days on which the rules follow their holiday
+		// schedule
+		Holidays *holidayCalendar `json:"holidays,omitempty"`
+		// Templates are schedules the schedules of the rules are based on,
+		// by name, e.g. "schoolNight" or "weekend"
+		Templates map[string]*schedule `json:"templates,omitempty"`
+		// Users are the profiles whose rules apply to the processes of an
+		// account instead of the rules above, by account name
+		Users map[string]*userProfile `json:"users,omitempty"`
+		// Profiles are the profiles of the children sharing an account, by
+		// name, the rules of the active one applying to its processes
+		Profiles map[string]*userProfile `json:"profiles,omitempty"`
+		// MaxCreditedInterval caps the time credited by a scan to the
+		// running activities, which is the time elapsed since the previous
+		// scan, 5 minutes (or the sampling interval if longer) by default
+		MaxCreditedInterval duration `json:"maxCreditedInterval,omitempty"`
+		// HistoryWeeks is the number of weeks the daily counters are kept
+		// in the state for, 4 by default
+		HistoryWeeks int `json:"historyWeeks,omitempty"`
+		// StateBackups is the number of copies of the state file kept, one
+		// per day, 7 by default, none when negative
+		StateBackups int `json:"stateBackups,omitempty"`
+		// ResumePolicy selects whether a scan longer than
+		// MaxCreditedInterval after the previous one, e.g. after the
+		// computer slept, credits MaxCreditedInterval, the default, or
+		// nothing
+		ResumePolicy string `json:"resumePolicy,omitempty"`
+		// ConfigDir is a directory whose .json files each hold a rule,
+		// added to the rules above, relative to the directory of the
+		// configuration file, e.g. "rules.d"
+		ConfigDir string `json:"configDir,omitempty"`
+		// TimeZone is the zone the schedules are evaluated in, e.g.
+		// Europe/Paris, the local one of the computer by default
+		TimeZone string `json:"timeZone,omitempty"`
+
+		// location of TimeZone, nil for the local one
+		location *time.Location
 	}
 
 	dadController struct {
 		// configuration
 		configFile      string
 		confLastModTime time.Time
-
-		SamplingInterval duration        `json:"samplingInterval"`
-		Activities       []*activityRule `json:"rules"`
+		stateFile       string
+		// signingKey is the secret the configuration must be signed with,
+		// nil when it does not need to be signed
+		signingKey []byte
+		// remote keeps configFile, the local copy of a configuration served
+		// over HTTPS, up to date, nil when the configuration is local
+		remote *remoteConfig
+
+		config
+		logFile   *rotatingFile
+		auditFile *rotatingFile
+
+		// mu serializes the scan loop and the HTTP API, which holds it
+		// while calling into the controller
+		mu                      sync.Mutex
+		samplingIntervalChanged chan struct{}
+		// stopping is closed by Stop to end the scan loop
+		stopping chan struct{}
+		stopOnce sync.Once
+		// running processes of the last scan, per activity, and the timer
+		// re-evaluating them at the next transition of their schedules,
+		// armed at boundaryGeneration
+		running            map[string][]runningProcess
+		boundaryTimer      timer
+		boundaryGeneration int
+		// exhaustion is when the time left on the first of the running
+		// activities to run out does, a second after it reaches zero
+		exhaustion time.Time
+		// scannedAt is when the last scan out of the loop started
+		scannedAt time.Time
+		// recent events shown on the dashboard
+		events []statusEvent
+		// processes asked to close and not killed yet, by "pid|path"
+		closing map[string]bool
+		// CPU times of the previous scan and usage since then, by "pid|path"
+		cpuTimes     map[string]time.Duration
+		cpuUsage     map[string]float64
+		cpuSampledAt time.Time
+		// smallest WarnBefore duration warned about today, by "user|activity"
+		warned map[string]time.Duration
+		// account names of the owners of the processes, by user identifier
+		accounts map[string]string
+		// identities of the executables of the running processes, by path
+		identities map[string]*executableIdentity
+		// foregroundKnown is set when the last listing told which process
+		// owns the foreground window
+		foregroundKnown bool
+		// elapsed is the time actually elapsed since the previous scan of
+		// the loop, longer than the sampling interval when a scan overruns
+		elapsed time.Duration
+		// credited is the time credited to the running activities by the
+		// last scan
+		credited time.Duration
 
 		// hook for tests
-		GetTime              func() time.Time                                          `json:"-"`
-		GetRunningProcesses  func() []runningProcess                                   `json:"-"`
-		KillRunningProcesses func(activity string, rp []runningProcess, reason string) `json:"-"`
-		WarnAboutKill        func(activity string, rp []runningProcess, reason string) `json:"-"`
+		GetTime       func() time.Time                                          `json:"-"`
+		Processes     ProcessProvider                                           `json:"-"`
+		WarnAboutKill func(activity string, rp []runningProcess, reason string) `json:"-"`
+		NotifyParent  func(message string)                                      `json:"-"`
+		AfterFunc     func(d time.Duration, f func()) timer                     `json:"-"`
+		IdleTime      func() (time.Duration, error)                             `json:"-"`
+		Publisher     func(path string) (string, error)                         `json:"-"`
+		// Store persists the state, the one of stateFile when nil
+		Store StateStore `json:"-"`
+
+		// custom policies consulted before the default ones
+		Policies []Policy `json:"-"`
 
 		// state
-		LastControlTime  time.Time                            `json:"lastControlTime"`
-		ActivityDuration map[time.Weekday]map[string]duration `json:"activityDuration"`
+		// StateVersion is the version of the state file, see stateVersion
+		StateVersion    int       `json:"stateVersion"`
+		LastControlTime time.Time `json:"lastControlTime"`
+		// counters per date, e.g. 2024-12-24, then per activity
+		ActivityDuration map[string]map[string]duration `json:"activityDuration"`
+		// counters of processes whose owner is known, per user identifier
+		UserActivityDuration     map[string]map[string]map[string]duration `json:"userActivityDuration,omitempty"`
+		SamplingIntervalOverride duration                                  `json:"samplingIntervalOverride,omitempty"`
+		LockdownUntil            time.Time                                 `json:"lockdownUntil,omitempty"`
+		MissingRequiredScans     map[string]int                            `json:"missingRequiredScans,omitempty"`
+		ProbationFactor          float64                                   `json:"probationFactor,omitempty"`
+		ProbationUntil           time.Time                                 `json:"probationUntil,omitempty"`
+		Exemptions               []processExemption                        `json:"exemptions,omitempty"`
+		// running time of the programs without rule since the last report
+		UnmanagedDuration   map[string]duration `json:"unmanagedDuration,omitempty"`
+		LastDiscoveryReport time.Time           `json:"lastDiscoveryReport,omitempty"`
+		// days of the week on which warn only rules were violated
+		Violations map[string]*weeklyViolations `json:"violations,omitempty"`
+		// number of kills per user identifier, then per date and activity
+		Kills     map[string]map[string]map[string]int `json:"kills,omitempty"`
+		Suspended []suspendedProcess                   `json:"suspended,omitempty"`
+		// counters of the week and of the month, per user identifier
+		WeeklyDuration  periodCounters `json:"weeklyDuration"`
+		MonthlyDuration periodCounters `json:"monthlyDuration"`
+		// schedules overridden through the HTTP API until their date is over
+		Overrides []scheduleOverride `json:"overrides,omitempty"`
+		// sessions of the activities, by "user|activity"
+		Sessions map[string]*activitySession `json:"sessions,omitempty"`
+		// runs of the processes of the activities today, in order of start
+		ProcessSessions []*processSession `json:"processSessions,omitempty"`
+		// time earned through the HTTP API until their date is over
+		Credits []timeCredit `json:"credits,omitempty"`
+		// time carried over from yesterday, per user identifier
+		Banked map[string]map[string]duration `json:"banked,omitempty"`
+		// ActiveProfile names the profile using the computer, if any
+		ActiveProfile string `json:"activeProfile,omitempty"`
+		// ConfigSigned is set once the configuration had to be signed, so
+		// that deleting the signing key does not turn the signature off
+		ConfigSigned bool `json:"configSigned,omitempty"`
+	}
+
+	// processExemption spares a process from enforcement until a given time.
+	// It is keyed on the path too, for a reused pid not to be exempted.
+	processExemption struct {
+		Pid   int       `json:"pid"`
+		Path  string    `json:"path"`
+		Until time.Time `json:"until"`
 	}
 
 	runningProcess struct {
 		Pid  int    `json:"Id"`
 		Path string `json:"Path"`
+		// UserID identifies the owner of the process (SID on Windows, uid
+		// elsewhere), empty when it cannot be determined
+		UserID string `json:"UserID,omitempty"`
+		// User is the account name of the owner, DOMAIN\name on Windows
+		User string `json:"UserName,omitempty"`
+		// CommandLine is the executable followed by its arguments, empty
+		// when it cannot be read
+		CommandLine string `json:"CommandLine,omitempty"`
+		// WindowTitles are the titles of the visible windows of the process,
+		// only known on Windows
+		WindowTitles []string `json:"WindowTitles,omitempty"`
+		// Foreground is set when the process owns the foreground window
+		Foreground bool `json:"Foreground,omitempty"`
+		// StartTime is when the process was created, zero when unknown
+		StartTime time.Time `json:"StartTime"`
+		// ParentPid is the pid of the process which created it, 0 when
+		// unknown
+		ParentPid int `json:"ParentId,omitempty"`
+		// Distribution is the WSL distribution running the process, whose
+		// pid is the one inside the distribution, empty for Windows processes
+		Distribution string `json:"Distribution,omitempty"`
+		// Package is the full name of the Microsoft Store package of the
+		// process, e.g. Microsoft.MinecraftUWP_1.20.5001.0_x64__8wekyb3d8bbwe,
+		// empty for the other processes
+		Package string `json:"Package,omitempty"`
+		// CPUTime is the CPU time consumed by the process since it started,
+		// 0 when unknown
+		CPUTime time.Duration `json:"CPUTime,omitempty"`
+		// Hash is the SHA-256 hash of the executable and Publisher the
+		// signer of its Authenticode signature, only set when a rule
+		// matches on them
+		Hash      string `json:"Hash,omitempty"`
+		Publisher string `json:"Publisher,omitempty"`
 	}
 )
 
 func newDadController(samplingInterval time.Duration, getTimeFunc func() time.Time) *dadController {
-	return &dadController{SamplingInterval: duration(samplingInterval),
-		ActivityDuration:     make(map[time.Weekday]map[string]duration),
-		GetTime:              getTimeFunc,
-		GetRunningProcesses:  getRunningProcesses,
-		KillRunningProcesses: kill,
-		WarnAboutKill:        warn,
-		LastControlTime:      getTimeFunc(),
+	ctrl := &dadController{config: config{SamplingInterval: duration(samplingInterval)},
+		ActivityDuration: make(map[string]map[string]duration),
+		GetTime:          getTimeFunc,
+		Processes:        nativeProvider(),
+		WarnAboutKill:    warn,
+		NotifyParent:     notifyParent,
+		AfterFunc:        afterFunc,
+		IdleTime:         systemIdleTime,
+		Publisher:        authenticodePublisher,
+		LastControlTime:  getTimeFunc(),
+
+		samplingIntervalChanged: make(chan struct{}, 1),
+		stopping:                make(chan struct{}),
 	}
+	return ctrl
 }
 
 func newDadControllerWithConfigFile(configFile string) *dadController {
+	return newDadControllerWithFiles(configFile, stateFileName)
+}
+
+// newDadControllerWithFiles returns a controller applying configFile and
+// saving its state to stateFile, whose directory may hold the secret the
+// configuration must be signed with.
+func newDadControllerWithFiles(configFile string, stateFile string) *dadController {
 	getTimeFunc := time.Now
 	ctrl := &dadController{
-		configFile:           configFile,
-		ActivityDuration:     make(map[time.Weekday]map[string]duration),
-		GetTime:              getTimeFunc,
-		GetRunningProcesses:  getRunningProcesses,
-		KillRunningProcesses: kill,
-		WarnAboutKill:        warn,
-		LastControlTime:      getTimeFunc(),
+		// scan, hence retry to load the configuration, as often as
+		// possible until it is loaded
+		config:           config{SamplingInterval: duration(minSamplingInterval)},
+		configFile:       configFile,
+		stateFile:        stateFile,
+		ActivityDuration: make(map[string]map[string]duration),
+		GetTime:          getTimeFunc,
+		Processes:        nativeProvider(),
+		WarnAboutKill:    warn,
+		NotifyParent:     notifyParent,
+		AfterFunc:        afterFunc,
+		IdleTime:         systemIdleTime,
+		Publisher:        authenticodePublisher,
+		LastControlTime:  getTimeFunc(),
+
+		samplingIntervalChanged: make(chan struct{}, 1),
+		stopping:                make(chan struct{}),
+	}
+	key, err := readSigningKey(keyFile(stateFile))
+	if err != nil {
+		// refuse any configuration rather than accepting unsigned ones
+		fmt.Fprintln(logOutput, "Failure to read the signing key, no configuration will be applied : ", err)
+		key = []byte{}
+	} else if key == nil && fileExists(signatureFile(configFile)) {
+		fmt.Fprintln(logOutput, "The configuration is signed but the signing key is missing, no configuration will be applied")
+		key = []byte{}
 	}
+	ctrl.signingKey = key
+	ctrl.ConfigSigned = key != nil
 	ctrl.reloadConfIfNeeded()
 	return ctrl
 }
 
+// reloadConfIfNeeded applies the configuration file when it changed since it
+// was last read. The current configuration is kept when the file cannot be
+// read, which is retried at the next call, or is invalid, until it changes
+// again.
 func (c *dadController) reloadConfIfNeeded() {
-	stat, err := os.Stat(c.configFile)
+	modTime, err := c.configModTime()
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(logOutput, "Failure to stat configuration file, keeping the current configuration : ", err)
+		return
 	}
-	if stat.ModTime().After(c.confLastModTime) {
-		fmt.Println("Detecting change of configuration. Reloading it.")
-		c.confLastModTime = stat.ModTime()
+	if modTime.After(c.confLastModTime) {
+		fmt.Fprintln(logOutput, "Detecting change of configuration. Reloading it.")
 
-		jsonFile, err := os.Open(c.configFile)
+		data, err := ioutil.ReadFile(c.configFile)
 		if err != nil {
-			panic(err)
+			fmt.Fprintln(logOutput, "Failure to read configuration file, keeping the current configuration : ", err)
+			return
 		}
-		defer jsonFile.Close()
+		c.confLastModTime = modTime
 
-		data, err := ioutil.ReadAll(jsonFile)
+		cfg, err := parseConfigIn(data, filepath.Dir(c.configFile))
 		if err != nil {
-			panic(err)
+			fmt.Fprintln(logOutput, "Invalid configuration, keeping the current one :")
+			for _, problem := range strings.Split(err.Error(), "\n") {
+				fmt.Fprintln(logOutput, "  ", problem)
+			}
+			return
+		}
+		if err := c.checkSignature(data, cfg); err != nil {
+			fmt.Fprintln(logOutput, "Refusing the configuration, keeping the current one : ", err)
+			return
 		}
 
-		var tmpCtrl dadController
-		json.Unmarshal(data, &tmpCtrl)
-
-		c.Activities = tmpCtrl.Activities
-		c.SamplingInterval = tmpCtrl.SamplingInterval
+		c.setLogFile(cfg.LogFile, cfg.LogRotation)
+		c.setAuditLog(cfg.AuditLog, cfg.LogRotation)
+		if cfg.ProcessProvider != c.ProcessProvider {
+			if p, err := newProcessProvider(cfg.ProcessProvider); err == nil {
+				c.Processes = p
+			}
+		}
+		c.config = *cfg
+		c.SamplingIntervalOverride = 0
 
-		fmt.Printf("Sampling Interval: %s\n", time.Duration(c.SamplingInterval).String())
+		fmt.Fprintf(logOutput, "Sampling Interval: %s\n", time.Duration(c.SamplingInterval).String())
 		for idx := range c.Activities {
-			fmt.Printf("Activity [%s]\n", c.Activities[idx].Name)
+			fmt.Fprintf(logOutput, "Activity [%s]\n", c.Activities[idx].Name)
+
+		}
+	}
+}
+
+// sameDay tells whether t2 falls on the date of t1, in the location of t1.
+func sameDay(t1 time.Time, t2 time.Time) bool {
+	return dateKey(t1) == dateKey(t2.In(t1.Location()))
+}
+
+// dateKey returns the date of t, e.g. 2024-12-24, which unlike durations
+// between times is not affected by changes of clock.
+func dateKey(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func clampSamplingInterval(d time.Duration) time.Duration {
+	if d < minSamplingInterval {
+		return minSamplingInterval
+	}
+	return d
+}
+
+// SetSamplingInterval changes the sampling interval at runtime. The interval
+// is clamped to minSamplingInterval, wakes up the scan loop so that the new
+// value applies to the wait in progress, and is saved in the state file so
+// that it survives a restart until the configuration file changes.
+func (c *dadController) SetSamplingInterval(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setSamplingIntervalLocked(d)
+}
+
+// setSamplingIntervalLocked is SetSamplingInterval for callers holding c.mu.
+func (c *dadController) setSamplingIntervalLocked(d time.Duration) {
+	d = clampSamplingInterval(d)
+	c.SamplingInterval = duration(d)
+	c.SamplingIntervalOverride = duration(d)
+
+	fmt.Fprintf(logOutput, "Sampling Interval changed to %s\n", d.String())
+	c.samplingIntervalUpdated()
+}
+
+// samplingIntervalUpdated wakes up the wait for the next scan, for it to
+// wait the new sampling interval.
+func (c *dadController) samplingIntervalUpdated() {
+	select {
+	case c.samplingIntervalChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (c *dadController) getSamplingInterval() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.SamplingInterval)
+}
+
+// waitNextScan sleeps until one sampling interval has elapsed since start,
+// re-arming itself whenever the sampling interval is changed meanwhile. It
+// returns false when the controller is stopped meanwhile.
+func (c *dadController) waitNextScan(start time.Time) bool {
+	for {
+		remaining := c.getSamplingInterval() - time.Since(start)
+		if remaining <= 0 {
+			return true
+		}
+
+		timer := time.NewTimer(remaining)
+		select {
+		case <-timer.C:
+			return true
+		case <-c.samplingIntervalChanged:
+			timer.Stop()
+		case <-c.stopping:
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// scanAfter waits one sampling interval after the previous scan started then
+// scans, returning when this scan started. A scan overrunning the sampling
+// interval is logged and the next one starts right away, the time it took
+// being credited to the running activities. It returns previous without
+// scanning when the controller is stopped meanwhile.
+func (c *dadController) scanAfter(previous time.Time) time.Time {
+	if !c.waitNextScan(previous) {
+		return previous
+	}
+	start := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.scannedAt.After(previous) {
+		// scanned meanwhile when the time left on an activity ran out
+		previous = c.scannedAt
+	}
+	c.elapsed = start.Sub(previous)
+	c.scan()
+	c.dumpState()
+	if took, interval := time.Since(start), time.Duration(c.SamplingInterval); took > interval {
+		fmt.Fprintf(logOutput, "Scan took %s, longer than the sampling interval of %s, skipping the wait for the next scan\n", took, interval)
+	}
+	return start
+}
+
+// creditedInterval returns the time to credit to the running activities at
+// the scan at now, which is the time actually elapsed since the previous
+// scan at previous, as measured by the loop or else between both times, up
+// to MaxCreditedInterval.
+func (c *dadController) creditedInterval(previous time.Time, now time.Time) time.Duration {
+	elapsed := c.elapsed
+	c.elapsed = 0
+	if elapsed <= 0 {
+		elapsed = now.Sub(previous).Round(time.Millisecond)
+	}
+	if elapsed <= 0 {
+		// the clock is pinned or was set back, assume a regular scan
+		return time.Duration(c.SamplingInterval)
+	}
+	if max := c.maxCreditedInterval(); elapsed > max {
+		return c.creditAfterResume(elapsed, max)
+	}
+	return elapsed
+}
+
+func (c *dadController) maxCreditedInterval() time.Duration {
+	if max := time.Duration(c.MaxCreditedInterval); max > 0 {
+		return max
+	}
+	if interval := time.Duration(c.SamplingInterval); interval > defaultMaxCreditedInterval {
+		return interval
+	}
+	return defaultMaxCreditedInterval
+}
+
+// Lockdown kills every managed process on each scan, whatever the schedules
+// say, until d has elapsed. A non-positive d lifts the lockdown.
+func (c *dadController) Lockdown(d time.Duration) {
+	if d <= 0 {
+		c.LockdownUntil = time.Time{}
+		fmt.Fprintln(logOutput, "Lockdown lifted")
+		return
+	}
+	c.LockdownUntil = c.now().Add(d)
+	fmt.Fprintf(logOutput, "Lockdown until %s\n", c.LockdownUntil)
+}
+
+// SetProbation scales down the maximum durations of every activity by
+// factor, clamped between 0 and 1, until the given time.
+func (c *dadController) SetProbation(factor float64, until time.Time) {
+	if math.IsNaN(factor) || factor > 1 {
+		factor = 1
+	} else if factor < 0 {
+		factor = 0
+	}
+	c.ProbationFactor = factor
+	c.ProbationUntil = until
+	fmt.Fprintf(logOutput, "Probation at %.0f%% until %s\n", factor*100, until)
+}
+
+func (c *dadController) probationActiveAt(t time.Time) bool {
+	return c.ProbationFactor < 1 && t.Before(c.ProbationUntil)
+}
+
+func (c *dadController) expireProbation(now time.Time) {
+	if !c.ProbationUntil.IsZero() && !now.Before(c.ProbationUntil) {
+		fmt.Fprintln(logOutput, "Probation is over")
+		c.ProbationFactor = 0
+		c.ProbationUntil = time.Time{}
+	}
+}
 
+// Exempt spares p from enforcement until d has elapsed, whatever the
+// schedule of its activity says.
+func (c *dadController) Exempt(p runningProcess, d time.Duration) processExemption {
+	e := processExemption{Pid: p.Pid, Path: p.Path, Until: c.now().Add(d)}
+	for i, existing := range c.Exemptions {
+		if existing.Pid == e.Pid && existing.Path == e.Path {
+			c.Exemptions = append(c.Exemptions[:i], c.Exemptions[i+1:]...)
+			break
 		}
 	}
+	c.Exemptions = append(c.Exemptions, e)
+	fmt.Fprintf(logOutput, "Process %d (%s) exempted until %s\n", e.Pid, e.Path, e.Until)
+	return e
+}
+
+func (c *dadController) isExempt(p runningProcess, now time.Time) bool {
+	for _, e := range c.Exemptions {
+		if e.Pid == p.Pid && e.Path == p.Path && now.Before(e.Until) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *dadController) expireExemptions(now time.Time) {
+	var kept []processExemption
+	for _, e := range c.Exemptions {
+		if now.Before(e.Until) {
+			kept = append(kept, e)
+		} else {
+			fmt.Fprintf(logOutput, "Exemption of process %d (%s) is over\n", e.Pid, e.Path)
+		}
+	}
+	c.Exemptions = kept
+}
+
+// PinNow freezes the controller's notion of now at t, for demos of what
+// the rules do at a given time without changing the system clock.
+func (c *dadController) PinNow(t time.Time) {
+	fmt.Fprintf(logOutput, "Pinning current time at %s\n", t)
+	c.GetTime = func() time.Time { return t }
+}
+
+// parseFakeNow parses a -fake-now value, either RFC 3339 or a local
+// "2006-01-02 15:04" time.
+func parseFakeNow(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+}
+
+func (c *dadController) setLogFile(path string, policy rotationPolicy) {
+	if c.logFile != nil && path == c.LogFile && policy == c.LogRotation {
+		return
+	}
+
+	if c.logFile != nil {
+		logOutput = os.Stdout
+		c.logFile.Close()
+		c.logFile = nil
+	}
+	c.LogFile = path
+	c.LogRotation = policy
+
+	if path == "" {
+		return
+	}
+	logFile, err := newRotatingFile(path, policy, time.Now)
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to open log file : ", err)
+		return
+	}
+	c.logFile = logFile
+	logOutput = logFile
 }
 
 func (c *dadController) GetActivityDuration(activity string) time.Duration {
-	day := c.LastControlTime.Weekday()
-	ad, found := c.ActivityDuration[day]
+	return c.GetUserActivityDuration("", activity)
+}
+
+// GetUserActivityDuration returns the time spent today on activity by the
+// processes owned by user.
+func (c *dadController) GetUserActivityDuration(user string, activity string) time.Duration {
+	ad, found := c.durationsOf(user)[dateKey(c.LastControlTime)]
 	if !found {
 		return time.Duration(0)
 	}
@@ -156,23 +832,88 @@ func (c *dadController) GetActivityDuration(activity string) time.Duration {
 }
 
 func (c *dadController) updateActivityDuration(activity string, activityDuration time.Duration) {
-	day := c.LastControlTime.Weekday()
+	c.updateUserActivityDuration("", activity, activityDuration)
+}
+
+func (c *dadController) updateUserActivityDuration(user string, activity string, activityDuration time.Duration) {
+	c.dayDurationsOf(user)[activity] = duration(activityDuration)
+}
+
+// durationsOf returns the activity counters of user. Counters of processes
+// whose owner is unknown are kept in ActivityDuration.
+func (c *dadController) durationsOf(user string) map[string]map[string]duration {
+	if user == "" {
+		if c.ActivityDuration == nil {
+			c.ActivityDuration = make(map[string]map[string]duration)
+		}
+		return c.ActivityDuration
+	}
+
+	if c.UserActivityDuration == nil {
+		c.UserActivityDuration = make(map[string]map[string]map[string]duration)
+	}
+	durations, found := c.UserActivityDuration[user]
+	if !found {
+		durations = make(map[string]map[string]duration)
+		c.UserActivityDuration[user] = durations
+	}
+	return durations
+}
+
+// dayDurationsOf returns the activity counters of user for the current day.
+func (c *dadController) dayDurationsOf(user string) map[string]duration {
+	day := dateKey(c.LastControlTime)
+	durations := c.durationsOf(user)
 
 	// make activity duration for the current day available
-	ad, found := c.ActivityDuration[day]
+	ad, found := durations[day]
 	if !found {
 		ad = make(map[string]duration)
-		c.ActivityDuration[day] = ad
+		durations[day] = ad
+	}
+	return ad
+}
+
+// migrateWeekdayCounters converts the counters of state files written when
+// they were keyed by weekday, 0 for Sunday to 6 for Saturday. Only the
+// counters of the day of the last control are kept, the others being stale.
+func (c *dadController) migrateWeekdayCounters() {
+	migrate := func(durations map[string]map[string]duration) {
+		for key, ad := range durations {
+			weekday, err := strconv.Atoi(key)
+			if err != nil {
+				continue
+			}
+			delete(durations, key)
+			if time.Weekday(weekday) == c.LastControlTime.Weekday() && !c.LastControlTime.IsZero() {
+				durations[dateKey(c.LastControlTime)] = ad
+			}
+		}
 	}
+	migrate(c.ActivityDuration)
+	for _, durations := range c.UserActivityDuration {
+		migrate(durations)
+	}
+}
 
-	ad[activity] = duration(activityDuration)
+// processesPerUser groups processes by owner, returning the sorted owners
+// along with them.
+func processesPerUser(processes []runningProcess) ([]string, map[string][]runningProcess) {
+	results := make(map[string][]runningProcess)
+	var users []string
+	for _, p := range processes {
+		if _, found := results[p.UserID]; !found {
+			users = append(users, p.UserID)
+		}
+		results[p.UserID] = append(results[p.UserID], p)
+	}
+	sort.Strings(users)
+	return users, results
 }
 
 func (c *dadController) getOrCreateActivityRule(activity string) *activityRule {
-	for _, a := range c.Activities {
-		if a.Name == activity {
-			return a
-		}
+	if a := c.findActivityRule(activity); a != nil {
+		return a
 	}
 
 	a := activityRule{Name: activity, AllowedSchedules: make(map[time.Weekday]*schedule)}
@@ -207,234 +948,691 @@ func (a *activityRule) SetMaximumAllowedDurationPerDay(days []time.Weekday, maxi
 	}
 }
 
-func (c *dadController) scan() {
-	rp := c.getRunningProcessesPerActivity()
-	c.updateActivityCounters(rp, c.GetTime())
-	c.controlActivities(rp)
+func (a *activityRule) AddDenyPeriod(days []time.Weekday, begin int, end int) {
+	for _, d := range days {
+		s := a.getOrCreateSchedule(d)
+		s.DenyPeriods = append(s.DenyPeriods, timePeriod{Begin: begin, End: end})
+	}
+}
+
+func (a *activityRule) SetSpendableWindow(days []time.Weekday, begin int, end int) {
+	for _, d := range days {
+		a.getOrCreateSchedule(d).SpendableWindow = &timePeriod{Begin: begin, End: end}
+	}
+}
+
+// scan runs one enforcement step and applies the actions it decided.
+func (c *dadController) scan() []enforcementAction {
+	actions := c.scanOnce()
+	c.applyActions(actions)
+	c.scheduleBoundaryCheck(c.LastControlTime)
+	return actions
+}
+
+// scanOnce updates the activity counters from the running processes and
+// returns the enforcement actions to take, without applying them.
+func (c *dadController) scanOnce() []enforcementAction {
+	processes, err := c.listProcesses()
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to list running processes : ", err)
+		return nil
+	}
+	c.checkRequiredProcesses(processes)
+	c.sampleCPU(processes, c.now())
+	rp := c.getRunningProcessesPerActivity(processes)
+	c.running = rp
+	c.updateActivityCounters(rp, c.now())
+	c.discoverUnmanaged(processes, c.LastControlTime)
+	c.resumeAllowed(processes, c.LastControlTime)
+	actions := c.controlActivities(rp, c.LastControlTime)
+	actions = append(actions, c.controlAllowlist(processes, c.LastControlTime)...)
+	return append(actions, c.controlCurfew(processes, c.LastControlTime)...)
+}
+
+// preview returns the actions a scan would decide right now, without
+// updating counters nor applying them.
+func (c *dadController) preview() []enforcementAction {
+	processes, err := c.listProcesses()
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to list running processes : ", err)
+		return nil
+	}
+	actions := c.controlActivities(c.getRunningProcessesPerActivity(processes), c.now())
+	actions = append(actions, c.controlAllowlist(processes, c.now())...)
+	return append(actions, c.controlCurfew(processes, c.now())...)
+}
+
+func (c *dadController) applyActions(actions []enforcementAction) {
+	for _, a := range actions {
+		switch a.Action {
+		case actionKill:
+			c.recordEvent(fmt.Sprintf("%s killed : %s", a.Activity, a.Reason))
+			c.kill(a.Activity, a.Processes, a.Reason)
+		case actionSuspend:
+			c.recordEvent(fmt.Sprintf("%s suspended : %s", a.Activity, a.Reason))
+			c.suspend(a.Activity, a.Processes)
+		case actionLock:
+			c.recordEvent(fmt.Sprintf("%s session locked : %s", a.Activity, a.Reason))
+			c.lockSession(a.Activity, a.Processes, a.Reason)
+		case actionLogoff:
+			c.recordEvent(fmt.Sprintf("%s session logged off : %s", a.Activity, a.Reason))
+			c.logoff(a.Activity, a.Processes, a.Reason)
+		case actionWarn:
+			c.recordViolation(a.User, a.Activity, c.now())
+		case actionRemind:
+			c.remind(a)
+		}
+	}
 }
 
-func (c *dadController) getRunningProcessesPerActivity() map[string][]runningProcess {
-	processes := c.GetRunningProcesses()
+func (c *dadController) getRunningProcessesPerActivity(processes []runningProcess) map[string][]runningProcess {
+	var kids []runningProcess
+	for _, p := range processes {
+		if !c.isParentAccount(p.User) && !c.isSuspended(p) {
+			kids = append(kids, p)
+		}
+	}
 
-	// map processes to activities
+	// map processes to activities, following the rules of their owner
+	c.rememberAccounts(kids)
+	kids = c.asActiveProfile(kids)
+	users, processesOf := processesPerUser(kids)
 	results := make(map[string][]runningProcess)
-	for _, activity := range c.Activities {
-		for _, processPattern := range activity.ProcessPatterns {
-			regex, _ := regexp.Compile(processPattern)
-
-			for _, rp := range processes {
-				if regex.MatchString(rp.Path) {
-					fmt.Println(rp.Path)
-					r, found := results[activity.Name]
-					if !found {
-						r = []runningProcess{}
-						results[activity.Name] = r
+	claimed := make(map[string]bool)
+	seen := make(map[string]map[string]bool)
+	now := c.now()
+	for _, user := range users {
+		for _, activity := range byPriority(c.rulesOf(user)) {
+			if activity.RequirePresent || !activity.activeOn(now) {
+				continue
+			}
+			candidates := processesOf[user]
+			if c.RuleMatching == ruleMatchingFirst {
+				candidates = nil
+				for _, p := range processesOf[user] {
+					if !claimed[processKey(p)] {
+						candidates = append(candidates, p)
 					}
-					results[activity.Name] = append(r, rp)
 				}
 			}
+			if seen[activity.Name] == nil {
+				seen[activity.Name] = make(map[string]bool)
+			}
+			for _, p := range activity.matchingProcesses(candidates) {
+				// a process matched by several patterns is counted once
+				if !seen[activity.Name][processKey(p)] {
+					seen[activity.Name][processKey(p)] = true
+					results[activity.Name] = append(results[activity.Name], p)
+				}
+				claimed[processKey(p)] = true
+			}
 		}
 	}
 
 	return results
 }
 
-func (c *dadController) updateActivityCounters(rp map[string][]runningProcess, now time.Time) {
-	if now.Year() != c.LastControlTime.Year() ||
-		now.Month() != c.LastControlTime.Month() ||
-		now.Day() != c.LastControlTime.Day() {
-		// change of day detected, reset of counters
-		delete(c.ActivityDuration, now.Weekday())
+// isParentAccount tells whether user is one of the parent accounts.
+func (c *dadController) isParentAccount(user string) bool {
+	if user == "" {
+		return false
 	}
-	c.LastControlTime = now
+	for _, parent := range c.ParentAccounts {
+		if accountMatches(user, parent) {
+			return true
+		}
+	}
+	return false
+}
 
-	if len(rp) > 0 {
-		day := c.LastControlTime.Weekday()
+// accountMatches tells whether user is account, ignoring case and, when
+// account has none, the domain of user.
+func accountMatches(user string, account string) bool {
+	if strings.EqualFold(user, account) {
+		return true
+	}
+	i := strings.LastIndex(user, `\`)
+	return i >= 0 && !strings.Contains(account, `\`) && strings.EqualFold(user[i+1:], account)
+}
 
-		// make activity duration for the current day is available
-		ad, found := c.ActivityDuration[day]
-		if !found {
-			ad = make(map[string]duration)
-			c.ActivityDuration[day] = ad
-		}
+// matchedText returns what the patterns of the rule are matched against,
+// besides the package of Store apps, the path of the process when its
+// command line is unknown.
+func (a *activityRule) matchedText(rp runningProcess) string {
+	if a.MatchCommandLine && rp.CommandLine != "" {
+		return rp.CommandLine
+	}
+	return rp.Path
+}
+
+func (a *activityRule) matchingProcesses(processes []runningProcess) []runningProcess {
+	var results []runningProcess
+	patterns, titlePatterns := a.compiledPatterns()
+	for _, regex := range patterns {
 
-		// update duration counters
-		for activity := range rp {
-			d, found := ad[activity]
-			if !found {
-				d = duration(0)
+		for _, rp := range processes {
+			if regex.MatchString(a.matchedText(rp)) || (rp.Package != "" && regex.MatchString(rp.Package)) {
+				fmt.Fprintln(logOutput, rp.Path)
+				results = append(results, rp)
 			}
-			ad[activity] = d + c.SamplingInterval
 		}
 	}
-
-	c.dumpActivitiesDuration()
+	for _, rp := range processes {
+		if a.matchesIdentity(rp) {
+			fmt.Fprintf(logOutput, "%s (%s %s)\n", rp.Path, rp.Hash, rp.Publisher)
+			results = append(results, rp)
+		}
+	}
+	for _, regex := range titlePatterns {
+		for _, rp := range processes {
+			for _, title := range rp.WindowTitles {
+				if regex.MatchString(title) {
+					fmt.Fprintf(logOutput, "%s (%s)\n", rp.Path, title)
+					results = append(results, rp)
+					break
+				}
+			}
+		}
+	}
+	return results
 }
 
-func (c *dadController) dumpActivitiesDuration() {
-	fmt.Println("================= Current State ===================")
-	day := c.LastControlTime.Weekday()
-	fmt.Println("LastControlTime: ", c.LastControlTime)
-	fmt.Println("CurrentDay:", day.String())
+// checkRequiredProcesses notifies the parent once a process required to be
+// present has been missing for MissingScansBeforeAlert consecutive scans,
+// and again when it shows up after that.
+func (c *dadController) checkRequiredProcesses(processes []runningProcess) {
+	for _, a := range c.Activities {
+		if !a.RequirePresent || !a.activeOn(c.now()) {
+			continue
+		}
 
-	ad, found := c.ActivityDuration[day]
-	if !found {
-		return
-	}
+		missing := c.MissingRequiredScans[a.Name]
+		if len(a.matchingProcesses(processes)) > 0 {
+			if missing >= a.missingScansBeforeAlert() {
+				c.notify(fmt.Sprintf("%s is running again", a.Name))
+			}
+			delete(c.MissingRequiredScans, a.Name)
+			continue
+		}
 
-	for a, d := range ad {
-		fmt.Printf("  Activity: [%s] = %s\n", a, time.Duration(d).String())
+		missing++
+		if c.MissingRequiredScans == nil {
+			c.MissingRequiredScans = make(map[string]int)
+		}
+		c.MissingRequiredScans[a.Name] = missing
+		if missing == a.missingScansBeforeAlert() {
+			c.notify(fmt.Sprintf("%s has not been running for %d scans", a.Name, missing))
+		}
 	}
+}
 
-	fmt.Println("===================================================")
+// enabled tells whether the rule is switched on.
+func (a *activityRule) enabled() bool {
+	return a.Enabled == nil || *a.Enabled
 }
 
-func (c *dadController) controlActivities(rp map[string][]runningProcess) {
-	day := c.LastControlTime.Weekday()
-	dayTime := c.LastControlTime.Hour()*100 + c.LastControlTime.Minute()
+func (a *activityRule) missingScansBeforeAlert() int {
+	if a.MissingScansBeforeAlert <= 0 {
+		return defaultMissingScansBeforeAlert
+	}
+	return a.MissingScansBeforeAlert
+}
 
-	ad, found := c.ActivityDuration[day]
-	if !found {
-		// should never happen
+func (c *dadController) updateActivityCounters(rp map[string][]runningProcess, now time.Time) {
+	if !sameDay(now, c.LastControlTime) {
+		// change of day detected, reset of counters
+		c.backUpState()
+		c.resetAfterOfflineGap(c.LastControlTime, now)
+		c.rollOver(c.LastControlTime, now)
+		c.forgetOldDays(now)
+		c.warned = nil
+		c.forgetProcessSessions(now)
+	}
+	c.WeeklyDuration.startAt(c.weekStart(now))
+	c.MonthlyDuration.startAt(monthStart(now))
+	previous := c.LastControlTime
+	c.LastControlTime = now
+	c.credited = c.creditedInterval(previous, now)
+	c.expireProbation(now)
+	c.expireExemptions(now)
+	c.expireOverrides(now)
+	c.expireCredits(now)
+
+	if c.isIdle() {
+		c.dumpActivitiesDuration()
 		return
 	}
 
-	fmt.Println("============  Controlling Activities ==============")
-	for activity := range rp {
-		a := c.getOrCreateActivityRule(activity)
-
-		schedule, found := a.AllowedSchedules[day]
-		if !found {
-			fmt.Printf("/!\\ %s activity not allowed to run on %s\n", activity, day.String())
-			c.KillRunningProcesses(activity, rp[activity], "Activity not allowed to be done on this day")
-			continue
+	// update duration counters of each user running the activity
+	shares := make(map[string]map[string]float64)
+	var entries []journalEntry
+	for activity, processes := range rp {
+		users, userProcesses := processesPerUser(processes)
+		for _, user := range users {
+			a := c.ruleOf(user, activity)
+			active := c.activeProcesses(a, userProcesses[user])
+			if len(active) == 0 {
+				fmt.Fprintf(logOutput, "Activity %s is idle, not counting it\n", activity)
+				continue
+			}
+			interval := runningInterval(active, now, c.credited)
+			credit := duration(interval)
+			if a != nil && a.CreditWithinPeriods {
+				credit = duration(c.creditWithinPeriods(a, now, interval))
+			}
+			if shares[user] == nil {
+				shares[user] = c.attributionShares(rp, user)
+			}
+			credited := duration(float64(credit) * shares[user][activity])
+			ad := c.dayDurationsOf(user)
+			ad[activity] = ad[activity] + credited
+			c.WeeklyDuration.add(user, activity, credited)
+			c.MonthlyDuration.add(user, activity, credited)
+			entries = append(entries, journalEntry{Time: now, User: user, Activity: activity, Credited: credited})
+			if a != nil {
+				c.trackSession(a, user, credited, previous, now)
+			}
+			for _, p := range active {
+				c.trackProcessSession(activity, user, p, runningInterval([]runningProcess{p}, now, c.credited), previous, now)
+			}
 		}
+	}
+	c.journal(entries)
 
-		if ad[activity] > schedule.MaxDuration {
-			fmt.Printf("/!\\ %s activity is above max duration %s for %s (currently %s)\n", activity, time.Duration(schedule.MaxDuration).String(), day.String(), time.Duration(ad[activity]).String())
-			c.KillRunningProcesses(activity, rp[activity], "Activity duration above threshold for this day")
-			continue
-		}
+	c.dumpActivitiesDuration()
+}
 
-		// TODO warning duration
+// processKey identifies p, the path telling apart a process reusing the pid
+// of another one and the distribution a WSL process sharing the pid of a
+// Windows one.
+func processKey(p runningProcess) string {
+	if p.Distribution != "" {
+		return fmt.Sprintf("%d|%s|%s", p.Pid, p.Path, p.Distribution)
+	}
+	return fmt.Sprintf("%d|%s", p.Pid, p.Path)
+}
 
-		foundValidPeriod := false
-		for _, ap := range schedule.AllowedPeriods {
-			if dayTime >= ap.Begin && dayTime < ap.End {
-				foundValidPeriod = true
-			}
+// runningInterval returns how long processes ran during the interval ending
+// at now, which is shorter than interval when all of them were started
+// during it.
+func runningInterval(processes []runningProcess, now time.Time, interval time.Duration) time.Duration {
+	var running time.Duration
+	for _, p := range processes {
+		if p.StartTime.IsZero() {
+			return interval
 		}
-
-		if !foundValidPeriod {
-			fmt.Printf("/!\\ %s activity is not allowed to run at this time\n", activity)
-			c.KillRunningProcesses(activity, rp[activity], "Activity not allowed to be done during this time range")
-			continue
+		if d := now.Sub(p.StartTime); d > running {
+			running = d
 		}
 	}
-	fmt.Println("===================================================")
+	if running < 0 {
+		return 0
+	}
+	if running < interval {
+		return running
+	}
+	return interval
 }
 
-func getRunningProcesses() []runningProcess {
-	fmt.Println("Scanning running processes ...")
-	cmd := exec.Command("powershell", "-Command", "& { ps | Select-Object Id,Path | ?{$_.Path -ne $null} | convertto-json }")
-
-	cmdOut, err := cmd.StdoutPipe()
-	if err != nil {
-		panic(err)
+// creditWithinPeriods returns how much of the interval ending at now
+// overlaps the allowed periods of the activity of a, or its spendable window
+// when it has no allowed periods. Periods being wall clock times, the
+// interval is split at each minute and a minute is credited when its wall
+// clock time falls in a period, the way the policies check them, so that an
+// hour repeated or skipped by a change of clock is neither credited twice
+// nor missed.
+func (c *dadController) creditWithinPeriods(a *activityRule, now time.Time, interval time.Duration) time.Duration {
+	resolved := c.effectiveSchedule(a, now)
+	periods := resolved.AllowedPeriods
+	if len(periods) == 0 && resolved.SpendableWindow != nil {
+		periods = []timePeriod{*resolved.SpendableWindow}
 	}
 
-	err = cmd.Start()
-	if err != nil {
-		panic(err)
+	start := now.Add(-interval)
+	if midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()); start.Before(midnight) {
+		start = midnight
 	}
 
-	data, err := ioutil.ReadAll(cmdOut)
-	if err != nil {
-		panic(err)
+	var credit time.Duration
+	for t := start; t.Before(now); {
+		next := t.Truncate(time.Minute).Add(time.Minute)
+		if next.After(now) {
+			next = now
+		}
+		dayTime := t.Hour()*100 + t.Minute()
+		for _, p := range periods {
+			if dayTime >= p.Begin && dayTime < p.End {
+				credit += next.Sub(t)
+				break
+			}
+		}
+		t = next
 	}
+	return credit
+}
 
-	var processes []runningProcess
-	if err := json.Unmarshal(data, &processes); err != nil {
-		panic(err)
+// timeOfDay returns the time of the day of date at hhmm, e.g. 2130.
+func timeOfDay(date time.Time, hhmm int) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), hhmm/100, hhmm%100, 0, 0, date.Location())
+}
+
+func (c *dadController) dumpActivitiesDuration() {
+	fmt.Fprintln(logOutput, "================= Current State ===================")
+	day := dateKey(c.LastControlTime)
+	fmt.Fprintln(logOutput, "LastControlTime: ", c.LastControlTime)
+	fmt.Fprintln(logOutput, "CurrentDay:", day)
+
+	for a, d := range c.ActivityDuration[day] {
+		fmt.Fprintf(logOutput, "  Activity: [%s] = %s\n", a, time.Duration(d).String())
+	}
+	for user, durations := range c.UserActivityDuration {
+		for a, d := range durations[day] {
+			fmt.Fprintf(logOutput, "  User: [%s] Activity: [%s] = %s\n", user, a, time.Duration(d).String())
+		}
 	}
 
-	fmt.Printf("Found %d running processes\n", len(processes))
+	fmt.Fprintln(logOutput, "===================================================")
+}
+
+// controlActivities decides what to do with the running processes of each
+// activity at the time now, which is the time of the last control except
+// when previewing.
+func (c *dadController) controlActivities(rp map[string][]runningProcess, now time.Time) []enforcementAction {
+	day := dateKey(now)
 
-	return processes
+	activities := make([]string, 0, len(rp))
+	for activity := range rp {
+		activities = append(activities, activity)
+	}
+	sort.Strings(activities)
+
+	var actions []enforcementAction
+	c.exhaustion = time.Time{}
+	fmt.Fprintln(logOutput, "============  Controlling Activities ==============")
+	for _, activity := range activities {
+		var enforced []runningProcess
+		for _, p := range rp[activity] {
+			if !c.isExempt(p, now) {
+				enforced = append(enforced, p)
+			}
+		}
+
+		users, processes := processesPerUser(enforced)
+		for _, user := range users {
+			a := c.ruleOf(user, activity)
+			if a == nil {
+				a = c.getOrCreateActivityRule(activity)
+			}
+			ctx := c.decisionContext(a, user, now)
+			ctx.Processes = processes[user]
+
+			decision, reason := c.decide(ctx)
+			if decision == actionKill {
+				if reminder, found := c.graceReminder(ctx); found {
+					actions = append(actions, reminder)
+					continue
+				}
+			}
+			if decision = enforcedDecision(a, decision); decision != actionAllow {
+				fmt.Fprintf(logOutput, "/!\\ %s activity (%s spent on %s) : %s\n", activity, ctx.Used.String(), day, reason)
+				actions = append(actions, enforcementAction{Activity: activity, User: user, Processes: processes[user], Action: decision, Reason: reason})
+			} else {
+				if reminder, found := c.reminder(ctx); found {
+					actions = append(actions, reminder)
+				}
+				if remaining := ctx.remaining(); ctx.Schedule != nil && remaining > 0 {
+					if t := now.Add(remaining + time.Second); c.exhaustion.IsZero() || t.Before(c.exhaustion) {
+						c.exhaustion = t
+					}
+				}
+			}
+		}
+	}
+	fmt.Fprintln(logOutput, "===================================================")
+	return actions
 }
 
 func warn(activity string, rp []runningProcess, reason string) {
 
 }
 
-func kill(activity string, rp []runningProcess, reason string) {
-	fmt.Printf("Killing activity %s\n", activity)
+func notifyParent(message string) {
+	fmt.Fprintf(logOutput, "[Notification] %s\n", message)
+}
+
+// kill terminates the processes of activity using the kill signal of its
+// rule, or asks them to close first when the rule has a close timeout, along
+// with their descendants when the rule kills process trees.
+func (c *dadController) kill(activity string, rp []runningProcess, reason string) {
+	signal := ""
+	var closeTimeout time.Duration
+	if a := c.ruleOf(ownerOf(rp), activity); a != nil {
+		signal = a.KillSignal
+		closeTimeout = time.Duration(a.CloseTimeout)
+		if a.KillTree {
+			processes, err := c.listProcesses()
+			if err != nil {
+				fmt.Fprintln(logOutput, "Failure to list descendant processes : ", err)
+			} else {
+				rp = append(rp, descendants(processes, rp)...)
+			}
+		}
+	}
+
+	fmt.Fprintf(logOutput, "Killing activity %s\n", activity)
+	c.countKill(activity, rp, c.now())
+	if closeTimeout > 0 {
+		c.closeThenKill(activity, rp, closeTimeout)
+		return
+	}
 	for _, p := range rp {
-		fmt.Printf("Killing process %d, %s\n", p.Pid, p.Path)
-		cmd := exec.Command("powershell", "-Command", fmt.Sprintf("& { Stop-Process -Id %d }", p.Pid))
-		if err := cmd.Run(); err != nil {
-			fmt.Printf("Failure to kill process %d : %s\n", p.Pid, err)
+		fmt.Fprintf(logOutput, "Killing process %d, %s\n", p.Pid, p.Path)
+		if err := c.Processes.Kill(p, signal); err != nil {
+			fmt.Fprintf(logOutput, "Failure to kill process %d : %s\n", p.Pid, err)
 		}
 	}
 }
 
 func (c *dadController) reloadStateIfExist() {
-	_, err := os.Stat("dad-controller.state")
-	if os.IsNotExist(err) {
-		return
-	} else if err != nil {
-		fmt.Println("Failure to stat state file : ", err)
+	if c.stateStore() == nil {
 		return
 	}
-
-	fmt.Println("Found state file, reloading it")
-
-	file, err := os.Open("dad-controller.state")
-	if err != nil {
-		fmt.Println("Failure to open state file : ", err)
-		return
+	var since time.Time
+	if c.reloadState() {
+		since = c.LastControlTime
 	}
-	defer file.Close()
+	c.replayJournal(since)
+}
 
-	data, err := ioutil.ReadAll(file)
+// reloadState restores the state saved in the state store, telling whether
+// it could.
+func (c *dadController) reloadState() bool {
+	data, err := c.stateStore().Load()
 	if err != nil {
-		fmt.Println("Failure to read state file : ", err)
-		return
+		fmt.Fprintln(logOutput, "Failure to read state file : ", err)
+		return false
+	}
+	if data == nil {
+		return false
 	}
 
+	fmt.Fprintln(logOutput, "Found state file, reloading it")
+
 	var tmpCtrl dadController
 	err = json.Unmarshal(data, &tmpCtrl)
 	if err != nil {
-		fmt.Println("Failure to parse state file : ", err)
-		return
+		fmt.Fprintln(logOutput, "Failure to parse state file : ", err)
+		return false
 	}
 
 	c.LastControlTime = tmpCtrl.LastControlTime
 	c.ActivityDuration = tmpCtrl.ActivityDuration
+	c.UserActivityDuration = tmpCtrl.UserActivityDuration
+	c.migrateState(tmpCtrl.StateVersion)
+	c.LockdownUntil = tmpCtrl.LockdownUntil
+	c.MissingRequiredScans = tmpCtrl.MissingRequiredScans
+	c.ProbationFactor = tmpCtrl.ProbationFactor
+	c.ProbationUntil = tmpCtrl.ProbationUntil
+	c.Exemptions = tmpCtrl.Exemptions
+	c.Suspended = tmpCtrl.Suspended
+	c.UnmanagedDuration = tmpCtrl.UnmanagedDuration
+	c.LastDiscoveryReport = tmpCtrl.LastDiscoveryReport
+	c.Violations = tmpCtrl.Violations
+	c.Kills = tmpCtrl.Kills
+	c.WeeklyDuration = tmpCtrl.WeeklyDuration
+	c.MonthlyDuration = tmpCtrl.MonthlyDuration
+	c.Overrides = tmpCtrl.Overrides
+	c.Sessions = tmpCtrl.Sessions
+	c.ProcessSessions = tmpCtrl.ProcessSessions
+	c.Credits = tmpCtrl.Credits
+	c.Banked = tmpCtrl.Banked
+	c.ActiveProfile = tmpCtrl.ActiveProfile
+	if tmpCtrl.ConfigSigned && c.signingKey == nil {
+		c.refuseConfigWithoutKey()
+	}
+	if tmpCtrl.SamplingIntervalOverride > 0 {
+		c.SamplingIntervalOverride = tmpCtrl.SamplingIntervalOverride
+		c.SamplingInterval = duration(clampSamplingInterval(time.Duration(tmpCtrl.SamplingIntervalOverride)))
+	}
 	c.dumpActivitiesDuration()
+	return true
 }
 
 func (c *dadController) dumpState() {
+	store := c.stateStore()
+	if store == nil {
+		return
+	}
+
+	c.StateVersion = stateVersion
 	data, err := json.Marshal(c)
 	if err != nil {
-		fmt.Println("Failure to serialize controller state to json : ", err)
+		fmt.Fprintln(logOutput, "Failure to serialize controller state to json : ", err)
 		return
 	}
 
-	err = ioutil.WriteFile("dad-controller.state", data, 0644)
+	err = store.SaveCounters(data)
 	if err != nil {
-		fmt.Println("Failure to write data to state file : ", err)
+		fmt.Fprintln(logOutput, "Failure to write data to state file : ", err)
 	}
 }
 
 func main() {
-	ctrl := newDadControllerWithConfigFile("dad-controller.json")
+	dumpConfigFlag := flag.Bool("dump-config", false, "print the resolved configuration and exit")
+	selfTestFlag := flag.Bool("selftest", false, "check that processes can be listed and killed, then exit")
+	initFlag := flag.Bool("init", false, "interactively generate a starter configuration and exit")
+	fakeNowFlag := flag.String("fake-now", "", "pin the current time, e.g. \"2024-06-02 20:05\", for demos")
+	serviceFlag := flag.String("service", "", "install, uninstall, start or stop the Windows service and exit")
+	daemonFlag := flag.Bool("daemon", false, "run under systemd, notifying it when ready and saving the state on SIGTERM")
+	systemdUnitFlag := flag.Bool("systemd-unit", false, "print a systemd unit running this executable as a daemon and exit")
+	configFlag := flag.String("config", "", "configuration file or https:// URL, "+configFileName+" of the working directory if any, else of "+systemConfigDir())
+	stateFlag := flag.String("state", "", "state file, "+stateFileName+" next to the configuration of the working directory if any, else in "+systemStateDir())
+	flag.Parse()
+
+	if runAsServiceIfNeeded(*configFlag, *stateFlag) {
+		return
+	}
+
+	if *serviceFlag != "" {
+		if err := controlService(*serviceFlag, *configFlag, *stateFlag); err != nil {
+			fmt.Fprintln(os.Stderr, "Failure to "+*serviceFlag+" the service : ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	configFile, stateFile := resolvePaths(*configFlag, *stateFlag)
+	if flag.Arg(0) == "validate" {
+		path := configFile
+		if flag.NArg() > 1 {
+			path = flag.Arg(1)
+		}
+		if !validateConfig(os.Stdout, path) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "export" {
+		// keep the output for the CSV
+		logOutput = os.Stderr
+		if err := exportUsage(os.Stdout, stateFile, flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, "Failure to export the usage : ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "sign" {
+		if err := signConfig(os.Stdout, configFile, keyFile(stateFile)); err != nil {
+			fmt.Fprintln(os.Stderr, "Failure to sign the configuration : ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *initFlag {
+		if err := initConfig(configFile, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "Failure to generate the configuration : ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *systemdUnitFlag {
+		if err := printSystemdUnit(); err != nil {
+			fmt.Fprintln(os.Stderr, "Failure to generate the systemd unit : ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *selfTestFlag {
+		if !reportSelfTest(os.Stdout, runSelfTest(defaultSelfTestProviders())) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *dumpConfigFlag {
+		if err := dumpConfig(os.Stdout, configFile); err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid configuration : ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if isSQLiteState(stateFile) && !sqliteSupported {
+		fmt.Fprintln(os.Stderr, "Invalid -state : ", errSQLiteUnsupported)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		fmt.Fprintln(os.Stderr, "Failure to create the state directory : ", err)
+	}
+	configFile, remote := localConfigFile(configFile, stateFile)
+	ctrl := newDadControllerWithFiles(configFile, stateFile)
+	ctrl.remote = remote
+	if *fakeNowFlag != "" {
+		fakeNow, err := parseFakeNow(*fakeNowFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Invalid -fake-now : ", err)
+			os.Exit(1)
+		}
+		ctrl.PinNow(fakeNow)
+		// a demo must not leave counters of a made-up day in the real state
+		ctrl.Store = &memoryStateStore{}
+	}
 
 	ctrl.reloadStateIfExist()
-	for {
-		ctrl.reloadConfIfNeeded()
-		time.Sleep(time.Duration(ctrl.SamplingInterval))
-		ctrl.scan()
-		ctrl.dumpState()
+	if ctrl.HTTPListen != "" {
+		go ctrl.serveHTTP(ctrl.HTTPListen)
+	}
+	if ctrl.WatchProcessStarts {
+		go ctrl.watchProcessStarts()
+	}
+	if *daemonFlag {
+		ctrl.runDaemon()
+	} else {
+		ctrl.run()
 	}
 }
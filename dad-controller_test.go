@@ -1,10 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -14,7 +20,88 @@ type TestContext struct {
 	controller       *dadController
 	currentTime      time.Time
 	runningProcesses []runningProcess
+	windowTitles     map[int][]string
+	foregroundPid    int
 	killedProcesses  []string
+	closedProcesses  []string
+	// suspendedProcesses are the processes suspended and not resumed yet
+	suspendedProcesses []string
+	// sessions are the sessions locked or logged off, "lock|pid" or
+	// "logoff|pid"
+	sessions      []string
+	actions       []enforcementAction
+	notifications []string
+	timers        []*fakeTimer
+}
+
+// fakeProcessProvider lists the running processes of the test and records
+// the processes it closes, kills or suspends.
+type fakeProcessProvider struct {
+	ctx *TestContext
+}
+
+func (p fakeProcessProvider) List() ([]runningProcess, error) {
+	return p.ctx.runningProcesses, nil
+}
+
+func (p fakeProcessProvider) Kill(rp runningProcess, signal string) error {
+	p.ctx.killedProcesses = append(p.ctx.killedProcesses, fmt.Sprintf("%d|%s", rp.Pid, rp.Path))
+	return nil
+}
+
+func (p fakeProcessProvider) Close(rp runningProcess) error {
+	p.ctx.closedProcesses = append(p.ctx.closedProcesses, fmt.Sprintf("%d|%s", rp.Pid, rp.Path))
+	return nil
+}
+
+func (p fakeProcessProvider) Suspend(rp runningProcess) error {
+	p.ctx.suspendedProcesses = append(p.ctx.suspendedProcesses, fmt.Sprintf("%d|%s", rp.Pid, rp.Path))
+	return nil
+}
+
+func (p fakeProcessProvider) Resume(rp runningProcess) error {
+	var kept []string
+	for _, s := range p.ctx.suspendedProcesses {
+		if s != fmt.Sprintf("%d|%s", rp.Pid, rp.Path) {
+			kept = append(kept, s)
+		}
+	}
+	p.ctx.suspendedProcesses = kept
+	return nil
+}
+
+func (p fakeProcessProvider) LockSession(rp runningProcess) error {
+	p.ctx.sessions = append(p.ctx.sessions, fmt.Sprintf("lock|%d", rp.Pid))
+	return nil
+}
+
+func (p fakeProcessProvider) Logoff(rp runningProcess) error {
+	p.ctx.sessions = append(p.ctx.sessions, fmt.Sprintf("logoff|%d", rp.Pid))
+	return nil
+}
+
+func (p fakeProcessProvider) WindowTitles() (map[int][]string, error) {
+	return p.ctx.windowTitles, nil
+}
+
+func (p fakeProcessProvider) ForegroundProcess() (int, error) {
+	if p.ctx.foregroundPid == 0 {
+		return 0, errors.New("no foreground window")
+	}
+	return p.ctx.foregroundPid, nil
+}
+
+// fakeTimer is armed at a time of the test clock and fired by the test.
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	wasArmed := !t.stopped
+	t.stopped = true
+	return wasArmed
 }
 
 func NewTest(t *testing.T) *TestContext {
@@ -25,10 +112,14 @@ func (ctx *TestContext) GivenADadControllerWithSamplingInterval(samplingInterval
 	getTimeFunc := func() time.Time { return ctx.currentTime }
 	ctx.controller = newDadController(samplingInterval, getTimeFunc)
 	ctx.controller.GetTime = getTimeFunc
-	ctx.controller.KillRunningProcesses = func(activity string, rp []runningProcess, reason string) {
-		for _, p := range rp {
-			ctx.killedProcesses = append(ctx.killedProcesses, fmt.Sprintf("%s|%d|%s|%s", activity, p.Pid, p.Path, reason))
-		}
+	ctx.controller.Processes = fakeProcessProvider{ctx}
+	ctx.controller.NotifyParent = func(message string) {
+		ctx.notifications = append(ctx.notifications, message)
+	}
+	ctx.controller.AfterFunc = func(d time.Duration, f func()) timer {
+		t := &fakeTimer{at: ctx.currentTime.Add(d), f: f}
+		ctx.timers = append(ctx.timers, t)
+		return t
 	}
 	return ctx
 }
@@ -51,6 +142,46 @@ func (ctx *TestContext) GivenAnActivityRuleAllowedEveryDayOnInterval(activity st
 	return ctx
 }
 
+func (ctx *TestContext) GivenAnActivityRuleSpendableEveryDayWithin(activity string, program string, allowedDuration time.Duration, begin int, end int) *TestContext {
+	ar := ctx.controller.getOrCreateActivityRule(activity)
+	ar.AddProgramPattern(program)
+	everyDays := []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	ar.SetMaximumAllowedDurationPerDay(everyDays, allowedDuration)
+	ar.SetSpendableWindow(everyDays, begin, end)
+	return ctx
+}
+
+func (ctx *TestContext) GivenARequiredProcessRule(name string, program string, missingScansBeforeAlert int) *TestContext {
+	ar := ctx.controller.getOrCreateActivityRule(name)
+	ar.AddProgramPattern(program)
+	ar.RequirePresent = true
+	ar.MissingScansBeforeAlert = missingScansBeforeAlert
+	return ctx
+}
+
+func (ctx *TestContext) GivenAnActivityRuleWithCappedPeriods(activity string, program string, allowedDuration time.Duration, periods ...timePeriod) *TestContext {
+	ar := ctx.controller.getOrCreateActivityRule(activity)
+	ar.AddProgramPattern(program)
+	everyDays := []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	ar.SetMaximumAllowedDurationPerDay(everyDays, allowedDuration)
+	for _, d := range everyDays {
+		s := ar.getOrCreateSchedule(d)
+		s.AllowedPeriods = append(s.AllowedPeriods, periods...)
+	}
+	return ctx
+}
+
+func (ctx *TestContext) GivenADenyPeriodOnWeekdays(activity string, begin int, end int) *TestContext {
+	ar := ctx.controller.getOrCreateActivityRule(activity)
+	ar.AddDenyPeriod([]time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, begin, end)
+	return ctx
+}
+
+func (ctx *TestContext) GivenPeriodOverlapResolution(mode string) *TestContext {
+	ctx.controller.PeriodOverlap = mode
+	return ctx
+}
+
 func (ctx *TestContext) GivenAnActivityRuleAllowedOnlyOnSunday(activity string, program string, allowedDuration time.Duration) *TestContext {
 	ar := ctx.controller.getOrCreateActivityRule(activity)
 	ar.AddProgramPattern(program)
@@ -60,6 +191,11 @@ func (ctx *TestContext) GivenAnActivityRuleAllowedOnlyOnSunday(activity string,
 	return ctx
 }
 
+func (ctx *TestContext) GivenAPolicy(p Policy) *TestContext {
+	ctx.controller.Policies = append(ctx.controller.Policies, p)
+	return ctx
+}
+
 func (ctx *TestContext) GivenAnActivityDuration(activity string, duration time.Duration) *TestContext {
 	ctx.controller.updateActivityDuration(activity, duration)
 	return ctx
@@ -67,7 +203,61 @@ func (ctx *TestContext) GivenAnActivityDuration(activity string, duration time.D
 
 func (ctx *TestContext) GivenARunningProcess(path string, pid int) *TestContext {
 	ctx.runningProcesses = append(ctx.runningProcesses, runningProcess{Path: path, Pid: pid})
-	ctx.controller.GetRunningProcesses = func() []runningProcess { return ctx.runningProcesses }
+	return ctx
+}
+
+func (ctx *TestContext) GivenARunningProcessOwnedBy(path string, pid int, user string) *TestContext {
+	ctx.runningProcesses = append(ctx.runningProcesses, runningProcess{Path: path, Pid: pid, UserID: user})
+	return ctx
+}
+
+func (ctx *TestContext) GivenARunningProcessOfAccount(path string, pid int, account string) *TestContext {
+	ctx.runningProcesses = append(ctx.runningProcesses, runningProcess{Path: path, Pid: pid, UserID: account, User: account})
+	return ctx
+}
+
+func (ctx *TestContext) GivenARunningProcessWithCommandLine(path string, pid int, commandLine string) *TestContext {
+	ctx.runningProcesses = append(ctx.runningProcesses, runningProcess{Path: path, Pid: pid, CommandLine: commandLine})
+	return ctx
+}
+
+func (ctx *TestContext) GivenAWindow(pid int, title string) *TestContext {
+	if ctx.windowTitles == nil {
+		ctx.windowTitles = make(map[int][]string)
+	}
+	ctx.windowTitles[pid] = append(ctx.windowTitles[pid], title)
+	return ctx
+}
+
+func (ctx *TestContext) GivenARunningProcessStartedAt(path string, pid int, start time.Time) *TestContext {
+	ctx.runningProcesses = append(ctx.runningProcesses, runningProcess{Path: path, Pid: pid, StartTime: start})
+	return ctx
+}
+
+func (ctx *TestContext) GivenAChildProcess(path string, pid int, parent int) *TestContext {
+	ctx.runningProcesses = append(ctx.runningProcesses, runningProcess{Path: path, Pid: pid, ParentPid: parent})
+	return ctx
+}
+
+func (ctx *TestContext) GivenNoRunningProcess() *TestContext {
+	ctx.runningProcesses = nil
+	return ctx
+}
+
+func (ctx *TestContext) GivenAUserActivityDuration(user string, activity string, duration time.Duration) *TestContext {
+	ctx.controller.updateUserActivityDuration(user, activity, duration)
+	return ctx
+}
+
+func (ctx *TestContext) WhenControllerRestarts() *TestContext {
+	restarted := newDadController(time.Duration(ctx.controller.SamplingInterval), ctx.controller.GetTime)
+	restarted.stateFile = ctx.controller.stateFile
+	restarted.Activities = ctx.controller.Activities
+	restarted.Processes = ctx.controller.Processes
+	restarted.AfterFunc = ctx.controller.AfterFunc
+	ctx.controller.dumpState()
+	restarted.reloadStateIfExist()
+	ctx.controller = restarted
 	return ctx
 }
 
@@ -79,8 +269,104 @@ func (ctx *TestContext) WhenDayChanges() *TestContext {
 
 func (ctx *TestContext) WhenScanHappens() *TestContext {
 	ctx.killedProcesses = []string{}
+	ctx.controller.events = nil
 	ctx.currentTime = ctx.currentTime.Add(time.Duration(ctx.controller.SamplingInterval))
-	ctx.controller.scan()
+	ctx.actions = ctx.controller.scan()
+	return ctx
+}
+
+func (ctx *TestContext) armedTimer() *fakeTimer {
+	for _, t := range ctx.timers {
+		if !t.stopped {
+			return t
+		}
+	}
+	return nil
+}
+
+func (ctx *TestContext) ThenATimerShouldBeArmedAt(expected time.Time) *TestContext {
+	if t := ctx.armedTimer(); t == nil || !t.at.Equal(expected) {
+		ctx.t.Errorf("Timer armed at %v (expected %s)", t, expected)
+	}
+	return ctx
+}
+
+func (ctx *TestContext) ThenNoTimerShouldBeArmed() *TestContext {
+	if t := ctx.armedTimer(); t != nil {
+		ctx.t.Errorf("Timer armed at %s", t.at)
+	}
+	return ctx
+}
+
+func (ctx *TestContext) WhenTimerFires() *TestContext {
+	t := ctx.armedTimer()
+	if t == nil {
+		ctx.t.Fatal("No timer armed")
+	}
+	t.stopped = true
+	ctx.killedProcesses = []string{}
+	ctx.controller.events = nil
+	ctx.currentTime = t.at
+	t.f()
+	return ctx
+}
+
+func (ctx *TestContext) WhenBoundaryCheckIsRescheduled() *TestContext {
+	ctx.controller.scheduleBoundaryCheck(ctx.currentTime)
+	return ctx
+}
+
+// WhenStoppedTimerFires runs the callback of the last timer stopped, as
+// time.AfterFunc does when Stop comes too late.
+func (ctx *TestContext) WhenStoppedTimerFires() *TestContext {
+	var stopped *fakeTimer
+	for _, t := range ctx.timers {
+		if t.stopped {
+			stopped = t
+		}
+	}
+	if stopped == nil {
+		ctx.t.Fatal("No timer stopped")
+	}
+	ctx.killedProcesses = []string{}
+	ctx.controller.events = nil
+	ctx.currentTime = stopped.at
+	stopped.f()
+	return ctx
+}
+
+func (ctx *TestContext) WhenSamplingIntervalIsSetTo(samplingInterval time.Duration) *TestContext {
+	ctx.controller.SetSamplingInterval(samplingInterval)
+	return ctx
+}
+
+func (ctx *TestContext) ThenSamplingIntervalShouldBe(expected time.Duration) *TestContext {
+	if d := time.Duration(ctx.controller.SamplingInterval); d != expected {
+		ctx.t.Errorf("Sampling interval is %s (expected %s)\n", d, expected)
+	}
+	return ctx
+}
+
+func (ctx *TestContext) WhenLockdownStartsFor(d time.Duration) *TestContext {
+	ctx.controller.Lockdown(d)
+	return ctx
+}
+
+func (ctx *TestContext) WhenProcessIsExemptedFor(path string, pid int, d time.Duration) *TestContext {
+	ctx.controller.Exempt(runningProcess{Path: path, Pid: pid}, d)
+	return ctx
+}
+
+func (ctx *TestContext) WhenProbationIsSet(factor float64, d time.Duration) *TestContext {
+	ctx.controller.SetProbation(factor, ctx.currentTime.Add(d))
+	return ctx
+}
+
+func (ctx *TestContext) ThenEffectiveMaxDurationShouldBe(activity string, expected time.Duration) *TestContext {
+	resolved := ctx.controller.EffectiveScheduleFor(activity, ctx.currentTime)
+	if time.Duration(resolved.MaxDuration) != expected {
+		ctx.t.Errorf("Activity %s effective max duration is %s (expected %s)\n", activity, time.Duration(resolved.MaxDuration), expected)
+	}
 	return ctx
 }
 
@@ -92,11 +378,45 @@ func (ctx *TestContext) ThenActivityExecutionDurationShouldBe(activity string, e
 	return ctx
 }
 
+func (ctx *TestContext) ThenUserActivityExecutionDurationShouldBe(user string, activity string, expectedDuration time.Duration) *TestContext {
+	activityDuration := ctx.controller.GetUserActivityDuration(user, activity)
+	if activityDuration != expectedDuration {
+		ctx.t.Errorf("Activity %s execution duration for %s is %s (expected %s)\n", activity, user, activityDuration, expectedDuration)
+	}
+	return ctx
+}
+
+func (ctx *TestContext) GivenTimeIsPinnedAt(t time.Time) *TestContext {
+	ctx.controller.PinNow(t)
+	return ctx
+}
+
 func (ctx *TestContext) GivenTimeIs(t time.Time) *TestContext {
 	ctx.currentTime = t
 	return ctx
 }
 
+func (ctx *TestContext) WhenPreviewHappens() *TestContext {
+	ctx.killedProcesses = []string{}
+	ctx.currentTime = ctx.currentTime.Add(time.Duration(ctx.controller.SamplingInterval))
+	ctx.actions = ctx.controller.preview()
+	return ctx
+}
+
+func (ctx *TestContext) ThenActionsShouldBe(expected ...enforcementAction) *TestContext {
+	if len(ctx.actions) != len(expected) || (len(expected) > 0 && !reflect.DeepEqual(ctx.actions, expected)) {
+		ctx.t.Errorf("Scan decided %+v (expected %+v)", ctx.actions, expected)
+	}
+	return ctx
+}
+
+func (ctx *TestContext) ThenParentShouldHaveBeenNotified(expected ...string) *TestContext {
+	if len(ctx.notifications) != len(expected) || (len(expected) > 0 && !reflect.DeepEqual(ctx.notifications, expected)) {
+		ctx.t.Errorf("Parent notified of %q (expected %q)", ctx.notifications, expected)
+	}
+	return ctx
+}
+
 func (ctx *TestContext) ThenNoProcessKilled() *TestContext {
 	if len(ctx.killedProcesses) > 0 {
 		ctx.t.Error("Some processes have been killed")
@@ -104,8 +424,15 @@ func (ctx *TestContext) ThenNoProcessKilled() *TestContext {
 	return ctx
 }
 
+func (ctx *TestContext) ThenSuspendedProcessesShouldBe(expected ...string) *TestContext {
+	if !reflect.DeepEqual(ctx.suspendedProcesses, expected) && len(ctx.suspendedProcesses)+len(expected) > 0 {
+		ctx.t.Errorf("suspended %q (expected %q)", ctx.suspendedProcesses, expected)
+	}
+	return ctx
+}
+
 func (ctx *TestContext) ThenProcessIsKilled(activity string, pid int, path string, reason string) *TestContext {
-	info := fmt.Sprintf("%s|%d|%s|%s", activity, pid, path, reason)
+	info := fmt.Sprintf("%d|%s", pid, path)
 	found := false
 	for _, k := range ctx.killedProcesses {
 		if k == info {
@@ -116,6 +443,18 @@ func (ctx *TestContext) ThenProcessIsKilled(activity string, pid int, path strin
 	if !found {
 		ctx.t.Errorf("%s not found in list of processes killed", info)
 	}
+
+	event := fmt.Sprintf("%s killed : %s", activity, reason)
+	found = false
+	for _, e := range ctx.controller.events {
+		if e.Message == event {
+			found = true
+			break
+		}
+	}
+	if !found {
+		ctx.t.Errorf("%s not found in events", event)
+	}
 	return ctx
 }
 
@@ -138,6 +477,25 @@ func TestActivityCountersMustBeResettedWhenChangingDay(t *testing.T) {
 		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(0)*time.Minute)
 }
 
+func TestProcessMatchedBySeveralPatternsIsKilledOnce(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(15)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+
+	processes, _ := ctx.controller.Processes.List()
+	if running := ctx.controller.getRunningProcessesPerActivity(processes); len(running["GTA"]) != 1 {
+		t.Errorf("GTA processes are %v", running["GTA"])
+	}
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this day")
+	if len(ctx.killedProcesses) != 1 {
+		t.Errorf("killed %q", ctx.killedProcesses)
+	}
+}
+
 func TestRunningProcessIsKilledIfRunningOnANonAllowedDay(t *testing.T) {
 	notSunday := time.Now()
 	if notSunday.Weekday() == time.Sunday {
@@ -173,19 +531,1186 @@ func TestRunningProcessIsKilledIfRunningOutsideOfAllowedPeriods(t *testing.T) {
 	afterPeriod := time.Date(now.Year(), now.Month(), now.Day(), 21, 0, 0, 0, time.Local)
 
 	NewTest(t).
+		GivenTimeIs(beforePeriod).
 		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
 		GivenAnActivityRuleAllowedEveryDayOnInterval("GTA", "GTA.exe", time.Duration(15)*time.Minute, 2000, 2100).
-		GivenTimeIs(beforePeriod).
 		GivenARunningProcess("C:\\GTA.exe", 1).
 		WhenScanHappens().
 		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1)*time.Minute).
 		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity not allowed to be done during this time range").
 		GivenTimeIs(afterPeriod).
 		WhenScanHappens().
-		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(2)*time.Minute).
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1)*time.Minute+defaultMaxCreditedInterval).
 		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity not allowed to be done during this time range")
 }
 
+func TestDenyPeriodOverridesAnAllowingSchedule(t *testing.T) {
+	monday := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.Local)
+
+	NewTest(t).
+		GivenTimeIs(monday).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Hour).
+		GivenADenyPeriodOnWeekdays("GTA", 830, 1530).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Blocked during restricted hours").
+		GivenTimeIs(monday.Add(time.Duration(330) * time.Minute)).
+		WhenScanHappens().
+		ThenNoProcessKilled()
+}
+
+func TestDenyPeriodOnlyAppliesToItsDays(t *testing.T) {
+	sunday := time.Date(2024, time.June, 2, 10, 0, 0, 0, time.Local)
+
+	NewTest(t).
+		GivenTimeIs(sunday).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Hour).
+		GivenADenyPeriodOnWeekdays("GTA", 830, 1530).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenNoProcessKilled()
+}
+
+func TestPeriodEndIsEnforcedAtTheBoundaryRatherThanAtTheNextScan(t *testing.T) {
+	now := time.Now()
+	boundary := time.Date(now.Year(), now.Month(), now.Day(), 21, 0, 0, 0, time.Local)
+
+	NewTest(t).
+		GivenTimeIs(boundary.Add(time.Duration(-75)*time.Second)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryDayOnInterval("GTA", "GTA.exe", time.Duration(15)*time.Minute, 2000, 2100).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenATimerShouldBeArmedAt(boundary).
+		WhenTimerFires().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity not allowed to be done during this time range").
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1)*time.Minute).
+		ThenNoTimerShouldBeArmed()
+}
+
+func TestBudgetRunningOutIsEnforcedToTheSecond(t *testing.T) {
+	last := time.Date(2024, time.October, 14, 17, 0, 0, 0, time.Local)
+
+	NewTest(t).
+		GivenTimeIs(last.Add(-time.Minute)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(20)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(1130)*time.Second).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenATimerShouldBeArmedAt(last.Add(time.Duration(11)*time.Second)).
+		WhenTimerFires().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1201)*time.Second).
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this day")
+}
+
+func TestTimerStoppedTooLateDoesNothing(t *testing.T) {
+	last := time.Date(2024, time.October, 14, 17, 0, 0, 0, time.Local)
+
+	NewTest(t).
+		GivenTimeIs(last.Add(-time.Minute)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(20)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(1130)*time.Second).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		WhenBoundaryCheckIsRescheduled().
+		WhenStoppedTimerFires().
+		ThenNoProcessKilled().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1190)*time.Second).
+		ThenATimerShouldBeArmedAt(last.Add(time.Duration(11)*time.Second)).
+		WhenTimerFires().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this day")
+}
+
+func TestNoTimerIsArmedWhenTheNextScanComesFirst(t *testing.T) {
+	now := time.Now()
+
+	NewTest(t).
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 20, 30, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryDayOnInterval("GTA", "GTA.exe", time.Duration(15)*time.Minute, 2000, 2100).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenNoTimerShouldBeArmed()
+}
+
+func TestSamplingIntervalChangedAtRuntimeIsUsedByNextScan(t *testing.T) {
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1)*time.Minute).
+		WhenSamplingIntervalIsSetTo(time.Duration(2)*time.Minute).
+		ThenSamplingIntervalShouldBe(time.Duration(2)*time.Minute).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(3)*time.Minute)
+}
+
+func TestSamplingIntervalIsClampedToMinimum(t *testing.T) {
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1) * time.Minute).
+		WhenSamplingIntervalIsSetTo(time.Duration(1) * time.Second).
+		ThenSamplingIntervalShouldBe(minSamplingInterval).
+		WhenSamplingIntervalIsSetTo(time.Duration(-1) * time.Minute).
+		ThenSamplingIntervalShouldBe(minSamplingInterval)
+}
+
+func TestSamplingIntervalChangeInterruptsWaitInProgress(t *testing.T) {
+	ctx := NewTest(t).GivenADadControllerWithSamplingInterval(time.Duration(2) * time.Hour)
+
+	done := make(chan struct{})
+	go func() {
+		ctx.controller.waitNextScan(time.Now().Add(-time.Hour))
+		close(done)
+	}()
+	ctx.controller.SetSamplingInterval(time.Duration(30) * time.Minute)
+
+	select {
+	case <-done:
+	case <-time.After(time.Duration(5) * time.Second):
+		t.Error("wait in progress did not pick up the new sampling interval")
+	}
+}
+
+func TestStopEndsTheScanLoopAndSavesTheState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "dad-controller.json")
+	if err := ioutil.WriteFile(configFile, []byte(`{"samplingInterval": "1h", "rules": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := newDadControllerWithConfigFile(configFile)
+	ctrl.stateFile = filepath.Join(dir, "dad-controller.state")
+	done := make(chan struct{})
+	go func() {
+		ctrl.run()
+		close(done)
+	}()
+	ctrl.Stop()
+	ctrl.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(time.Duration(5) * time.Second):
+		t.Fatal("the scan loop did not stop")
+	}
+	if _, err := os.Stat(ctrl.stateFile); err != nil {
+		t.Errorf("state not saved on stop: %s", err)
+	}
+}
+
+type slowProcessProvider struct {
+	ProcessProvider
+	delay time.Duration
+}
+
+func (p slowProcessProvider) List() ([]runningProcess, error) {
+	time.Sleep(p.delay)
+	return p.ProcessProvider.List()
+}
+
+func TestOverrunningScanSkipsTheWaitAndCreditsTheElapsedTime(t *testing.T) {
+	var log bytes.Buffer
+	defer func(w io.Writer) { logOutput = w }(logOutput)
+	logOutput = &log
+
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(50)*time.Millisecond).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute)
+	slowScan := time.Duration(150) * time.Millisecond
+	ctx.GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Processes = slowProcessProvider{ctx.controller.Processes, slowScan}
+
+	first := time.Now()
+	previous := first
+	for i := 0; i < 3; i++ {
+		start := ctx.controller.scanAfter(previous)
+		if i > 0 && start.Sub(previous) < slowScan {
+			t.Errorf("scan %d started %s after the previous one, before it was over", i, start.Sub(previous))
+		}
+		previous = start
+	}
+
+	ctx.ThenActivityExecutionDurationShouldBe("GTA", previous.Sub(first))
+	if !strings.Contains(log.String(), "longer than the sampling interval") {
+		t.Errorf("overrun not logged:\n%s", log.String())
+	}
+}
+
+func TestDelayedScanCreditsTheElapsedTimeUpToTheCap(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(2)*time.Hour).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.MaxCreditedInterval = duration(time.Duration(10) * time.Minute)
+
+	ctx.GivenTimeIs(time.Date(2024, time.October, 14, 20, 3, 0, 0, time.Local))
+	ctx.controller.scanOnce()
+	ctx.ThenActivityExecutionDurationShouldBe("GTA", time.Duration(3)*time.Minute)
+
+	ctx.GivenTimeIs(time.Date(2024, time.October, 14, 22, 0, 0, 0, time.Local))
+	ctx.controller.scanOnce()
+	ctx.ThenActivityExecutionDurationShouldBe("GTA", time.Duration(13)*time.Minute)
+}
+
+func TestLockdownKillsAllManagedProcessesUntilItExpires(t *testing.T) {
+	now := time.Now()
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenTimeIs(now).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Internet", "firefox.exe", time.Duration(15)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		GivenARunningProcess("C:\\firefox.exe", 2).
+		WhenLockdownStartsFor(time.Duration(30)*time.Minute).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Lockdown in progress").
+		ThenProcessIsKilled("Internet", 2, "C:\\firefox.exe", "Lockdown in progress").
+		GivenTimeIs(now.Add(time.Duration(31) * time.Minute)).
+		WhenScanHappens().
+		ThenNoProcessKilled()
+}
+
+func TestLockdownSurvivesRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1) * time.Minute).
+		WhenLockdownStartsFor(time.Duration(1) * time.Hour)
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+	ctx.controller.dumpState()
+
+	restarted := newDadController(time.Duration(1)*time.Minute, time.Now)
+	restarted.stateFile = ctx.controller.stateFile
+	restarted.reloadStateIfExist()
+	if !restarted.LockdownUntil.Equal(ctx.controller.LockdownUntil) {
+		t.Errorf("lockdown until %s after restart (expected %s)", restarted.LockdownUntil, ctx.controller.LockdownUntil)
+	}
+}
+
+func TestCountersKeyedByWeekdayAreMigratedToDates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	monday := time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)
+	state := fmt.Sprintf(`{"lastControlTime":%q,"activityDuration":{"1":{"GTA":"10m0s"},"0":{"GTA":"20m0s"}},"userActivityDuration":{"S-1-5-21-1001":{"1":{"GTA":"5m0s"}}}}`, monday.Format(time.RFC3339Nano))
+	stateFile := filepath.Join(dir, "dad-controller.state")
+	if err := ioutil.WriteFile(stateFile, []byte(state), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := newDadController(time.Duration(1)*time.Minute, func() time.Time { return monday })
+	ctrl.stateFile = stateFile
+	ctrl.reloadStateIfExist()
+	if d := ctrl.GetActivityDuration("GTA"); d != time.Duration(10)*time.Minute {
+		t.Errorf("GTA duration is %s after migration", d)
+	}
+	if d := ctrl.GetUserActivityDuration("S-1-5-21-1001", "GTA"); d != time.Duration(5)*time.Minute {
+		t.Errorf("GTA duration of the user is %s after migration", d)
+	}
+	if len(ctrl.ActivityDuration) != 1 {
+		t.Errorf("stale counters kept: %v", ctrl.ActivityDuration)
+	}
+}
+
+func TestCountersOfTheSameWeekdayAreNotReusedAWeekLater(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(15)*time.Minute)
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+
+	ctx.GivenTimeIs(time.Date(2024, time.October, 21, 20, 0, 0, 0, time.Local)).
+		WhenControllerRestarts()
+	if d := time.Duration(ctx.controller.durationsOf("")[dateKey(ctx.currentTime)]["GTA"]); d != 0 {
+		t.Errorf("GTA duration is %s a week later", d)
+	}
+	ctx.GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenNoProcessKilled()
+}
+
+func TestExemptedProcessSurvivesUntilItsExemptionExpires(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(1)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		GivenARunningProcess("C:\\GTA.exe", 2).
+		WhenProcessIsExemptedFor("C:\\GTA.exe", 1, time.Duration(2)*time.Minute).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 2, "C:\\GTA.exe", "Activity duration above threshold for this day")
+	if len(ctx.killedProcesses) != 1 {
+		t.Errorf("only pid 2 should have been killed: %q", ctx.killedProcesses)
+	}
+
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this day")
+	if len(ctx.controller.Exemptions) != 0 {
+		t.Errorf("expired exemptions should be dropped: %+v", ctx.controller.Exemptions)
+	}
+}
+
+func TestExemptionDoesNotApplyToAReusedPid(t *testing.T) {
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(1)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenProcessIsExemptedFor("C:\\Homework.exe", 1, time.Duration(30)*time.Minute).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this day")
+}
+
+func TestCustomPolicyCanAllowWhatDefaultPoliciesWouldKill(t *testing.T) {
+	notSunday := time.Now()
+	if notSunday.Weekday() == time.Sunday {
+		notSunday = notSunday.Add(time.Duration(24) * time.Hour)
+	}
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedOnlyOnSunday("Homework", "Word.exe", time.Duration(15)*time.Minute).
+		GivenAPolicy(PolicyFunc(func(ctx decisionContext) (action, string) {
+			if ctx.Activity == "Homework" {
+				return actionAllow, ""
+			}
+			return actionNone, ""
+		})).
+		GivenTimeIs(notSunday).
+		GivenARunningProcess("C:\\Word.exe", 1).
+		WhenScanHappens().
+		ThenNoProcessKilled()
+}
+
+func TestCustomPolicyCanKillWhatDefaultPoliciesWouldAllow(t *testing.T) {
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAPolicy(PolicyFunc(func(ctx decisionContext) (action, string) {
+			if ctx.Used > ctx.Allowed/2 {
+				return actionKill, "Family pool exhausted"
+			}
+			return actionNone, ""
+		})).
+		GivenAnActivityDuration("GTA", time.Duration(7)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Family pool exhausted")
+}
+
+func TestCustomPolicyCanSuspend(t *testing.T) {
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAPolicy(PolicyFunc(func(ctx decisionContext) (action, string) {
+			return actionSuspend, "Dinner time"
+		})).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenSuspendedProcessesShouldBe("1|C:\\GTA.exe")
+}
+
+func TestCustomPolicyCannotDecideAReminder(t *testing.T) {
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAPolicy(PolicyFunc(func(ctx decisionContext) (action, string) {
+			return actionRemind, "Soon over"
+		})).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenActionsShouldBe()
+}
+
+func TestScanReturnsTheActionsItDecided(t *testing.T) {
+	now := time.Now()
+	gta := runningProcess{Path: "C:\\GTA.exe", Pid: 1}
+	firefox := runningProcess{Path: "C:\\firefox.exe", Pid: 2}
+
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 20, 30, 0, 0, time.Local)).
+		GivenAnActivityRuleAllowedEveryTime("Internet", "firefox.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityRuleAllowedEveryDayOnInterval("GTA", "GTA.exe", time.Duration(15)*time.Minute, 2000, 2100).
+		GivenARunningProcess(gta.Path, gta.Pid).
+		GivenARunningProcess(firefox.Path, firefox.Pid).
+		WhenScanHappens().
+		ThenActionsShouldBe().
+		GivenAnActivityDuration("GTA", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("Internet", time.Duration(15)*time.Minute).
+		WhenScanHappens().
+		ThenActionsShouldBe(
+			enforcementAction{Activity: "GTA", Processes: []runningProcess{gta}, Action: actionKill, Reason: "Activity duration above threshold for this day"},
+			enforcementAction{Activity: "Internet", Processes: []runningProcess{firefox}, Action: actionKill, Reason: "Activity duration above threshold for this day"}).
+		GivenAnActivityDuration("GTA", 0).
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 21, 30, 0, 0, time.Local)).
+		WhenScanHappens().
+		ThenActionsShouldBe(
+			enforcementAction{Activity: "GTA", Processes: []runningProcess{gta}, Action: actionKill, Reason: "Activity not allowed to be done during this time range"},
+			enforcementAction{Activity: "Internet", Processes: []runningProcess{firefox}, Action: actionKill, Reason: "Activity duration above threshold for this day"})
+}
+
+func TestScanOnceDoesNotApplyActions(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(15)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+
+	actions := ctx.controller.scanOnce()
+	if len(actions) != 1 || actions[0].Action != actionKill {
+		t.Errorf("scanOnce decided %+v", actions)
+	}
+	ctx.ThenNoProcessKilled()
+}
+
+func TestActivityCountersAreKeptPerUserAcrossRestarts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenARunningProcessOwnedBy("C:\\GTA.exe", 1, "S-1-5-21-1001").
+		GivenARunningProcessOwnedBy("C:\\GTA.exe", 2, "S-1-5-21-1002")
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+
+	ctx.WhenScanHappens().
+		GivenNoRunningProcess().
+		GivenARunningProcessOwnedBy("C:\\GTA.exe", 1, "S-1-5-21-1001").
+		WhenScanHappens().
+		ThenUserActivityExecutionDurationShouldBe("S-1-5-21-1001", "GTA", time.Duration(2)*time.Minute).
+		ThenUserActivityExecutionDurationShouldBe("S-1-5-21-1002", "GTA", time.Duration(1)*time.Minute).
+		ThenActivityExecutionDurationShouldBe("GTA", 0).
+		WhenControllerRestarts().
+		ThenUserActivityExecutionDurationShouldBe("S-1-5-21-1001", "GTA", time.Duration(2)*time.Minute).
+		ThenUserActivityExecutionDurationShouldBe("S-1-5-21-1002", "GTA", time.Duration(1)*time.Minute)
+}
+
+func TestOnlyProcessesOfUserOverLimitAreKilled(t *testing.T) {
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAUserActivityDuration("S-1-5-21-1001", "GTA", time.Duration(15)*time.Minute).
+		GivenARunningProcessOwnedBy("C:\\GTA.exe", 1, "S-1-5-21-1001").
+		GivenARunningProcessOwnedBy("C:\\GTA.exe", 2, "S-1-5-21-1002").
+		WhenScanHappens().
+		ThenActionsShouldBe(enforcementAction{
+			Activity:  "GTA",
+			User:      "S-1-5-21-1001",
+			Processes: []runningProcess{{Path: "C:\\GTA.exe", Pid: 1, UserID: "S-1-5-21-1001"}},
+			Action:    actionKill,
+			Reason:    "Activity duration above threshold for this day",
+		})
+}
+
+func TestProcessesOfParentAccountsAreNeitherCountedNorKilled(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAUserActivityDuration(`HOME-PC\Dad`, "GTA", time.Duration(20)*time.Minute).
+		GivenAUserActivityDuration(`HOME-PC\Tom`, "GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcessOfAccount("C:\\GTA.exe", 1, `HOME-PC\Dad`).
+		GivenARunningProcessOfAccount("C:\\GTA.exe", 2, `HOME-PC\Tom`)
+	ctx.controller.ParentAccounts = []string{"dad"}
+
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("GTA", 2, "C:\\GTA.exe", "Activity duration above threshold for this day").
+		ThenUserActivityExecutionDurationShouldBe(`HOME-PC\Dad`, "GTA", time.Duration(20)*time.Minute).
+		ThenUserActivityExecutionDurationShouldBe(`HOME-PC\Tom`, "GTA", time.Duration(21)*time.Minute)
+	if len(ctx.killedProcesses) != 1 {
+		t.Errorf("only the process of Tom should have been killed: %q", ctx.killedProcesses)
+	}
+}
+
+func TestPatternsMatchTheCommandLineWhenAsked(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Minecraft", "net\\.minecraft", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("Minecraft", time.Duration(20)*time.Minute).
+		GivenARunningProcessWithCommandLine("C:\\java.exe", 1, `C:\java.exe -cp minecraft.jar net.minecraft.client.Main`).
+		GivenARunningProcessWithCommandLine("C:\\java.exe", 2, `C:\java.exe -jar geogebra.jar`)
+	ctx.controller.Activities[0].MatchCommandLine = true
+
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("Minecraft", 1, "C:\\java.exe", "Activity duration above threshold for this day")
+	if len(ctx.killedProcesses) != 1 {
+		t.Errorf("only the Minecraft process should have been killed: %q", ctx.killedProcesses)
+	}
+}
+
+func TestPatternsMatchThePathByDefault(t *testing.T) {
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Minecraft", "net\\.minecraft", time.Duration(15)*time.Minute).
+		GivenARunningProcessWithCommandLine("C:\\java.exe", 1, `C:\java.exe net.minecraft.client.Main`).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("Minecraft", 0)
+}
+
+func TestWindowTitlesMapBrowserGamesToActivities(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Roblox", "RobloxPlayer", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("Roblox", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\chrome.exe", 1).
+		GivenARunningProcess("C:\\chrome.exe", 2).
+		GivenAWindow(1, "Roblox - Google Chrome").
+		GivenAWindow(2, "Homework - Google Docs - Google Chrome")
+	ctx.controller.Activities[0].TitlePatterns = []string{"^Roblox"}
+
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("Roblox", 1, "C:\\chrome.exe", "Activity duration above threshold for this day")
+	if len(ctx.killedProcesses) != 1 {
+		t.Errorf("only the Roblox window should have been killed: %q", ctx.killedProcesses)
+	}
+}
+
+func TestProcessStartedDuringTheIntervalIsCreditedSinceItsStart(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute)
+	ctx.GivenARunningProcessStartedAt("C:\\GTA.exe", 1, ctx.currentTime.Add(time.Duration(40)*time.Second)).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(20)*time.Second).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(80)*time.Second)
+}
+
+func TestProcessWithUnknownStartTimeIsCreditedTheWholeInterval(t *testing.T) {
+	NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1)*time.Minute)
+}
+
+func TestKillTreeKillsTheDescendantsOfTheLauncher(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Fortnite", "EpicGamesLauncher", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("Fortnite", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\EpicGamesLauncher.exe", 1).
+		GivenAChildProcess("C:\\FortniteLauncher.exe", 2, 1).
+		GivenAChildProcess("C:\\FortniteClient.exe", 3, 2).
+		GivenAChildProcess("C:\\notepad.exe", 4, 9)
+	ctx.controller.Activities[0].KillTree = true
+
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("Fortnite", 1, "C:\\EpicGamesLauncher.exe", "Activity duration above threshold for this day").
+		ThenProcessIsKilled("Fortnite", 2, "C:\\FortniteLauncher.exe", "Activity duration above threshold for this day").
+		ThenProcessIsKilled("Fortnite", 3, "C:\\FortniteClient.exe", "Activity duration above threshold for this day")
+	if len(ctx.killedProcesses) != 3 {
+		t.Errorf("only the launcher and its descendants should have been killed: %q", ctx.killedProcesses)
+	}
+}
+
+func TestOnlyMatchedProcessesAreKilledByDefault(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Fortnite", "EpicGamesLauncher", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("Fortnite", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\EpicGamesLauncher.exe", 1).
+		GivenAChildProcess("C:\\FortniteClient.exe", 2, 1)
+
+	ctx.WhenScanHappens()
+	if expected := []string{"1|C:\\EpicGamesLauncher.exe"}; !reflect.DeepEqual(ctx.killedProcesses, expected) {
+		t.Errorf("killed %q (expected %q)", ctx.killedProcesses, expected)
+	}
+}
+
+func TestDescendantsSkipReusedPids(t *testing.T) {
+	start := time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)
+	launcher := runningProcess{Pid: 10, Path: "/launcher", StartTime: start}
+	processes := []runningProcess{
+		launcher,
+		{Pid: 11, Path: "/game", ParentPid: 10, StartTime: start.Add(time.Second)},
+		{Pid: 12, Path: "/shell", ParentPid: 10, StartTime: start.Add(-time.Hour)},
+	}
+	expected := []runningProcess{processes[1]}
+	if d := descendants(processes, []runningProcess{launcher}); !reflect.DeepEqual(d, expected) {
+		t.Errorf("found %+v (expected %+v)", d, expected)
+	}
+}
+
+func TestSuspendRuleFreezesProcessesUntilTheNextDay(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].Suspend = true
+
+	ctx.WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenSuspendedProcessesShouldBe("1|C:\\GTA.exe").
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(21)*time.Minute)
+	if ctx.controller.events[0].Message != "GTA suspended : Activity duration above threshold for this day" {
+		t.Errorf("unexpected events %+v", ctx.controller.events)
+	}
+
+	ctx.WhenScanHappens().
+		ThenSuspendedProcessesShouldBe("1|C:\\GTA.exe").
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(21)*time.Minute)
+
+	ctx.currentTime = ctx.currentTime.Add(time.Duration(24) * time.Hour)
+	ctx.WhenScanHappens().
+		ThenSuspendedProcessesShouldBe().
+		ThenActivityExecutionDurationShouldBe("GTA", 0).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1)*time.Minute)
+}
+
+func TestSuspendedProcessesSurviveRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].Suspend = true
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+
+	ctx.WhenScanHappens().
+		WhenControllerRestarts()
+	if !ctx.controller.isSuspended(runningProcess{Pid: 1, Path: "C:\\GTA.exe"}) {
+		t.Errorf("suspended processes not restored: %+v", ctx.controller.Suspended)
+	}
+}
+
+func TestProcessesAreAskedToCloseBeforeBeingKilled(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		GivenARunningProcess("C:\\GTA.exe", 2)
+	ctx.controller.Activities[0].CloseTimeout = duration(time.Duration(30) * time.Second)
+
+	ctx.WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenATimerShouldBeArmedAt(ctx.currentTime.Add(time.Duration(30) * time.Second))
+	if expected := []string{"1|C:\\GTA.exe", "2|C:\\GTA.exe"}; !reflect.DeepEqual(ctx.closedProcesses, expected) {
+		t.Errorf("closed %q (expected %q)", ctx.closedProcesses, expected)
+	}
+
+	ctx.GivenNoRunningProcess().
+		GivenARunningProcess("C:\\GTA.exe", 2).
+		WhenTimerFires()
+	if expected := []string{"2|C:\\GTA.exe"}; !reflect.DeepEqual(ctx.killedProcesses, expected) {
+		t.Errorf("killed %q (expected %q)", ctx.killedProcesses, expected)
+	}
+}
+
+func TestProcessesAskedToCloseAreNotAskedAgain(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].CloseTimeout = duration(time.Duration(2) * time.Minute)
+
+	ctx.WhenScanHappens().
+		WhenScanHappens()
+	if len(ctx.closedProcesses) != 1 || len(ctx.timers) != 1 {
+		t.Errorf("closed %q with %d timers (expected a single close)", ctx.closedProcesses, len(ctx.timers))
+	}
+}
+
+func TestIdleProcessesAreNotCounted(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute)
+	ctx.controller.Activities[0].MinCPUUsage = 0.05
+	running := func(cpu time.Duration) *TestContext {
+		ctx.runningProcesses = []runningProcess{{Path: "C:\\GTA.exe", Pid: 1, CPUTime: cpu}}
+		return ctx
+	}
+
+	// usage is unknown on the first scan
+	running(time.Duration(10)*time.Second).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1)*time.Minute)
+	// paused in a menu
+	running(time.Duration(11)*time.Second).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1)*time.Minute)
+	running(time.Duration(41)*time.Second).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(2)*time.Minute)
+}
+
+func TestOnlyForegroundTimeIsCounted(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		GivenARunningProcess("C:\\Discord.exe", 2)
+	ctx.controller.Activities[0].ForegroundOnly = true
+
+	ctx.foregroundPid = 1
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1)*time.Minute)
+	// minimized while chatting
+	ctx.foregroundPid = 2
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1)*time.Minute)
+	// the foreground window is unknown, e.g. on the lock screen
+	ctx.foregroundPid = 0
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(2)*time.Minute)
+}
+
+func TestCPUUsageIsIgnoredWithoutMinimum(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute)
+	ctx.runningProcesses = []runningProcess{{Path: "C:\\GTA.exe", Pid: 1, CPUTime: time.Second}}
+
+	ctx.WhenScanHappens().
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(2)*time.Minute)
+}
+
+func TestPatternsMatchTheStorePackage(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Minecraft", "^Microsoft\\.MinecraftUWP_", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("Minecraft", time.Duration(20)*time.Minute)
+	ctx.runningProcesses = []runningProcess{
+		{Pid: 1, Path: `C:\Program Files\WindowsApps\Microsoft.MinecraftUWP\Minecraft.Windows.exe`, Package: "Microsoft.MinecraftUWP_1.20.5001.0_x64__8wekyb3d8bbwe"},
+		{Pid: 2, Path: `C:\Windows\notepad.exe`},
+	}
+
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("Minecraft", 1, `C:\Program Files\WindowsApps\Microsoft.MinecraftUWP\Minecraft.Windows.exe`, "Activity duration above threshold for this day")
+	if len(ctx.killedProcesses) != 1 {
+		t.Errorf("only the Store app should have been killed: %q", ctx.killedProcesses)
+	}
+}
+
+func TestParentAccountMatching(t *testing.T) {
+	ctrl := newDadController(time.Duration(1)*time.Minute, time.Now)
+	ctrl.ParentAccounts = []string{"dad", `OFFICE\Mum`}
+	for user, expected := range map[string]bool{
+		"dad":         true,
+		"DAD":         true,
+		`HOME-PC\Dad`: true,
+		`OFFICE\mum`:  true,
+		`HOME-PC\Mum`: false,
+		"tom":         false,
+		"":            false,
+	} {
+		if parent := ctrl.isParentAccount(user); parent != expected {
+			t.Errorf("isParentAccount(%q) = %t", user, parent)
+		}
+	}
+}
+
+func TestActivityIsKilledOutsideOfItsSpendableWindow(t *testing.T) {
+	now := time.Now()
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleSpendableEveryDayWithin("GTA", "GTA.exe", time.Duration(3)*time.Hour, 800, 2000).
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 7, 0, 0, 0, time.Local)).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity not allowed to be done outside of its spendable window").
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 20, 0, 0, 0, time.Local)).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity not allowed to be done outside of its spendable window")
+}
+
+func TestActivityIsCappedByDurationInsideItsSpendableWindow(t *testing.T) {
+	now := time.Now()
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleSpendableEveryDayWithin("GTA", "GTA.exe", time.Duration(3)*time.Hour, 800, 2000).
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 9, 0, 0, 0, time.Local)).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenNoProcessKilled().
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 15, 0, 0, 0, time.Local)).
+		GivenAnActivityDuration("GTA", time.Duration(3)*time.Hour).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this day")
+}
+
+func TestParentIsNotifiedWhenARequiredProcessIsMissing(t *testing.T) {
+	NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenARequiredProcessRule("Monitoring agent", "agent.exe", 3).
+		GivenARunningProcess("C:\\agent.exe", 1).
+		WhenScanHappens().
+		ThenNoProcessKilled().
+		GivenNoRunningProcess().
+		WhenScanHappens().
+		WhenScanHappens().
+		ThenParentShouldHaveBeenNotified().
+		WhenScanHappens().
+		ThenParentShouldHaveBeenNotified("Monitoring agent has not been running for 3 scans").
+		WhenScanHappens().
+		ThenParentShouldHaveBeenNotified("Monitoring agent has not been running for 3 scans").
+		GivenARunningProcess("C:\\agent.exe", 2).
+		WhenScanHappens().
+		ThenParentShouldHaveBeenNotified("Monitoring agent has not been running for 3 scans", "Monitoring agent is running again").
+		ThenActivityExecutionDurationShouldBe("Monitoring agent", 0)
+}
+
+func TestPreviewMatchesWhatAScanWouldKill(t *testing.T) {
+	now := time.Now()
+	notSunday := time.Date(now.Year(), now.Month(), now.Day(), 18, 0, 0, 0, time.Local)
+	if notSunday.Weekday() == time.Sunday {
+		notSunday = notSunday.AddDate(0, 0, 1)
+	}
+	gta := runningProcess{Path: "C:\\GTA.exe", Pid: 1}
+	firefox := runningProcess{Path: "C:\\firefox.exe", Pid: 2}
+	steam := runningProcess{Path: "C:\\Steam.exe", Pid: 3}
+	word := runningProcess{Path: "C:\\Word.exe", Pid: 4}
+	expected := []enforcementAction{
+		{Activity: "GTA", Processes: []runningProcess{gta}, Action: actionKill, Reason: "Activity not allowed to be done during this time range"},
+		{Activity: "Internet", Processes: []runningProcess{firefox}, Action: actionKill, Reason: "Activity duration above threshold for this day"},
+		{Activity: "Steam", Processes: []runningProcess{steam}, Action: actionKill, Reason: "Activity not allowed to be done on this day"},
+	}
+
+	ctx := NewTest(t).
+		GivenTimeIs(notSunday).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryDayOnInterval("GTA", "GTA.exe", time.Duration(15)*time.Minute, 2000, 2100).
+		GivenAnActivityRuleAllowedEveryTime("Internet", "firefox.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityRuleAllowedOnlyOnSunday("Steam", "Steam.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Homework", "Word.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("Internet", time.Duration(20)*time.Minute).
+		GivenARunningProcess(gta.Path, gta.Pid).
+		GivenARunningProcess(firefox.Path, firefox.Pid).
+		GivenARunningProcess(steam.Path, steam.Pid).
+		GivenARunningProcess(word.Path, word.Pid).
+		WhenPreviewHappens().
+		ThenActionsShouldBe(expected...).
+		ThenNoProcessKilled().
+		ThenActivityExecutionDurationShouldBe("Internet", time.Duration(20)*time.Minute)
+
+	ctx.WhenScanHappens().
+		ThenActionsShouldBe(expected...).
+		ThenProcessIsKilled("GTA", 1, gta.Path, expected[0].Reason).
+		ThenProcessIsKilled("Internet", 2, firefox.Path, expected[1].Reason).
+		ThenProcessIsKilled("Steam", 3, steam.Path, expected[2].Reason)
+}
+
+func TestCreditedTimeIsSplitAtPeriodBoundary(t *testing.T) {
+	now := time.Now()
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 20, 59, 30, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryDayOnInterval("GTA", "GTA.exe", time.Duration(15)*time.Minute, 2000, 2100).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.getOrCreateActivityRule("GTA").CreditWithinPeriods = true
+
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(30)*time.Second).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(30)*time.Second)
+}
+
+func TestCreditedTimeIsNotSplitByDefault(t *testing.T) {
+	now := time.Now()
+	NewTest(t).
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 20, 59, 30, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryDayOnInterval("GTA", "GTA.exe", time.Duration(15)*time.Minute, 2000, 2100).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(1)*time.Minute)
+}
+
+func TestCreditedTimeIsSplitAtPeriodBeginning(t *testing.T) {
+	now := time.Now()
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 19, 59, 45, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryDayOnInterval("GTA", "GTA.exe", time.Duration(15)*time.Minute, 2000, 2100).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.getOrCreateActivityRule("GTA").CreditWithinPeriods = true
+
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(45)*time.Second)
+}
+
+func TestCreditedTimeFollowsTheWallClockAcrossAChangeOfClock(t *testing.T) {
+	// scan at 02:20 CEST, the first time it is 02:20 on the night summer
+	// time ends
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 27, 0, 10, 0, 0, time.UTC)).
+		GivenADadControllerWithSamplingInterval(time.Duration(10)*time.Minute).
+		GivenAnActivityRuleAllowedEveryDayOnInterval("GTA", "GTA.exe", time.Duration(3)*time.Hour, 215, 300).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.getOrCreateActivityRule("GTA").CreditWithinPeriods = true
+	ctx.controller.TimeZone = "Europe/Paris"
+	if err := ctx.controller.loadTimeZone(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(5)*time.Minute)
+}
+
+func TestProbationScalesLimitsUntilItExpires(t *testing.T) {
+	now := time.Now()
+	NewTest(t).
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 10, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAnActivityDuration("GTA", time.Duration(30)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenProbationIsSet(0.5, time.Duration(2)*time.Hour).
+		ThenEffectiveMaxDurationShouldBe("GTA", time.Duration(30)*time.Minute).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this day").
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.Local)).
+		WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenEffectiveMaxDurationShouldBe("GTA", time.Duration(1)*time.Hour)
+}
+
+func TestProbationSurvivesRestart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		WhenProbationIsSet(0.25, time.Duration(72)*time.Hour)
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+
+	ctx.WhenControllerRestarts().
+		ThenEffectiveMaxDurationShouldBe("GTA", time.Duration(15)*time.Minute)
+}
+
+func TestProbationFactorIsClamped(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		WhenProbationIsSet(2, time.Duration(1)*time.Hour).
+		ThenEffectiveMaxDurationShouldBe("GTA", time.Duration(1)*time.Hour).
+		WhenProbationIsSet(-1, time.Duration(1)*time.Hour).
+		ThenEffectiveMaxDurationShouldBe("GTA", 0)
+
+	if ctx.controller.ProbationFactor != 0 {
+		t.Errorf("probation factor is %f", ctx.controller.ProbationFactor)
+	}
+}
+
+var (
+	eveningPeriod = timePeriod{Begin: 1900, End: 2200, MaxDuration: duration(time.Duration(1) * time.Hour)}
+	dinnerPeriod  = timePeriod{Begin: 2000, End: 2100, MaxDuration: duration(time.Duration(30) * time.Minute)}
+)
+
+func testOverlappingPeriodCaps(t *testing.T, mode string, killedAfter time.Duration, periods ...timePeriod) {
+	now := time.Now()
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 19, 59, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(10)*time.Minute).
+		GivenPeriodOverlapResolution(mode).
+		GivenAnActivityRuleWithCappedPeriods("GTA", "GTA.exe", time.Duration(3)*time.Hour, periods...).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+
+	for used := time.Duration(10) * time.Minute; used < killedAfter; used += time.Duration(10) * time.Minute {
+		ctx.WhenScanHappens().ThenNoProcessKilled()
+	}
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", killedAfter).
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this day")
+}
+
+func TestOverlappingPeriodCapsMostRestrictive(t *testing.T) {
+	testOverlappingPeriodCaps(t, periodOverlapMostRestrictive, time.Duration(40)*time.Minute, eveningPeriod, dinnerPeriod)
+}
+
+func TestOverlappingPeriodCapsDefaultToMostRestrictive(t *testing.T) {
+	testOverlappingPeriodCaps(t, "", time.Duration(40)*time.Minute, eveningPeriod, dinnerPeriod)
+}
+
+func TestOverlappingPeriodCapsMostGenerous(t *testing.T) {
+	testOverlappingPeriodCaps(t, periodOverlapMostGenerous, time.Duration(70)*time.Minute, dinnerPeriod, eveningPeriod)
+}
+
+func TestOverlappingPeriodCapsFirstMatch(t *testing.T) {
+	testOverlappingPeriodCaps(t, periodOverlapFirstMatch, time.Duration(70)*time.Minute, eveningPeriod, dinnerPeriod)
+	testOverlappingPeriodCaps(t, periodOverlapFirstMatch, time.Duration(40)*time.Minute, dinnerPeriod, eveningPeriod)
+}
+
+func testSharedProcessAttribution(t *testing.T, mode string, browsing time.Duration, youtube time.Duration) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Browsing", "firefox.exe", time.Duration(60)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("YouTube", "firefox.exe", time.Duration(60)*time.Minute).
+		GivenARunningProcess("C:\\firefox.exe", 1)
+	ctx.controller.SharedProcessAttribution = mode
+	ctx.controller.getOrCreateActivityRule("YouTube").Weight = 3
+
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("Browsing", browsing).
+		ThenActivityExecutionDurationShouldBe("YouTube", youtube)
+}
+
+func TestSharedProcessIsCreditedInFullByDefault(t *testing.T) {
+	testSharedProcessAttribution(t, "", time.Duration(1)*time.Minute, time.Duration(1)*time.Minute)
+	testSharedProcessAttribution(t, sharedProcessFull, time.Duration(1)*time.Minute, time.Duration(1)*time.Minute)
+}
+
+func TestSharedProcessIsSplitEvenly(t *testing.T) {
+	testSharedProcessAttribution(t, sharedProcessSplit, time.Duration(30)*time.Second, time.Duration(30)*time.Second)
+}
+
+func TestSharedProcessIsSplitByWeight(t *testing.T) {
+	testSharedProcessAttribution(t, sharedProcessWeighted, time.Duration(15)*time.Second, time.Duration(45)*time.Second)
+}
+
+func testRuleMatching(t *testing.T, mode string, priority int, browsing time.Duration, youtube time.Duration) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Browsing", "firefox.exe", time.Duration(60)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("YouTube", "firefox.exe", time.Duration(60)*time.Minute).
+		GivenARunningProcess("C:\\firefox.exe", 1)
+	ctx.controller.RuleMatching = mode
+	ctx.controller.getOrCreateActivityRule("YouTube").Priority = priority
+
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("Browsing", browsing).
+		ThenActivityExecutionDurationShouldBe("YouTube", youtube)
+}
+
+func TestProcessMatchesAllRulesByDefault(t *testing.T) {
+	testRuleMatching(t, "", 10, time.Duration(1)*time.Minute, time.Duration(1)*time.Minute)
+	testRuleMatching(t, ruleMatchingAll, 10, time.Duration(1)*time.Minute, time.Duration(1)*time.Minute)
+}
+
+func TestProcessMatchesTheFirstRuleInConfigurationOrder(t *testing.T) {
+	testRuleMatching(t, ruleMatchingFirst, 0, time.Duration(1)*time.Minute, 0)
+}
+
+func TestProcessMatchesTheRuleOfHighestPriority(t *testing.T) {
+	testRuleMatching(t, ruleMatchingFirst, 10, 0, time.Duration(1)*time.Minute)
+}
+
+func TestActivityWithAnUnsharedProcessIsCreditedInFull(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Browsing", "(firefox|chrome).exe", time.Duration(60)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("YouTube", "firefox.exe", time.Duration(60)*time.Minute).
+		GivenARunningProcess("C:\\firefox.exe", 1).
+		GivenARunningProcess("C:\\chrome.exe", 2)
+	ctx.controller.SharedProcessAttribution = sharedProcessSplit
+
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("Browsing", time.Duration(1)*time.Minute).
+		ThenActivityExecutionDurationShouldBe("YouTube", time.Duration(30)*time.Second)
+}
+
+func TestSchedulingDecisionsHonorThePinnedTime(t *testing.T) {
+	sunday := time.Date(2024, time.June, 2, 20, 5, 0, 0, time.Local)
+	monday := sunday.AddDate(0, 0, 1)
+
+	ctx := NewTest(t).
+		GivenTimeIs(monday).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedOnlyOnSunday("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		GivenTimeIsPinnedAt(sunday).
+		WhenScanHappens().
+		ThenNoProcessKilled().
+		WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(2)*time.Minute)
+
+	if !ctx.controller.LastControlTime.Equal(sunday) {
+		t.Errorf("last control at %s (expected %s)", ctx.controller.LastControlTime, sunday)
+	}
+	if r := ctx.controller.EffectiveScheduleFor("GTA", ctx.controller.GetTime()); !r.Allowed {
+		t.Error("GTA should be allowed on the pinned Sunday")
+	}
+
+	ctx.WhenLockdownStartsFor(time.Duration(1) * time.Hour)
+	if expected := sunday.Add(time.Hour); !ctx.controller.LockdownUntil.Equal(expected) {
+		t.Errorf("lockdown until %s (expected %s)", ctx.controller.LockdownUntil, expected)
+	}
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Lockdown in progress")
+}
+
+func TestDisabledRuleIsNeitherCountedNorEnforced(t *testing.T) {
+	disabled := false
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].Enabled = &disabled
+
+	ctx.WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(20)*time.Minute)
+}
+
+func TestParseFakeNow(t *testing.T) {
+	expected := time.Date(2024, time.June, 2, 20, 5, 0, 0, time.Local)
+	for _, value := range []string{"2024-06-02 20:05", expected.Format(time.RFC3339)} {
+		if fakeNow, err := parseFakeNow(value); err != nil || !fakeNow.Equal(expected) {
+			t.Errorf("%q parsed as %s, %v", value, fakeNow, err)
+		}
+	}
+	if _, err := parseFakeNow("sunday evening"); err == nil {
+		t.Error("invalid time accepted")
+	}
+}
+
 func TestJson(t *testing.T) {
 	ctx := NewTest(t).
 		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
@@ -203,7 +1728,7 @@ func TestJson(t *testing.T) {
 	}
 
 	if ctrl.LastControlTime != ctx.controller.LastControlTime {
-		data, _ := json.Marshal(ctrl)
+		data, _ := json.Marshal(&ctrl)
 		fmt.Println(string(data))
 
 		t.Error("mismatch")
@@ -219,7 +1744,7 @@ func TestUnmarchal(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
-	data, _ = json.Marshal(ctrl)
+	data, _ = json.Marshal(&ctrl)
 	fmt.Println(string(data))
 
 }
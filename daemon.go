@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// sdNotify sends state, e.g. "READY=1", to systemd when it started the
+// controller as a Type=notify service, doing nothing otherwise.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	if strings.HasPrefix(socket, "@") {
+		// abstract namespace socket
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// runDaemon runs the controller until it receives SIGTERM or SIGINT, telling
+// systemd when it is ready and when it stops. The state is saved on the way
+// out.
+func (c *dadController) runDaemon() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+	go func() {
+		sig, ok := <-signals
+		if !ok {
+			return
+		}
+		fmt.Fprintf(logOutput, "Received %s, stopping\n", sig)
+		if err := sdNotify("STOPPING=1"); err != nil {
+			fmt.Fprintln(logOutput, "Failure to notify systemd : ", err)
+		}
+		c.Stop()
+	}()
+
+	if err := sdNotify("READY=1"); err != nil {
+		fmt.Fprintln(logOutput, "Failure to notify systemd : ", err)
+	}
+	c.run()
+}
+
+// systemdUnit returns a unit running exe as a daemon from dir, where its
+// configuration and state files are, restarting it whenever it exits.
+func systemdUnit(exe string, dir string) string {
+	return fmt.Sprintf(`[Unit]
+Description=Dad Controller
+After=network.target
+
+[Service]
+Type=notify
+ExecStart=%s -daemon
+WorkingDirectory=%s
+Restart=always
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`, exe, dir)
+}
+
+// printSystemdUnit prints the unit of the running executable, to be saved
+// as /etc/systemd/system/dad-controller.service.
+func printSystemdUnit() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.Abs(exe); err != nil {
+		return err
+	}
+	fmt.Print(systemdUnit(exe, filepath.Dir(exe)))
+	return nil
+}
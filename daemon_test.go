@@ -0,0 +1,61 @@
+//go:build linux
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSdNotifySendsStateToTheNotifySocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	defer os.Setenv("NOTIFY_SOCKET", os.Getenv("NOTIFY_SOCKET"))
+	os.Setenv("NOTIFY_SOCKET", socket)
+
+	if err := sdNotify("READY=1"); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Duration(5) * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := string(buf[:n]); state != "READY=1" {
+		t.Errorf("received %q (expected READY=1)", state)
+	}
+}
+
+func TestSdNotifyWithoutSystemdDoesNothing(t *testing.T) {
+	defer os.Setenv("NOTIFY_SOCKET", os.Getenv("NOTIFY_SOCKET"))
+	os.Unsetenv("NOTIFY_SOCKET")
+
+	if err := sdNotify("READY=1"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSystemdUnitRunsTheDaemon(t *testing.T) {
+	unit := systemdUnit("/opt/dad/dad-controller", "/opt/dad")
+	for _, line := range []string{"Type=notify", "ExecStart=/opt/dad/dad-controller -daemon", "WorkingDirectory=/opt/dad", "Restart=always"} {
+		if !strings.Contains(unit, line+"\n") {
+			t.Errorf("%q missing from unit:\n%s", line, unit)
+		}
+	}
+}
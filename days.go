@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnmarshalJSON reads the rule, whose schedules may be keyed by weekday
+// number as well as by day name, range or list, e.g. "mon-fri", "sat,sun"
+// or "weekend". A single day takes precedence over the ranges including it,
+// the "default" key giving the default schedule of the rule.
+func (a *activityRule) UnmarshalJSON(b []byte) error {
+	type rule activityRule
+	v := struct {
+		*rule
+		Schedules map[string]*schedule `json:"schedules"`
+	}{rule: (*rule)(a)}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Schedules == nil {
+		return nil
+	}
+
+	a.AllowedSchedules = make(map[time.Weekday]*schedule)
+	single := make(map[time.Weekday]bool)
+	for key, s := range v.Schedules {
+		if s == nil {
+			continue
+		}
+		if strings.ToLower(key) == "default" {
+			if a.DefaultSchedule != nil {
+				return fmt.Errorf("invalid schedule of activity [%s] : default schedule given more than once", a.Name)
+			}
+			a.DefaultSchedule = s
+			continue
+		}
+		days, err := parseScheduleDays(key)
+		if err != nil {
+			return fmt.Errorf("invalid schedule of activity [%s] : %s", a.Name, err)
+		}
+		for _, d := range days {
+			if len(days) > 1 && single[d] {
+				continue
+			}
+			if _, found := a.AllowedSchedules[d]; found && (len(days) > 1 || single[d]) {
+				return fmt.Errorf("invalid schedule of activity [%s] : %s is scheduled more than once", a.Name, d.String())
+			}
+			a.AllowedSchedules[d] = s.clone()
+			single[d] = len(days) == 1
+		}
+	}
+	return nil
+}
+
+// parseScheduleDays parses a key of the schedules of a rule.
+func parseScheduleDays(key string) ([]time.Weekday, error) {
+	if n, err := strconv.Atoi(key); err == nil {
+		if n < 0 || n > 6 {
+			return nil, fmt.Errorf("unknown day %d", n)
+		}
+		return []time.Weekday{time.Weekday(n)}, nil
+	}
+	switch strings.ToLower(key) {
+	case "weekend":
+		key = "sat-sun"
+	case "weekdays":
+		key = "mon-fri"
+	}
+	return parseCompactDays(compactToken{pos: 1, text: key})
+}
+
+// clone returns a copy of s not sharing its periods.
+func (s *schedule) clone() *schedule {
+	c := *s
+	c.AllowedPeriods = append([]timePeriod(nil), s.AllowedPeriods...)
+	c.DenyPeriods = append([]timePeriod(nil), s.DenyPeriods...)
+	if s.SpendableWindow != nil {
+		w := *s.SpendableWindow
+		c.SpendableWindow = &w
+	}
+	return &c
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchedulesCanBeKeyedByDayRanges(t *testing.T) {
+	cfg, err := parseConfig([]byte(`{
+		"rules": [{
+			"name": "GTA",
+			"programs": ["GTA.exe"],
+			"schedules": {
+				"mon-fri": {"allowedPeriods": [{"begin": "17:00", "end": "20:00"}], "maxDuration": "1h"},
+				"wed": {"allowedPeriods": [{"begin": "14:00", "end": "20:00"}], "maxDuration": "2h"},
+				"weekend": {"allowedPeriods": [{"begin": "10:00", "end": "20:00"}], "maxDuration": "3h"}
+			}
+		}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schedules := cfg.Activities[0].AllowedSchedules
+	for day, expected := range map[time.Weekday]time.Duration{
+		time.Monday: time.Hour, time.Tuesday: time.Hour, time.Wednesday: 2 * time.Hour, time.Thursday: time.Hour,
+		time.Friday: time.Hour, time.Saturday: 3 * time.Hour, time.Sunday: 3 * time.Hour,
+	} {
+		if s := schedules[day]; s == nil || time.Duration(s.MaxDuration) != expected {
+			t.Errorf("%s schedule is %+v", day, s)
+		}
+	}
+	if schedules[time.Monday] == schedules[time.Tuesday] {
+		t.Error("days of a range should not share their schedule")
+	}
+}
+
+func TestOverlappingDayRangesAreRejected(t *testing.T) {
+	for _, invalid := range []string{
+		`{"rules": [{"name": "GTA", "programs": ["GTA.exe"], "schedules": {"mon-fri": {"maxDuration": "1h"}, "weekdays": {"maxDuration": "2h"}}}]}`,
+		`{"rules": [{"name": "GTA", "programs": ["GTA.exe"], "schedules": {"3": {"maxDuration": "1h"}, "wed": {"maxDuration": "2h"}}}]}`,
+		`{"rules": [{"name": "GTA", "programs": ["GTA.exe"], "schedules": {"7": {"maxDuration": "1h"}}}]}`,
+		`{"rules": [{"name": "GTA", "programs": ["GTA.exe"], "schedules": {"mon-xyz": {"maxDuration": "1h"}}}]}`,
+	} {
+		if _, err := parseConfig([]byte(invalid)); err == nil {
+			t.Errorf("%s should be invalid", invalid)
+		}
+	}
+}
+
+func TestDefaultScheduleAppliesOnDaysWithoutSchedule(t *testing.T) {
+	ctx := NewTest(t).GivenADadControllerWithSamplingInterval(time.Minute)
+	cfg, err := parseConfig([]byte(`{
+		"rules": [{
+			"name": "GTA",
+			"programs": ["GTA.exe"],
+			"schedules": {
+				"default": {"allowedPeriods": [{"begin": "17:00", "end": "20:00"}], "maxDuration": "1h"},
+				"sun": {"allowedPeriods": [{"begin": "10:00", "end": "20:00"}], "maxDuration": "3h"}
+			}
+		}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.controller.config = *cfg
+
+	sunday := time.Date(2024, time.October, 13, 10, 0, 0, 0, time.Local)
+	if r := ctx.controller.EffectiveScheduleFor("GTA", sunday); time.Duration(r.MaxDuration) != 3*time.Hour || len(r.Modifiers) != 0 {
+		t.Errorf("Sunday schedule is %+v", r)
+	}
+	for day := 1; day < 7; day++ {
+		r := ctx.controller.EffectiveScheduleFor("GTA", sunday.AddDate(0, 0, day))
+		if !r.Allowed || time.Duration(r.MaxDuration) != time.Hour || !reflect.DeepEqual(r.Modifiers, []string{"default schedule"}) {
+			t.Errorf("%s schedule is %+v", sunday.AddDate(0, 0, day).Weekday(), r)
+		}
+	}
+}
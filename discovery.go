@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDiscoveryMinDuration = 30 * time.Minute
+	defaultDiscoveryTop         = 5
+)
+
+// discoveryPolicy configures the reports of the programs running a lot
+// without matching any rule, to help writing rules for them. Zero values fall
+// back to the defaults.
+type discoveryPolicy struct {
+	// ReportInterval is how often the parent is notified, which is also
+	// the period over which running time is accumulated
+	ReportInterval duration `json:"reportInterval"`
+	// MinDuration is the running time above which a program is reported
+	MinDuration duration `json:"minDuration,omitempty"`
+	Top         int      `json:"top,omitempty"`
+	// Ignore lists patterns of programs never reported, e.g. system ones
+	Ignore []string `json:"ignore,omitempty"`
+}
+
+type discoveredProgram struct {
+	Name     string
+	Duration time.Duration
+}
+
+func (p *discoveryPolicy) minDuration() time.Duration {
+	if p.MinDuration <= 0 {
+		return defaultDiscoveryMinDuration
+	}
+	return time.Duration(p.MinDuration)
+}
+
+func (p *discoveryPolicy) top() int {
+	if p.Top <= 0 {
+		return defaultDiscoveryTop
+	}
+	return p.Top
+}
+
+func (p *discoveryPolicy) ignores(path string) bool {
+	for _, pattern := range p.Ignore {
+		if regex, err := regexp.Compile(pattern); err == nil && regex.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// programName returns the lower-cased executable name of path, which may be
+// a Windows path whatever the platform the controller is tested on.
+func programName(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		path = path[i+1:]
+	}
+	return strings.ToLower(path)
+}
+
+// discoverUnmanaged credits the programs matching no activity rule with the
+// sampling interval and notifies the parent of the heaviest ones once the
+// report interval has elapsed.
+func (c *dadController) discoverUnmanaged(processes []runningProcess, now time.Time) {
+	if c.Discovery == nil || c.Discovery.ReportInterval <= 0 {
+		return
+	}
+
+	managed := make(map[int]bool)
+	for _, a := range c.allRules() {
+		for _, p := range a.matchingProcesses(processes) {
+			managed[p.Pid] = true
+		}
+	}
+
+	// several processes of a program only count once
+	credited := make(map[string]bool)
+	for _, p := range processes {
+		name := programName(p.Path)
+		if managed[p.Pid] || name == "" || credited[name] || c.Discovery.ignores(p.Path) {
+			continue
+		}
+		credited[name] = true
+		if c.UnmanagedDuration == nil {
+			c.UnmanagedDuration = make(map[string]duration)
+		}
+		c.UnmanagedDuration[name] += duration(c.credited)
+	}
+
+	if c.LastDiscoveryReport.IsZero() {
+		c.LastDiscoveryReport = now
+		return
+	}
+	if now.Sub(c.LastDiscoveryReport) < time.Duration(c.Discovery.ReportInterval) {
+		return
+	}
+
+	if report := c.discoveryReport(); len(report) > 0 {
+		names := make([]string, len(report))
+		for i, p := range report {
+			names[i] = fmt.Sprintf("%s (%s)", p.Name, p.Duration)
+		}
+		c.notify(fmt.Sprintf("Programs without rule running a lot: %s", strings.Join(names, ", ")))
+	}
+	c.UnmanagedDuration = nil
+	c.LastDiscoveryReport = now
+}
+
+// discoveryReport returns the programs without rule which ran longer than
+// the minimum duration, the heaviest first.
+func (c *dadController) discoveryReport() []discoveredProgram {
+	var report []discoveredProgram
+	for name, d := range c.UnmanagedDuration {
+		if time.Duration(d) >= c.Discovery.minDuration() {
+			report = append(report, discoveredProgram{Name: name, Duration: time.Duration(d)})
+		}
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Duration != report[j].Duration {
+			return report[i].Duration > report[j].Duration
+		}
+		return report[i].Name < report[j].Name
+	})
+	if len(report) > c.Discovery.top() {
+		report = report[:c.Discovery.top()]
+	}
+	return report
+}
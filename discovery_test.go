@@ -0,0 +1,64 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiscoveryReportsTheHeaviestProgramsWithoutRule(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(24)*time.Hour)
+	ctx.controller.Discovery = &discoveryPolicy{
+		ReportInterval: duration(time.Hour),
+		MinDuration:    duration(20 * time.Minute),
+		Top:            2,
+		Ignore:         []string{`(?i)svchost\.exe$`},
+	}
+
+	for scan := 0; scan < 61; scan++ {
+		ctx.GivenNoRunningProcess().
+			GivenARunningProcess("C:\\GTA.exe", 1).
+			GivenARunningProcess("C:\\Windows\\svchost.exe", 2).
+			GivenARunningProcess("C:\\Roblox\\Roblox.exe", 3).
+			GivenARunningProcess("C:\\Roblox\\ROBLOX.exe", 4)
+		if scan < 30 {
+			ctx.GivenARunningProcess("C:\\Discord\\Discord.exe", 5).
+				GivenARunningProcess("C:\\Steam\\Steam.exe", 6)
+		}
+		if scan < 25 {
+			ctx.GivenARunningProcess("C:\\Windows\\notepad.exe", 7)
+		}
+		ctx.WhenScanHappens()
+		if scan < 60 {
+			ctx.ThenParentShouldHaveBeenNotified()
+		}
+	}
+
+	ctx.ThenParentShouldHaveBeenNotified("Programs without rule running a lot: roblox.exe (1h1m0s), discord.exe (30m0s)")
+	if len(ctx.controller.UnmanagedDuration) != 0 {
+		t.Errorf("counters should be reset after a report: %v", ctx.controller.UnmanagedDuration)
+	}
+}
+
+func TestDiscoveryIsDisabledByDefault(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenARunningProcess("C:\\Roblox\\Roblox.exe", 1).
+		WhenScanHappens()
+	if len(ctx.controller.UnmanagedDuration) != 0 {
+		t.Errorf("programs should not be tracked: %v", ctx.controller.UnmanagedDuration)
+	}
+}
+
+func TestProgramName(t *testing.T) {
+	for path, expected := range map[string]string{
+		"C:\\Games\\GTA5.exe": "gta5.exe",
+		"/usr/bin/firefox":    "firefox",
+		"steam":               "steam",
+	} {
+		if name := programName(path); name != expected {
+			t.Errorf("programName(%q) = %q (expected %q)", path, name, expected)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// timeCredit is time earned on an activity, e.g. for chores done, adding to
+// its maximum durations on a single date, after which it expires.
+type timeCredit struct {
+	Activity string   `json:"activity"`
+	Date     string   `json:"date"`
+	Duration duration `json:"duration"`
+}
+
+// Credit adds d to the maximum durations of activity on the day of date.
+func (c *dadController) Credit(activity string, date time.Time, d time.Duration) timeCredit {
+	day := date.Format("2006-01-02")
+	for i := range c.Credits {
+		if c.Credits[i].Activity == activity && c.Credits[i].Date == day {
+			c.Credits[i].Duration += duration(d)
+			fmt.Fprintf(logOutput, "%s earned on %s for %s, %s in total\n", d, activity, day, time.Duration(c.Credits[i].Duration))
+			return c.Credits[i]
+		}
+	}
+	credit := timeCredit{Activity: activity, Date: day, Duration: duration(d)}
+	c.Credits = append(c.Credits, credit)
+	fmt.Fprintf(logOutput, "%s earned on %s for %s\n", d, activity, day)
+	return credit
+}
+
+// credit returns the time earned on activity for the day of date.
+func (c *dadController) credit(activity string, date time.Time) time.Duration {
+	day := date.Format("2006-01-02")
+	for _, credit := range c.Credits {
+		if credit.Activity == activity && credit.Date == day {
+			return time.Duration(credit.Duration)
+		}
+	}
+	return 0
+}
+
+// expireCredits forgets the time earned for the days before now.
+func (c *dadController) expireCredits(now time.Time) {
+	today := now.Format("2006-01-02")
+	var kept []timeCredit
+	for _, credit := range c.Credits {
+		if credit.Date >= today {
+			kept = append(kept, credit)
+		}
+	}
+	c.Credits = kept
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEarnedTimeExtendsTheMaxDurationOfTheDay(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAnActivityDuration("GTA", time.Duration(70)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Credit("GTA", ctx.currentTime, time.Duration(20)*time.Minute)
+	ctx.controller.Credit("GTA", ctx.currentTime, time.Duration(10)*time.Minute)
+
+	ctx.WhenScanHappens().ThenNoProcessKilled()
+	if r := ctx.controller.EffectiveScheduleFor("GTA", ctx.currentTime.AddDate(0, 0, 1)); r.MaxDuration != duration(time.Hour) {
+		t.Errorf("earned time should not apply the day after: %+v", r)
+	}
+
+	ctx.controller.expireCredits(ctx.currentTime.AddDate(0, 0, 1))
+	if len(ctx.controller.Credits) != 0 {
+		t.Errorf("earned time should have expired: %+v", ctx.controller.Credits)
+	}
+}
+
+func TestHTTPCredit(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour)
+	handler := ctx.pinnedHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/credit?activity=GTA&minutes=30&reason=chores+done", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST returned %d: %s", rec.Code, rec.Body.String())
+	}
+	if r := ctx.controller.EffectiveScheduleFor("GTA", ctx.currentTime); r.MaxDuration != duration(time.Duration(90)*time.Minute) {
+		t.Errorf("earned time resolved as %+v", r)
+	}
+
+	for _, invalid := range []string{"/credit?activity=Unknown&minutes=30", "/credit?activity=GTA&minutes=-5"} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, invalid, nil))
+		if rec.Code == http.StatusOK {
+			t.Errorf("POST %s should fail", invalid)
+		}
+	}
+}
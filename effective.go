@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// resolvedSchedule is the schedule that actually applies to an activity on a
+// given date, once every modifier has been taken into account.
+// Modes of resolution of overlapping allowed periods with different maximum
+// durations: the first period listed, the one allowing the longest duration or
+// the one allowing the shortest duration governs.
+const (
+	periodOverlapFirstMatch      = "firstMatch"
+	periodOverlapMostGenerous    = "mostGenerous"
+	periodOverlapMostRestrictive = "mostRestrictive"
+)
+
+type resolvedSchedule struct {
+	schedule
+	Activity string `json:"activity"`
+	Date     string `json:"date"`
+	// Allowed is false when the activity is not allowed at all that day
+	Allowed bool `json:"allowed"`
+	// Modifiers describes what changed the weekday schedule of the rule
+	Modifiers []string `json:"modifiers,omitempty"`
+	// continuedUntil is the end of the periods of the day before continuing
+	// after midnight, 0 when none does
+	continuedUntil int
+}
+
+func (c *dadController) findActivityRule(activity string) *activityRule {
+	for _, a := range c.Activities {
+		if a.Name == activity {
+			return a
+		}
+	}
+	return nil
+}
+
+// EffectiveScheduleFor resolves the allowed periods and maximum duration of
+// activity on the day of date.
+func (c *dadController) EffectiveScheduleFor(activity string, date time.Time) resolvedSchedule {
+	return c.effectiveScheduleOf("", activity, date)
+}
+
+// effectiveScheduleOf resolves the schedule of activity on the day of date
+// for the processes of user.
+func (c *dadController) effectiveScheduleOf(user string, activity string, date time.Time) resolvedSchedule {
+	if a := c.ruleOf(user, activity); a != nil {
+		return c.effectiveSchedule(a, date)
+	}
+	return resolvedSchedule{Activity: activity, Date: date.Format("2006-01-02")}
+}
+
+// effectiveSchedule resolves the schedule of the rule a on the day of date.
+func (c *dadController) effectiveSchedule(a *activityRule, date time.Time) resolvedSchedule {
+	activity := a.Name
+	r := resolvedSchedule{Activity: activity, Date: date.Format("2006-01-02")}
+
+	s, modifier := c.scheduleOn(a, date)
+	if modifier != "" {
+		r.Modifiers = append(r.Modifiers, modifier)
+	}
+	if s != nil {
+		r.Allowed = true
+		r.AllowedPeriods, _ = splitAtMidnight(s.AllowedPeriods)
+		r.MaxDuration = s.MaxDuration
+		r.DenyPeriods, _ = splitAtMidnight(s.DenyPeriods)
+		r.WarnBefore = s.WarnBefore
+		if s.SpendableWindow != nil {
+			w, _ := splitAtMidnight([]timePeriod{*s.SpendableWindow})
+			r.SpendableWindow = &w[0]
+		}
+	}
+
+	// periods of the day before crossing midnight
+	previous := date.AddDate(0, 0, -1)
+	if s, _ := c.scheduleOn(a, previous); s != nil {
+		_, allowed := splitAtMidnight(s.AllowedPeriods)
+		_, denied := splitAtMidnight(s.DenyPeriods)
+		for i := range allowed {
+			if allowed[i].MaxDuration == 0 {
+				allowed[i].MaxDuration = s.MaxDuration
+			}
+		}
+		for _, p := range allowed {
+			if p.End > r.continuedUntil {
+				r.continuedUntil = p.End
+			}
+		}
+		if len(allowed) > 0 || len(denied) > 0 {
+			r.Allowed = r.Allowed || len(allowed) > 0
+			r.AllowedPeriods = append(allowed, r.AllowedPeriods...)
+			r.DenyPeriods = append(denied, r.DenyPeriods...)
+			r.Modifiers = append(r.Modifiers, fmt.Sprintf("periods of %s continuing after midnight", previous.Weekday().String()))
+		}
+		// the spendable window of the day before governs until it ends,
+		// along with the maximum duration it bounds
+		if s.SpendableWindow != nil {
+			if _, w := splitAtMidnight([]timePeriod{*s.SpendableWindow}); len(w) > 0 && date.Hour()*100+date.Minute() < w[0].End {
+				r.Allowed = true
+				r.SpendableWindow = &w[0]
+				r.MaxDuration = s.MaxDuration
+				if w[0].End > r.continuedUntil {
+					r.continuedUntil = w[0].End
+				}
+				r.Modifiers = append(r.Modifiers, fmt.Sprintf("spendable window of %s continuing after midnight", previous.Weekday().String()))
+			}
+		}
+	}
+
+	if r.Allowed && c.probationActiveAt(date) {
+		r.MaxDuration = duration(float64(r.MaxDuration) * c.ProbationFactor)
+		for i := range r.AllowedPeriods {
+			r.AllowedPeriods[i].MaxDuration = duration(float64(r.AllowedPeriods[i].MaxDuration) * c.ProbationFactor)
+		}
+		r.Modifiers = append(r.Modifiers, fmt.Sprintf("probation at %.0f%% until %s", c.ProbationFactor*100, c.ProbationUntil.Format("2006-01-02 15:04")))
+	}
+
+	if earned := c.credit(activity, date); r.Allowed && earned > 0 {
+		r.MaxDuration += duration(earned)
+		for i := range r.AllowedPeriods {
+			if r.AllowedPeriods[i].MaxDuration > 0 {
+				r.AllowedPeriods[i].MaxDuration += duration(earned)
+			}
+		}
+		r.Modifiers = append(r.Modifiers, fmt.Sprintf("%s earned", earned))
+	}
+
+	if c.LockdownUntil.After(date) {
+		dayEnd := time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, date.Location())
+		if c.LockdownUntil.Before(dayEnd) {
+			r.Modifiers = append(r.Modifiers, fmt.Sprintf("lockdown until %s", c.LockdownUntil.Format("15:04")))
+		} else {
+			r.Modifiers = append(r.Modifiers, "lockdown all day")
+		}
+	}
+
+	return r
+}
+
+// scheduleOn returns the schedule of the rule on the day of date, nil when
+// the activity is not allowed that day, along with what replaced its weekday
+// schedule, if anything. Overrides of the date come first, holidays follow
+// the holiday schedule of the rule, its saturday schedule when it has none,
+// and the days without a schedule of their own follow its default schedule.
+func (c *dadController) scheduleOn(a *activityRule, date time.Time) (*schedule, string) {
+	if s, found := c.override(a, date); found {
+		return s, "override of " + date.Format("2006-01-02")
+	}
+	if c.isHoliday(date) {
+		if a.HolidaySchedule != nil {
+			return a.HolidaySchedule, "holiday"
+		}
+		s, _ := a.weekdaySchedule(time.Saturday)
+		return s, "holiday, saturday schedule"
+	}
+	s, found := a.weekdaySchedule(date.Weekday())
+	if !found && s != nil {
+		return s, "default schedule"
+	}
+	return s, ""
+}
+
+// weekdaySchedule returns the schedule of the rule on day, its default
+// schedule when day has none, found telling whether day has one.
+func (a *activityRule) weekdaySchedule(day time.Weekday) (s *schedule, found bool) {
+	if s, found := a.AllowedSchedules[day]; found {
+		return s, true
+	}
+	return a.DefaultSchedule, false
+}
+
+// splitAtMidnight returns the periods of a day, the periods ending before
+// they begin, e.g. 2200-0100, crossing midnight. Their part before midnight
+// is returned with the other periods, their part after midnight, which
+// belongs to the next day, separately.
+func splitAtMidnight(periods []timePeriod) ([]timePeriod, []timePeriod) {
+	var sameDay, nextDay []timePeriod
+	for _, p := range periods {
+		if p.End >= p.Begin {
+			sameDay = append(sameDay, p)
+			continue
+		}
+		sameDay = append(sameDay, timePeriod{Begin: p.Begin, End: 2400, MaxDuration: p.MaxDuration})
+		if p.End > 0 {
+			nextDay = append(nextDay, timePeriod{Begin: 0, End: p.End, MaxDuration: p.MaxDuration})
+		}
+	}
+	return sameDay, nextDay
+}
+
+// continuationUsage returns what user spent on activity the day before now
+// when now falls in a period of that day continuing after midnight, so that
+// the period is capped by the usage since it began rather than since
+// midnight.
+func (c *dadController) continuationUsage(r resolvedSchedule, user string, activity string, now time.Time) time.Duration {
+	if now.Hour()*100+now.Minute() >= r.continuedUntil {
+		return 0
+	}
+	return time.Duration(c.durationsOf(user)[dateKey(now.AddDate(0, 0, -1))][activity])
+}
+
+// contains tells whether dayTime, as HHMM, falls in the period, which ends
+// the next day when it ends before it begins.
+func (p timePeriod) contains(dayTime int) bool {
+	if p.End < p.Begin {
+		return dayTime >= p.Begin || dayTime < p.End
+	}
+	return dayTime >= p.Begin && dayTime < p.End
+}
+
+// maxDurationAt returns the maximum duration governing at t, which is the
+// maximum duration of the day unless t falls in an allowed period having its
+// own maximum duration. Overlapping periods are resolved according to mode,
+// which defaults to periodOverlapMostRestrictive.
+func (r resolvedSchedule) maxDurationAt(t time.Time, mode string) time.Duration {
+	dayTime := t.Hour()*100 + t.Minute()
+	var governing []time.Duration
+	for _, p := range r.AllowedPeriods {
+		if dayTime < p.Begin || dayTime >= p.End {
+			continue
+		}
+		if p.MaxDuration > 0 {
+			governing = append(governing, time.Duration(p.MaxDuration))
+		} else {
+			governing = append(governing, time.Duration(r.MaxDuration))
+		}
+	}
+	if len(governing) == 0 {
+		return time.Duration(r.MaxDuration)
+	}
+
+	chosen := governing[0]
+	for _, d := range governing[1:] {
+		switch mode {
+		case periodOverlapFirstMatch:
+		case periodOverlapMostGenerous:
+			if d > chosen {
+				chosen = d
+			}
+		default:
+			if d < chosen {
+				chosen = d
+			}
+		}
+	}
+	return chosen
+}
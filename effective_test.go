@@ -0,0 +1,140 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEffectiveScheduleFor(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedOnlyOnSunday("GTA", "GTA.exe", time.Duration(15)*time.Minute)
+	ar := ctx.controller.getOrCreateActivityRule("GTA")
+	ar.SetSpendableWindow([]time.Weekday{time.Sunday}, 800, 2000)
+
+	sunday := time.Date(2024, time.June, 2, 10, 0, 0, 0, time.Local)
+	expected := resolvedSchedule{
+		schedule: schedule{
+			AllowedPeriods:  []timePeriod{{Begin: 0, End: 2359}},
+			MaxDuration:     duration(15 * time.Minute),
+			SpendableWindow: &timePeriod{Begin: 800, End: 2000},
+		},
+		Activity: "GTA",
+		Date:     "2024-06-02",
+		Allowed:  true,
+	}
+	if r := ctx.controller.EffectiveScheduleFor("GTA", sunday); !reflect.DeepEqual(r, expected) {
+		t.Errorf("resolved %+v (expected %+v)", r, expected)
+	}
+
+	if r := ctx.controller.EffectiveScheduleFor("GTA", sunday.AddDate(0, 0, 1)); r.Allowed {
+		t.Errorf("GTA should not be allowed on Monday: %+v", r)
+	}
+	if r := ctx.controller.EffectiveScheduleFor("Unknown", sunday); r.Allowed {
+		t.Errorf("unknown activity should not be allowed: %+v", r)
+	}
+}
+
+func TestEffectiveScheduleForListsLockdown(t *testing.T) {
+	sunday := time.Date(2024, time.June, 2, 10, 0, 0, 0, time.Local)
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenTimeIs(sunday).
+		GivenAnActivityRuleAllowedOnlyOnSunday("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		WhenLockdownStartsFor(time.Duration(90) * time.Minute)
+
+	if r := ctx.controller.EffectiveScheduleFor("GTA", sunday); !reflect.DeepEqual(r.Modifiers, []string{"lockdown until 11:30"}) {
+		t.Errorf("modifiers are %q", r.Modifiers)
+	}
+	if r := ctx.controller.EffectiveScheduleFor("GTA", sunday.Add(2*time.Hour)); len(r.Modifiers) != 0 {
+		t.Errorf("lockdown should be over: %q", r.Modifiers)
+	}
+}
+
+func testPeriodCrossingMidnight(t *testing.T, now time.Time, killed bool) {
+	ctx := NewTest(t).
+		GivenTimeIs(now).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ar := ctx.controller.getOrCreateActivityRule("GTA")
+	ar.AddProgramPattern("GTA.exe")
+	ar.SetMaximumAllowedDurationPerDay([]time.Weekday{time.Saturday}, time.Duration(2)*time.Hour)
+	ar.AddAllowedPeriod([]time.Weekday{time.Saturday}, 2200, 100)
+
+	ctx.WhenScanHappens()
+	if killed != (len(ctx.killedProcesses) > 0) {
+		t.Errorf("at %s, GTA killed: %t (expected %t)", now, !killed, killed)
+	}
+}
+
+func TestPeriodCrossingMidnight(t *testing.T) {
+	testPeriodCrossingMidnight(t, time.Date(2024, time.October, 12, 21, 30, 0, 0, time.Local), true)
+	testPeriodCrossingMidnight(t, time.Date(2024, time.October, 12, 23, 0, 0, 0, time.Local), false)
+	testPeriodCrossingMidnight(t, time.Date(2024, time.October, 13, 0, 30, 0, 0, time.Local), false)
+	testPeriodCrossingMidnight(t, time.Date(2024, time.October, 13, 1, 30, 0, 0, time.Local), true)
+	// the morning of Saturday is not part of the period
+	testPeriodCrossingMidnight(t, time.Date(2024, time.October, 12, 0, 30, 0, 0, time.Local), true)
+}
+
+func TestPeriodCrossingMidnightIsCappedByTheUsageSinceItBegan(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 12, 23, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ar := ctx.controller.getOrCreateActivityRule("GTA")
+	ar.AddProgramPattern("GTA.exe")
+	ar.SetMaximumAllowedDurationPerDay([]time.Weekday{time.Saturday}, time.Duration(1)*time.Hour)
+	ar.AddAllowedPeriod([]time.Weekday{time.Saturday}, 2200, 100)
+
+	ctx.WhenScanHappens().
+		GivenAnActivityDuration("GTA", time.Duration(1)*time.Hour).
+		ThenNoProcessKilled().
+		GivenTimeIs(time.Date(2024, time.October, 13, 0, 10, 0, 0, time.Local)).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this day")
+}
+
+func testSpendableWindowCrossingMidnight(t *testing.T, now time.Time, killed bool) {
+	ctx := NewTest(t).
+		GivenTimeIs(now).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ar := ctx.controller.getOrCreateActivityRule("GTA")
+	ar.AddProgramPattern("GTA.exe")
+	ar.SetMaximumAllowedDurationPerDay([]time.Weekday{time.Saturday}, time.Duration(2)*time.Hour)
+	ar.SetSpendableWindow([]time.Weekday{time.Saturday}, 2200, 100)
+
+	ctx.WhenScanHappens()
+	if killed != (len(ctx.killedProcesses) > 0) {
+		t.Errorf("at %s, GTA killed: %t (expected %t)", now, !killed, killed)
+	}
+}
+
+func TestSpendableWindowCrossingMidnight(t *testing.T) {
+	testSpendableWindowCrossingMidnight(t, time.Date(2024, time.October, 12, 21, 30, 0, 0, time.Local), true)
+	testSpendableWindowCrossingMidnight(t, time.Date(2024, time.October, 12, 23, 0, 0, 0, time.Local), false)
+	testSpendableWindowCrossingMidnight(t, time.Date(2024, time.October, 13, 0, 30, 0, 0, time.Local), false)
+	testSpendableWindowCrossingMidnight(t, time.Date(2024, time.October, 13, 1, 30, 0, 0, time.Local), true)
+	// the morning of Saturday is not part of the window
+	testSpendableWindowCrossingMidnight(t, time.Date(2024, time.October, 12, 0, 30, 0, 0, time.Local), true)
+}
+
+func TestCompactPeriodCrossingMidnight(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 13, 0, 30, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ar := ctx.controller.getOrCreateActivityRule("GTA")
+	ar.AddProgramPattern("GTA.exe")
+	ar.Allow = "sat 22:00-01:00 max 2h"
+	if err := ar.expandAllow(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.WhenScanHappens().
+		ThenNoProcessKilled().
+		GivenTimeIs(time.Date(2024, time.October, 13, 1, 30, 0, 0, time.Local)).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this day")
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// countKill counts a kill of the processes of activity on the day of now,
+// for each of their owners.
+func (c *dadController) countKill(activity string, rp []runningProcess, now time.Time) {
+	day := dateKey(now)
+	counted := make(map[string]bool)
+	for _, p := range rp {
+		if counted[p.UserID] {
+			continue
+		}
+		counted[p.UserID] = true
+		if c.Kills == nil {
+			c.Kills = make(map[string]map[string]map[string]int)
+		}
+		if c.Kills[p.UserID] == nil {
+			c.Kills[p.UserID] = make(map[string]map[string]int)
+		}
+		if c.Kills[p.UserID][day] == nil {
+			c.Kills[p.UserID][day] = make(map[string]int)
+		}
+		c.Kills[p.UserID][day][activity]++
+	}
+}
+
+// forgetOldKills drops the kill counts of the days before oldest, removing
+// the users left without any.
+func (c *dadController) forgetOldKills(oldest string) {
+	for user, days := range c.Kills {
+		for day := range days {
+			if day < oldest {
+				delete(days, day)
+			}
+		}
+		if len(days) == 0 {
+			delete(c.Kills, user)
+		}
+	}
+}
+
+// usageRow is the time spent by a user on an activity on a day, and the
+// number of times it was killed, the user being empty when unknown.
+type usageRow struct {
+	Day      string
+	User     string
+	Activity string
+	Duration time.Duration
+	Kills    int
+}
+
+// usage returns the usage of the days kept from from to to, both included
+// and ignored when empty, sorted by day, user and activity.
+func (c *dadController) usage(from string, to string) []usageRow {
+	rows := make(map[[3]string]*usageRow)
+	row := func(day, user, activity string) *usageRow {
+		key := [3]string{day, user, activity}
+		if rows[key] == nil {
+			rows[key] = &usageRow{Day: day, User: user, Activity: activity}
+		}
+		return rows[key]
+	}
+	inRange := func(day string) bool {
+		return (from == "" || day >= from) && (to == "" || day <= to)
+	}
+
+	durations := map[string]map[string]map[string]duration{"": c.ActivityDuration}
+	for user, d := range c.UserActivityDuration {
+		durations[user] = d
+	}
+	for user, days := range durations {
+		for day, ad := range days {
+			if !inRange(day) {
+				continue
+			}
+			for activity, d := range ad {
+				row(day, user, activity).Duration = time.Duration(d)
+			}
+		}
+	}
+	for user, days := range c.Kills {
+		for day, kills := range days {
+			if !inRange(day) {
+				continue
+			}
+			for activity, n := range kills {
+				row(day, user, activity).Kills = n
+			}
+		}
+	}
+
+	results := make([]usageRow, 0, len(rows))
+	for _, r := range rows {
+		results = append(results, *r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.User != b.User {
+			return a.User < b.User
+		}
+		return a.Activity < b.Activity
+	})
+	return results
+}
+
+// writeUsageCSV writes rows as CSV, the durations in minutes so that they
+// can be summed by a spreadsheet.
+func writeUsageCSV(w io.Writer, rows []usageRow) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"date", "user", "activity", "minutes", "kills"})
+	for _, r := range rows {
+		out.Write([]string{r.Day, r.User, r.Activity, strconv.FormatFloat(r.Duration.Minutes(), 'f', 1, 64), strconv.Itoa(r.Kills)})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// exportUsage runs the export command, writing the usage saved in the state
+// file as asked by args, e.g. --format csv --from 2024-10-01 --to
+// 2024-10-31.
+func exportUsage(w io.Writer, stateFile string, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "csv", "output format, only csv is supported")
+	from := flags.String("from", "", "first day exported, e.g. 2024-10-01, the oldest one kept by default")
+	to := flags.String("to", "", "last day exported, e.g. 2024-10-31, the last one by default")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *format != "csv" {
+		return fmt.Errorf("unknown format %q, expected csv", *format)
+	}
+	for _, day := range []string{*from, *to} {
+		if _, err := time.Parse("2006-01-02", day); day != "" && err != nil {
+			return fmt.Errorf("invalid day %q, expected e.g. 2024-10-31", day)
+		}
+	}
+
+	c := newDadController(minSamplingInterval, time.Now)
+	c.stateFile = stateFile
+	if !c.reloadState() {
+		return fmt.Errorf("no state could be read from %s", stateFile)
+	}
+	return writeUsageCSV(w, c.usage(*from, *to))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUsageIsExportedAsCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAnActivityRuleAllowedEveryTime("Minecraft", "Minecraft.exe", time.Duration(2)*time.Hour).
+		GivenAnActivityDuration("Minecraft", time.Duration(90)*time.Minute).
+		WhenDayChanges().
+		GivenAnActivityDuration("GTA", time.Duration(59)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.currentTime = ctx.controller.LastControlTime
+	ctx.WhenScanHappens().WhenScanHappens()
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+	ctx.controller.dumpState()
+
+	var out bytes.Buffer
+	if err := exportUsage(&out, ctx.controller.stateFile, []string{"--format", "csv"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := "date,user,activity,minutes,kills\n" +
+		"2024-10-14,,Minecraft,90.0,0\n" +
+		"2024-10-15,,GTA,61.0,1\n"
+	if out.String() != expected {
+		t.Errorf("unexpected export:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := exportUsage(&out, ctx.controller.stateFile, []string{"--from", "2024-10-15", "--to", "2024-10-31"}); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "date,user,activity,minutes,kills\n2024-10-15,,GTA,61.0,1\n" {
+		t.Errorf("unexpected export from 2024-10-15:\n%s", out.String())
+	}
+
+	if err := exportUsage(ioutil.Discard, ctx.controller.stateFile, []string{"--format", "xlsx"}); err == nil {
+		t.Error("unknown format accepted")
+	}
+}
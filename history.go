@@ -0,0 +1,50 @@
+package main
+
+import "time"
+
+// defaultHistoryWeeks is the number of weeks the daily counters are kept for
+// by default.
+const defaultHistoryWeeks = 4
+
+func (c *dadController) historyWeeks() int {
+	if c.HistoryWeeks <= 0 {
+		return defaultHistoryWeeks
+	}
+	return c.HistoryWeeks
+}
+
+// forgetOldDays drops the daily counters and kill counts of the days before
+// the history kept at now, removing the users left without any.
+func (c *dadController) forgetOldDays(now time.Time) {
+	oldest := dateKey(now.AddDate(0, 0, -7*c.historyWeeks()))
+	forgetDaysBefore(c.ActivityDuration, oldest)
+	for user, durations := range c.UserActivityDuration {
+		forgetDaysBefore(durations, oldest)
+		if len(durations) == 0 {
+			delete(c.UserActivityDuration, user)
+		}
+	}
+	c.forgetOldKills(oldest)
+}
+
+// forgetDaysBefore drops from durations the counters of the days before
+// oldest, e.g. 2024-12-24.
+func forgetDaysBefore(durations map[string]map[string]duration, oldest string) {
+	for day := range durations {
+		if day < oldest {
+			delete(durations, day)
+		}
+	}
+}
+
+// history returns the time spent by user on activity on each day kept, by
+// date.
+func (c *dadController) history(user string, activity string) map[string]duration {
+	results := make(map[string]duration)
+	for day, ad := range c.durationsOf(user) {
+		if d, found := ad[activity]; found {
+			results[day] = d
+		}
+	}
+	return results
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDailyCountersAreKeptForTheHistoryWeeks(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAnActivityDuration("GTA", time.Duration(40)*time.Minute)
+	ctx.controller.HistoryWeeks = 1
+
+	ctx.WhenDayChanges().
+		GivenAnActivityDuration("GTA", time.Duration(30)*time.Minute).
+		WhenDayChanges()
+	expected := map[string]duration{
+		"2024-10-14": duration(time.Duration(40) * time.Minute),
+		"2024-10-15": duration(time.Duration(30) * time.Minute),
+	}
+	if history := ctx.controller.history("", "GTA"); !reflect.DeepEqual(history, expected) {
+		t.Errorf("history is %v (expected %v)", history, expected)
+	}
+
+	ctx.controller.updateActivityCounters(nil, time.Date(2024, time.October, 22, 20, 0, 0, 0, time.Local))
+	delete(expected, "2024-10-14")
+	if history := ctx.controller.history("", "GTA"); !reflect.DeepEqual(history, expected) {
+		t.Errorf("history is %v a week later (expected %v)", history, expected)
+	}
+}
+
+func TestHTTPHistory(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAUserActivityDuration("S-1-5-21-1001", "GTA", time.Duration(40)*time.Minute)
+
+	rec := httptest.NewRecorder()
+	ctx.controller.httpHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/history?activity=GTA&user=S-1-5-21-1001", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var history map[string]duration
+	if err := json.Unmarshal(rec.Body.Bytes(), &history); err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]duration{"2024-10-14": duration(time.Duration(40) * time.Minute)}; !reflect.DeepEqual(history, expected) {
+		t.Errorf("history is %v (expected %v)", history, expected)
+	}
+
+	rec = httptest.NewRecorder()
+	ctx.controller.httpHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/history?activity=Fortnite", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET of an unknown activity returned %d", rec.Code)
+	}
+}
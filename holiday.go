@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// holidayCalendar lists the days on which the rules follow their holiday
+// schedule instead of their weekday one, e.g. school vacations.
+type holidayCalendar struct {
+	// Dates are days, 2024-12-24, or inclusive ranges of days,
+	// 2024-12-21..2025-01-05
+	Dates []string `json:"dates,omitempty"`
+	// ICal is the path of an iCalendar file whose events are holidays
+	ICal string `json:"ical,omitempty"`
+
+	// days of the calendar, by "2006-01-02"
+	days map[string]bool
+}
+
+// load resolves the days of the calendar, reading its iCalendar file.
+func (h *holidayCalendar) load() error {
+	h.days = make(map[string]bool)
+	for _, d := range h.Dates {
+		bounds := strings.SplitN(d, "..", 2)
+		first, err := time.Parse("2006-01-02", strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return fmt.Errorf("invalid holiday %q : %s", d, err)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = time.Parse("2006-01-02", strings.TrimSpace(bounds[1])); err != nil {
+				return fmt.Errorf("invalid holiday %q : %s", d, err)
+			}
+		}
+		h.addDays(first, last.AddDate(0, 0, 1))
+	}
+
+	if h.ICal != "" {
+		data, err := ioutil.ReadFile(h.ICal)
+		if err != nil {
+			return fmt.Errorf("failure to read holidays : %s", err)
+		}
+		events, err := parseICalEvents(data)
+		if err != nil {
+			return fmt.Errorf("invalid holidays in %s : %s", h.ICal, err)
+		}
+		for _, e := range events {
+			h.addDays(e[0], e[1])
+		}
+	}
+	return nil
+}
+
+// addDays adds the days from first included to end excluded.
+func (h *holidayCalendar) addDays(first time.Time, end time.Time) {
+	for d := first; d.Before(end); d = d.AddDate(0, 0, 1) {
+		h.days[d.Format("2006-01-02")] = true
+	}
+}
+
+func (c *dadController) isHoliday(date time.Time) bool {
+	return c.Holidays != nil && c.Holidays.days[date.Format("2006-01-02")]
+}
+
+// parseICalEvents returns the first day and the day after the last day of
+// the events of an iCalendar file.
+func parseICalEvents(data []byte) ([][2]time.Time, error) {
+	// unfold the lines continued on the next ones
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var events [][2]time.Time
+	var start, end time.Time
+	inEvent := false
+	for _, line := range lines {
+		name, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			name, value = line[:i], line[i+1:]
+		}
+		if i := strings.Index(name, ";"); i >= 0 {
+			name = name[:i]
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "BEGIN":
+			if strings.EqualFold(value, "VEVENT") {
+				inEvent, start, end = true, time.Time{}, time.Time{}
+			}
+		case "DTSTART":
+			if inEvent {
+				start, err = parseICalDay(value, false)
+			}
+		case "DTEND":
+			if inEvent {
+				end, err = parseICalDay(value, true)
+			}
+		case "END":
+			if inEvent && strings.EqualFold(value, "VEVENT") {
+				inEvent = false
+				if start.IsZero() {
+					return nil, fmt.Errorf("event without DTSTART")
+				}
+				if !end.After(start) {
+					end = start.AddDate(0, 0, 1)
+				}
+				events = append(events, [2]time.Time{start, end})
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// parseICalDay parses the day of a DATE or DATE-TIME value. The day of an
+// end DATE-TIME later than midnight is part of the event, so the day after
+// it is returned.
+func parseICalDay(value string, isEnd bool) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	day, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	if isEnd && len(value) > 8 && !strings.HasPrefix(value[8:], "T000000") {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHolidaysFollowTheSaturdaySchedule(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedOnlyOnSunday("GTA", "GTA.exe", time.Duration(15)*time.Minute)
+	ar := ctx.controller.getOrCreateActivityRule("GTA")
+	ar.SetMaximumAllowedDurationPerDay([]time.Weekday{time.Saturday}, time.Duration(3)*time.Hour)
+	ar.AddAllowedPeriod([]time.Weekday{time.Saturday}, 1000, 2000)
+	ctx.controller.Holidays = &holidayCalendar{Dates: []string{"2024-12-23..2024-12-24"}}
+	if err := ctx.controller.Holidays.load(); err != nil {
+		t.Fatal(err)
+	}
+
+	monday := time.Date(2024, time.December, 23, 10, 0, 0, 0, time.Local)
+	r := ctx.controller.EffectiveScheduleFor("GTA", monday)
+	if !r.Allowed || r.MaxDuration != duration(time.Duration(3)*time.Hour) || !reflect.DeepEqual(r.Modifiers, []string{"holiday, saturday schedule"}) {
+		t.Errorf("holiday resolved as %+v", r)
+	}
+	if r := ctx.controller.EffectiveScheduleFor("GTA", monday.AddDate(0, 0, 2)); r.Allowed {
+		t.Errorf("GTA should not be allowed after the holidays: %+v", r)
+	}
+
+	ar.HolidaySchedule = &schedule{AllowedPeriods: []timePeriod{{Begin: 1400, End: 1800}}, MaxDuration: duration(time.Hour)}
+	if r := ctx.controller.EffectiveScheduleFor("GTA", monday); r.MaxDuration != duration(time.Hour) {
+		t.Errorf("holiday schedule of the rule should apply: %+v", r)
+	}
+}
+
+func TestHolidaysAreImportedFromICal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "holidays.ics")
+	ical := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Vacances de\r\n  Noël\r\nDTSTART;VALUE=DATE:20241221\r\nDTEND;VALUE=DATE:20241223\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nDTSTART:20250101T080000Z\r\nDTEND:20250101T170000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	if err := ioutil.WriteFile(path, []byte(ical), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &holidayCalendar{ICal: path}
+	if err := h.load(); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]bool{"2024-12-21": true, "2024-12-22": true, "2025-01-01": true}
+	if !reflect.DeepEqual(h.days, expected) {
+		t.Errorf("holidays are %v (expected %v)", h.days, expected)
+	}
+}
+
+func TestInvalidHolidayIsRejected(t *testing.T) {
+	if _, err := parseConfig([]byte(`{"holidays": {"dates": ["2024-13-01"]}, "rules": []}`)); err == nil {
+		t.Error("configuration with an invalid holiday should be invalid")
+	}
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed dashboard/index.html
+var dashboard embed.FS
+
+func (c *dadController) serveHTTP(addr string) {
+	fmt.Fprintf(logOutput, "Serving HTTP API on %s\n", addr)
+	if err := http.ListenAndServe(addr, c.httpHandler()); err != nil {
+		fmt.Fprintln(logOutput, "Failure to serve HTTP API : ", err)
+	}
+}
+
+func (c *dadController) httpHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sampling-interval", c.handleSamplingInterval)
+	mux.HandleFunc("/lockdown", c.handleLockdown)
+	mux.HandleFunc("/schedule", c.handleSchedule)
+	mux.HandleFunc("/preview", c.handlePreview)
+	mux.HandleFunc("/exempt", c.handleExempt)
+	mux.HandleFunc("/override", c.handleOverride)
+	mux.HandleFunc("/credit", c.handleCredit)
+	mux.HandleFunc("/profile", c.handleProfile)
+	mux.HandleFunc("/status", c.handleStatus)
+	mux.HandleFunc("/history", c.handleHistory)
+	mux.HandleFunc("/", handleDashboard)
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Fprintln(logOutput, "Failure to write HTTP response : ", err)
+	}
+}
+
+// checkPIN rejects the request unless it carries the configured PIN, either
+// as the "pin" parameter or the X-PIN header. Without a PIN configured, every
+// request is rejected so that the controller cannot be changed by anyone
+// reaching the port.
+func (c *dadController) checkPIN(w http.ResponseWriter, r *http.Request) bool {
+	c.mu.Lock()
+	expected := c.PIN
+	c.mu.Unlock()
+	if expected == "" {
+		http.Error(w, "no PIN is configured, changes are disabled", http.StatusForbidden)
+		return false
+	}
+
+	pin := r.Header.Get("X-PIN")
+	if pin == "" {
+		pin = r.FormValue("pin")
+	}
+	if subtle.ConstantTimeCompare([]byte(pin), []byte(expected)) != 1 {
+		http.Error(w, "invalid PIN", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// authorizeChange checks the PIN of a request changing the controller and
+// returns the reason given for the change in the "reason" parameter,
+// rejecting the request when none is given and reasons are required.
+func (c *dadController) authorizeChange(w http.ResponseWriter, r *http.Request) (string, bool) {
+	if !c.checkPIN(w, r) {
+		return "", false
+	}
+
+	reason := strings.TrimSpace(r.FormValue("reason"))
+	c.mu.Lock()
+	requireReason := c.RequireReason
+	c.mu.Unlock()
+	if requireReason && reason == "" {
+		http.Error(w, "a reason is required", http.StatusBadRequest)
+		return "", false
+	}
+	return reason, true
+}
+
+// handleSamplingInterval returns the sampling interval on GET and changes it
+// on POST /sampling-interval?value=30s.
+func (c *dadController) handleSamplingInterval(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		reason, ok := c.authorizeChange(w, r)
+		if !ok {
+			return
+		}
+		d, err := time.ParseDuration(r.FormValue("value"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid sampling interval: %s", err), http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		c.setSamplingIntervalLocked(d)
+		c.audit(auditEntry{Time: c.now(), Action: "sampling-interval", Details: time.Duration(c.SamplingInterval).String(), Reason: reason, Remote: r.RemoteAddr})
+		c.dumpState()
+		c.mu.Unlock()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, map[string]duration{"samplingInterval": duration(c.getSamplingInterval())})
+}
+
+// handleLockdown returns the end of the lockdown on GET, starts one on
+// POST /lockdown?minutes=60 and lifts it on DELETE.
+func (c *dadController) handleLockdown(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		reason, ok := c.authorizeChange(w, r)
+		if !ok {
+			return
+		}
+		minutes, err := strconv.Atoi(r.FormValue("minutes"))
+		if err != nil || minutes <= 0 {
+			http.Error(w, "minutes must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		c.Lockdown(time.Duration(minutes) * time.Minute)
+		c.audit(auditEntry{Time: c.now(), Action: "lockdown", Details: fmt.Sprintf("%d minutes", minutes), Reason: reason, Remote: r.RemoteAddr})
+		c.dumpState()
+		c.mu.Unlock()
+	case http.MethodDelete:
+		reason, ok := c.authorizeChange(w, r)
+		if !ok {
+			return
+		}
+		c.mu.Lock()
+		c.Lockdown(0)
+		c.audit(auditEntry{Time: c.now(), Action: "lockdown-lift", Reason: reason, Remote: r.RemoteAddr})
+		c.dumpState()
+		c.mu.Unlock()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.mu.Lock()
+	until := c.LockdownUntil
+	c.mu.Unlock()
+	writeJSON(w, map[string]time.Time{"lockdownUntil": until})
+}
+
+// handleExempt returns the exemptions in progress on GET and spares a running
+// process from enforcement on POST /exempt?pid=1234&minutes=30.
+func (c *dadController) handleExempt(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		reason, ok := c.authorizeChange(w, r)
+		if !ok {
+			return
+		}
+		pid, err := strconv.Atoi(r.FormValue("pid"))
+		if err != nil {
+			http.Error(w, "pid must be an integer", http.StatusBadRequest)
+			return
+		}
+		minutes, err := strconv.Atoi(r.FormValue("minutes"))
+		if err != nil || minutes <= 0 {
+			http.Error(w, "minutes must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		processes, err := c.listProcesses()
+		if err != nil {
+			c.mu.Unlock()
+			http.Error(w, fmt.Sprintf("failure to list running processes: %s", err), http.StatusInternalServerError)
+			return
+		}
+		p, found := findProcess(processes, pid)
+		if !found {
+			c.mu.Unlock()
+			http.Error(w, fmt.Sprintf("no running process with pid %d", pid), http.StatusNotFound)
+			return
+		}
+		c.Exempt(p, time.Duration(minutes)*time.Minute)
+		c.audit(auditEntry{Time: c.now(), Action: "exempt", Details: fmt.Sprintf("%d (%s) for %d minutes", p.Pid, p.Path, minutes), Reason: reason, Remote: r.RemoteAddr})
+		c.dumpState()
+		c.mu.Unlock()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.mu.Lock()
+	exemptions := append([]processExemption{}, c.Exemptions...)
+	c.mu.Unlock()
+	writeJSON(w, exemptions)
+}
+
+// handleOverride returns the overrides in progress on GET and replaces the
+// schedule of an activity on a single date on
+// POST /override?activity=GTA&date=2024-12-24&maxDuration=3h&periods=14:00-22:00.
+func (c *dadController) handleOverride(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		reason, ok := c.authorizeChange(w, r)
+		if !ok {
+			return
+		}
+		activity := r.FormValue("activity")
+		if activity == "" {
+			http.Error(w, "activity is required", http.StatusBadRequest)
+			return
+		}
+		maxDuration, err := time.ParseDuration(r.FormValue("maxDuration"))
+		if err != nil || maxDuration < 0 {
+			http.Error(w, "maxDuration must be a duration such as 3h", http.StatusBadRequest)
+			return
+		}
+		periods, err := parsePeriods(r.FormValue("periods"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid periods: %s", err), http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		if !c.hasActivity(activity) {
+			c.mu.Unlock()
+			http.Error(w, fmt.Sprintf("unknown activity %s", activity), http.StatusNotFound)
+			return
+		}
+		date, err := time.ParseInLocation("2006-01-02", r.FormValue("date"), c.now().Location())
+		if err != nil {
+			c.mu.Unlock()
+			http.Error(w, fmt.Sprintf("invalid date: %s", err), http.StatusBadRequest)
+			return
+		}
+		o := c.Override(activity, date, schedule{AllowedPeriods: periods, MaxDuration: duration(maxDuration)})
+		c.audit(auditEntry{Time: c.now(), Action: "override", Details: fmt.Sprintf("%s on %s: %s max %s", o.Activity, o.Date, r.FormValue("periods"), maxDuration), Reason: reason, Remote: r.RemoteAddr})
+		c.dumpState()
+		c.mu.Unlock()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.mu.Lock()
+	overrides := append([]scheduleOverride{}, c.Overrides...)
+	c.mu.Unlock()
+	writeJSON(w, overrides)
+}
+
+// handleCredit returns the time earned on GET and adds time to the maximum
+// durations of an activity for today on POST /credit?activity=GTA&minutes=30.
+func (c *dadController) handleCredit(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		reason, ok := c.authorizeChange(w, r)
+		if !ok {
+			return
+		}
+		activity := r.FormValue("activity")
+		minutes, err := strconv.Atoi(r.FormValue("minutes"))
+		if err != nil || minutes <= 0 {
+			http.Error(w, "minutes must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		if !c.hasActivity(activity) {
+			c.mu.Unlock()
+			http.Error(w, fmt.Sprintf("unknown activity %s", activity), http.StatusNotFound)
+			return
+		}
+		credit := c.Credit(activity, c.now(), time.Duration(minutes)*time.Minute)
+		c.audit(auditEntry{Time: c.now(), Action: "credit", Details: fmt.Sprintf("%d minutes on %s for %s", minutes, credit.Activity, credit.Date), Reason: reason, Remote: r.RemoteAddr})
+		c.dumpState()
+		c.mu.Unlock()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.mu.Lock()
+	credits := append([]timeCredit{}, c.Credits...)
+	c.mu.Unlock()
+	writeJSON(w, credits)
+}
+
+// handleProfile returns the active profile on GET, switches to another one
+// on POST /profile?name=Emma and back to the accounts on DELETE.
+func (c *dadController) handleProfile(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodDelete:
+		reason, ok := c.authorizeChange(w, r)
+		if !ok {
+			return
+		}
+		name := ""
+		if r.Method == http.MethodPost {
+			name = r.FormValue("name")
+			if name == "" {
+				http.Error(w, "name is required", http.StatusBadRequest)
+				return
+			}
+		}
+		c.mu.Lock()
+		if err := c.SwitchProfile(name); err != nil {
+			c.mu.Unlock()
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		c.audit(auditEntry{Time: c.now(), Action: "profile", Details: name, Reason: reason, Remote: r.RemoteAddr})
+		c.dumpState()
+		c.mu.Unlock()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.mu.Lock()
+	profile := c.ActiveProfile
+	c.mu.Unlock()
+	writeJSON(w, map[string]string{"profile": profile})
+}
+
+// handleStatus returns on GET the time used and remaining on each activity,
+// whether it is blocked and the recent events.
+func (c *dadController) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.mu.Lock()
+	status := c.status()
+	c.mu.Unlock()
+	writeJSON(w, status)
+}
+
+// handleHistory returns the time spent each day kept on the activity given
+// by the activity parameter, by the processes of the user given by the user
+// parameter or else of unknown owner.
+func (c *dadController) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	activity := r.FormValue("activity")
+	c.mu.Lock()
+	if !c.hasActivity(activity) {
+		c.mu.Unlock()
+		http.Error(w, fmt.Sprintf("unknown activity %s", activity), http.StatusNotFound)
+		return
+	}
+	history := c.history(r.FormValue("user"), activity)
+	c.mu.Unlock()
+	writeJSON(w, history)
+}
+
+// handleDashboard serves the read-only dashboard polling /status.
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	page, err := dashboard.ReadFile("dashboard/index.html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
+
+// handleSchedule returns the effective schedule of an activity on GET
+// /schedule?activity=GTA&date=2024-12-24, the date defaulting to today, for
+// the processes of the user given by the user parameter if any.
+func (c *dadController) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	date := c.now()
+	if value := r.FormValue("date"); value != "" {
+		d, err := time.ParseInLocation("2006-01-02", value, date.Location())
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid date: %s", err), http.StatusBadRequest)
+			return
+		}
+		date = d
+	}
+	writeJSON(w, c.effectiveScheduleOf(r.FormValue("user"), r.FormValue("activity"), date))
+}
+
+// handlePreview returns on GET the actions a scan would take right now,
+// without taking them.
+func (c *dadController) handlePreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.mu.Lock()
+	actions := c.preview()
+	c.mu.Unlock()
+	if actions == nil {
+		actions = []enforcementAction{}
+	}
+	writeJSON(w, actions)
+}
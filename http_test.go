@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPIN = "1234"
+
+// pinnedHandler configures a PIN and returns the HTTP API of the controller,
+// sending the PIN with every request.
+func (ctx *TestContext) pinnedHandler() http.Handler {
+	ctx.controller.PIN = testPIN
+	handler := ctx.controller.httpHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("X-PIN", testPIN)
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func TestHTTPSamplingInterval(t *testing.T) {
+	ctx := NewTest(t).GivenADadControllerWithSamplingInterval(time.Duration(1) * time.Minute)
+	handler := ctx.pinnedHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sampling-interval?value=2m", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST returned %d: %s", rec.Code, rec.Body.String())
+	}
+	ctx.ThenSamplingIntervalShouldBe(time.Duration(2) * time.Minute)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sampling-interval", nil))
+	if !strings.Contains(rec.Body.String(), `"samplingInterval":"2m0s"`) {
+		t.Errorf("GET returned %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/sampling-interval?value=soon", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid interval returned %d", rec.Code)
+	}
+}
+
+func TestHTTPLockdownRequiresPIN(t *testing.T) {
+	ctx := NewTest(t).GivenADadControllerWithSamplingInterval(time.Duration(1) * time.Minute)
+	ctx.controller.PIN = "1234"
+	handler := ctx.controller.httpHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/lockdown?minutes=60&pin=0000", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("POST with wrong PIN returned %d", rec.Code)
+	}
+	if !ctx.controller.LockdownUntil.IsZero() {
+		t.Error("lockdown started with wrong PIN")
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/lockdown?minutes=60", nil)
+	req.Header.Set("X-PIN", "1234")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST returned %d: %s", rec.Code, rec.Body.String())
+	}
+	if ctx.controller.LockdownUntil.Before(ctx.currentTime.Add(time.Duration(59) * time.Minute)) {
+		t.Errorf("lockdown until %s", ctx.controller.LockdownUntil)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/lockdown?pin=1234", nil))
+	if rec.Code != http.StatusOK || !ctx.controller.LockdownUntil.IsZero() {
+		t.Errorf("DELETE returned %d, lockdown until %s", rec.Code, ctx.controller.LockdownUntil)
+	}
+}
+
+func TestHTTPPreview(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+
+	rec := httptest.NewRecorder()
+	ctx.controller.httpHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/preview", nil))
+	if !strings.Contains(rec.Body.String(), `"action":"kill","reason":"Activity duration above threshold for this day"`) {
+		t.Errorf("GET /preview returned %s", rec.Body.String())
+	}
+	ctx.ThenNoProcessKilled()
+}
+
+func TestHTTPChangesRequireAReasonWhenConfigured(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).GivenADadControllerWithSamplingInterval(time.Duration(1) * time.Minute)
+	ctx.controller.RequireReason = true
+	ctx.controller.setAuditLog(filepath.Join(dir, "audit.log"), rotationPolicy{})
+	defer ctx.controller.auditFile.Close()
+	handler := ctx.pinnedHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/lockdown?minutes=60", nil))
+	if rec.Code != http.StatusBadRequest || !ctx.controller.LockdownUntil.IsZero() {
+		t.Errorf("POST without reason returned %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/lockdown?minutes=60&reason=dinner+time", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST returned %d: %s", rec.Code, rec.Body.String())
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry auditEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Fatalf("invalid audit log %q: %s", string(data), err)
+	}
+	if entry.Action != "lockdown" || entry.Details != "60 minutes" || entry.Reason != "dinner time" {
+		t.Errorf("audit entry is %+v", entry)
+	}
+}
+
+func TestHTTPReasonIsOptionalByDefault(t *testing.T) {
+	ctx := NewTest(t).GivenADadControllerWithSamplingInterval(time.Duration(1) * time.Minute)
+
+	rec := httptest.NewRecorder()
+	ctx.pinnedHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/lockdown?minutes=60", nil))
+	if rec.Code != http.StatusOK || ctx.controller.LockdownUntil.IsZero() {
+		t.Errorf("POST without reason returned %d", rec.Code)
+	}
+}
+
+func TestHTTPExemptRunningProcess(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1234)
+	handler := ctx.pinnedHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/exempt?pid=4321&minutes=30", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("exempting a process not running returned %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/exempt?pid=1234&minutes=30", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST returned %d: %s", rec.Code, rec.Body.String())
+	}
+	expected := []processExemption{{Pid: 1234, Path: "C:\\GTA.exe", Until: ctx.currentTime.Add(time.Duration(30) * time.Minute)}}
+	var exemptions []processExemption
+	if err := json.Unmarshal(rec.Body.Bytes(), &exemptions); err != nil {
+		t.Fatal(err)
+	}
+	if len(exemptions) != 1 || exemptions[0].Pid != expected[0].Pid || exemptions[0].Path != expected[0].Path || !exemptions[0].Until.Equal(expected[0].Until) {
+		t.Errorf("exemptions are %+v (expected %+v)", exemptions, expected)
+	}
+}
+
+func TestHTTPDashboardPollsStatus(t *testing.T) {
+	ctx := NewTest(t).GivenADadControllerWithSamplingInterval(time.Duration(1) * time.Minute)
+	handler := ctx.controller.httpHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("GET / returned %d (%s)", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(rec.Body.String(), `fetch("status")`) {
+		t.Error("dashboard does not poll the status endpoint")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /unknown returned %d", rec.Code)
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	now := time.Now()
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(now.Year(), now.Month(), now.Day(), 20, 30, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryDayOnInterval("GTA", "GTA.exe", time.Duration(15)*time.Minute, 2000, 2100).
+		GivenAnActivityRuleAllowedEveryDayOnInterval("Minecraft", "Minecraft.exe", time.Duration(15)*time.Minute, 2000, 2100).
+		GivenAnActivityDuration("GTA", time.Duration(10)*time.Minute).
+		GivenAnActivityDuration("Minecraft", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\Minecraft.exe", 1).
+		WhenScanHappens()
+
+	rec := httptest.NewRecorder()
+	ctx.controller.httpHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var status statusReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []activityStatus{
+		{Activity: "GTA", Used: duration(10 * time.Minute), Allowed: duration(15 * time.Minute), Remaining: duration(5 * time.Minute)},
+		{Activity: "Minecraft", Used: duration(21 * time.Minute), Allowed: duration(15 * time.Minute), Blocked: true, Reason: "Activity duration above threshold for this day"},
+	}
+	if !reflect.DeepEqual(status.Activities, expected) {
+		t.Errorf("activities are %+v (expected %+v)", status.Activities, expected)
+	}
+	if len(status.Events) != 1 || status.Events[0].Message != "Minecraft killed : Activity duration above threshold for this day" {
+		t.Errorf("events are %+v", status.Events)
+	}
+}
+
+func TestHTTPChangesAreRefusedWithoutPIN(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenARunningProcess("C:\\GTA.exe", 1234)
+	handler := ctx.controller.httpHandler()
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/lockdown?minutes=60", nil),
+		httptest.NewRequest(http.MethodPost, "/sampling-interval?value=2m", nil),
+		httptest.NewRequest(http.MethodPost, "/credit?activity=GTA&minutes=30", nil),
+		httptest.NewRequest(http.MethodPost, "/override?activity=GTA&date=2024-12-24&maxDuration=3h&periods=10:00-12:00", nil),
+		httptest.NewRequest(http.MethodPost, "/exempt?pid=1234&minutes=30", nil),
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s %s without PIN returned %d", r.Method, r.URL, rec.Code)
+		}
+	}
+	if !ctx.controller.LockdownUntil.IsZero() || len(ctx.controller.Credits) != 0 || len(ctx.controller.Exemptions) != 0 {
+		t.Error("the controller was changed without PIN")
+	}
+	ctx.ThenSamplingIntervalShouldBe(time.Duration(1) * time.Minute)
+}
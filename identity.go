@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// executableIdentity is what identifies an executable whatever its name, as
+// of its last modification.
+type executableIdentity struct {
+	size      int64
+	modTime   time.Time
+	hash      string
+	publisher string
+	// publisherKnown is set once the publisher was looked up, hashes and
+	// signatures being only computed when a rule needs them
+	publisherKnown bool
+}
+
+// identifyExecutables sets the SHA-256 hash and the publisher of the
+// executables of the processes when a rule matches on them, computing them
+// once per version of each file.
+func (c *dadController) identifyExecutables(processes []runningProcess) {
+	needHash, needPublisher := false, false
+	for _, a := range c.allRules() {
+		needHash = needHash || len(a.Hashes) > 0
+		needPublisher = needPublisher || len(a.Publishers) > 0
+	}
+	if !needHash && !needPublisher {
+		return
+	}
+
+	if c.identities == nil {
+		c.identities = make(map[string]*executableIdentity)
+	}
+	seen := make(map[string]bool)
+	for i := range processes {
+		p := &processes[i]
+		if p.Path == "" || p.Distribution != "" {
+			continue
+		}
+		seen[p.Path] = true
+		id := c.executableIdentity(p.Path, needHash, needPublisher)
+		if id == nil {
+			continue
+		}
+		p.Hash, p.Publisher = id.hash, id.publisher
+	}
+	for path := range c.identities {
+		if !seen[path] {
+			delete(c.identities, path)
+		}
+	}
+}
+
+func (c *dadController) executableIdentity(path string, needHash, needPublisher bool) *executableIdentity {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	id, found := c.identities[path]
+	if !found || id.size != stat.Size() || !id.modTime.Equal(stat.ModTime()) {
+		id = &executableIdentity{size: stat.Size(), modTime: stat.ModTime()}
+		c.identities[path] = id
+	}
+	if needHash && id.hash == "" {
+		if id.hash, err = fileSHA256(path); err != nil {
+			fmt.Fprintf(logOutput, "Failure to hash %s : %s\n", path, err)
+		}
+	}
+	if needPublisher && !id.publisherKnown && c.Publisher != nil {
+		id.publisherKnown = true
+		if id.publisher, err = c.Publisher(path); err != nil {
+			fmt.Fprintf(logOutput, "Failure to read the signature of %s : %s\n", path, err)
+		}
+	}
+	return id
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// matchesIdentity tells whether the executable of rp has one of the hashes
+// or publishers of the rule.
+func (a *activityRule) matchesIdentity(rp runningProcess) bool {
+	if rp.Hash != "" {
+		for _, hash := range a.Hashes {
+			if strings.EqualFold(hash, rp.Hash) {
+				return true
+			}
+		}
+	}
+	if rp.Publisher != "" {
+		for _, publisher := range a.Publishers {
+			if strings.EqualFold(publisher, rp.Publisher) {
+				return true
+			}
+		}
+	}
+	return false
+}
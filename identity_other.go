@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+func authenticodePublisher(path string) (string, error) {
+	return "", errors.New("Authenticode signatures only exist on Windows")
+}
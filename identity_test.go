@@ -0,0 +1,57 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenamedExecutableIsMatchedByHash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	renamed := filepath.Join(dir, "winword.exe")
+	if err := ioutil.WriteFile(renamed, []byte("GTA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess(renamed, 1)
+	hash, err := fileSHA256(renamed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx.controller.Activities[0].Hashes = []string{strings.ToUpper(hash)}
+
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, renamed, "Activity duration above threshold for this day")
+}
+
+func TestExecutableIsMatchedByPublisher(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Rockstar", "^$", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("Rockstar", time.Duration(20)*time.Minute).
+		GivenARunningProcess(os.Args[0], 1)
+	ctx.controller.Activities[0].Publishers = []string{"Rockstar Games, Inc."}
+	lookups := 0
+	ctx.controller.Publisher = func(path string) (string, error) {
+		lookups++
+		return "Rockstar Games, Inc.", nil
+	}
+
+	ctx.WhenScanHappens().
+		WhenScanHappens().
+		ThenProcessIsKilled("Rockstar", 1, os.Args[0], "Activity duration above threshold for this day")
+	if lookups != 1 {
+		t.Errorf("the signature should be read once per executable, read %d times", lookups)
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// authenticodePublisher returns the common name of the certificate which
+// signed the executable at path, empty when it has no valid signature.
+func authenticodePublisher(path string) (string, error) {
+	script := fmt.Sprintf(`& { $s = Get-AuthenticodeSignature -LiteralPath '%s'; if ($s.Status -eq 'Valid') { $s.SignerCertificate.GetNameInfo('SimpleName', $false) } }`, strings.Replace(path, "'", "''", -1))
+	out, err := exec.Command("powershell", "-NoProfile", "-Command", script).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isIdle tells whether no keyboard nor mouse input was received for longer
+// than the idle timeout, in which case running activities are not counted.
+// The machine is deemed active when its idle time cannot be measured.
+func (c *dadController) isIdle() bool {
+	if c.IdleTimeout <= 0 || c.IdleTime == nil {
+		return false
+	}
+	idle, err := c.IdleTime()
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to measure idle time : ", err)
+		return false
+	}
+	if idle < time.Duration(c.IdleTimeout) {
+		return false
+	}
+	fmt.Fprintf(logOutput, "No input for %s, not counting running activities\n", idle.Truncate(time.Second))
+	return true
+}
+
+// parseHIDIdleTime parses the "HIDIdleTime" = <nanoseconds> line output by
+// ioreg on macOS.
+func parseHIDIdleTime(data []byte) (time.Duration, bool) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		i := strings.Index(line, `"HIDIdleTime" = `)
+		if i < 0 {
+			continue
+		}
+		ns, err := strconv.ParseInt(strings.TrimSpace(line[i+len(`"HIDIdleTime" = `):]), 10, 64)
+		if err != nil {
+			continue
+		}
+		return time.Duration(ns), true
+	}
+	return 0, false
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"time"
+)
+
+// systemIdleTime returns the HIDIdleTime of the HID system, the time since
+// the last keyboard or mouse input.
+func systemIdleTime() (time.Duration, error) {
+	out, err := exec.Command("ioreg", "-c", "IOHIDSystem", "-d", "4").Output()
+	if err != nil {
+		return 0, err
+	}
+	idle, found := parseHIDIdleTime(out)
+	if !found {
+		return 0, errors.New("no HIDIdleTime reported by ioreg")
+	}
+	return idle, nil
+}
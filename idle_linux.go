@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// inputEvents records the time of the last event read from the input
+// devices, which are only readable by root.
+var inputEvents struct {
+	once    sync.Once
+	mu      sync.Mutex
+	watched bool
+	last    time.Time
+}
+
+// watchInputDevices reads the events of every input device in the
+// background, remembering when the last one was received.
+func watchInputDevices() bool {
+	devices, _ := filepath.Glob("/dev/input/event*")
+	watched := false
+	for _, device := range devices {
+		f, err := os.Open(device)
+		if err != nil {
+			continue
+		}
+		watched = true
+		go func(f *os.File) {
+			defer f.Close()
+			buf := make([]byte, 24*64)
+			for {
+				if _, err := f.Read(buf); err != nil {
+					return
+				}
+				inputEvents.mu.Lock()
+				inputEvents.last = time.Now()
+				inputEvents.mu.Unlock()
+			}
+		}(f)
+	}
+	return watched
+}
+
+// systemIdleTime returns the time since the last input event when the input
+// devices are readable, since the controller started when none was received
+// yet. Otherwise it asks xprintidle for the idle time of the X session.
+func systemIdleTime() (time.Duration, error) {
+	inputEvents.once.Do(func() {
+		inputEvents.last = time.Now()
+		inputEvents.watched = watchInputDevices()
+	})
+	if inputEvents.watched {
+		inputEvents.mu.Lock()
+		defer inputEvents.mu.Unlock()
+		return time.Since(inputEvents.last), nil
+	}
+
+	if os.Getenv("DISPLAY") == "" {
+		return 0, errors.New("input devices are not readable and there is no X display")
+	}
+	out, err := exec.Command("xprintidle").Output()
+	if err != nil {
+		return 0, err
+	}
+	ms, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
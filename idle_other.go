@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+func systemIdleTime() (time.Duration, error) {
+	return 0, errors.New("idle time cannot be measured on this platform")
+}
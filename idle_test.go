@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestIdleMachineDoesNotCountRunningActivities(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 19, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Minecraft", "Minecraft.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("Minecraft", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\Minecraft.exe", 1)
+	ctx.controller.IdleTimeout = duration(time.Duration(10) * time.Minute)
+	idle := time.Duration(0)
+	ctx.controller.IdleTime = func() (time.Duration, error) { return idle, nil }
+
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("Minecraft", time.Duration(21)*time.Minute)
+	// left running during dinner, still enforced
+	idle = time.Duration(25) * time.Minute
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("Minecraft", time.Duration(21)*time.Minute).
+		ThenProcessIsKilled("Minecraft", 1, "C:\\Minecraft.exe", "Activity duration above threshold for this day")
+}
+
+func TestUnmeasurableIdleTimeCountsRunningActivities(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 19, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Minecraft", "Minecraft.exe", time.Duration(15)*time.Minute).
+		GivenARunningProcess("C:\\Minecraft.exe", 1)
+	ctx.controller.IdleTimeout = duration(time.Duration(10) * time.Minute)
+	ctx.controller.IdleTime = func() (time.Duration, error) { return 0, errors.New("no display") }
+
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("Minecraft", time.Duration(1)*time.Minute)
+}
+
+func TestParseHIDIdleTime(t *testing.T) {
+	data := []byte(`    | |   {
+    | |     "HIDIdleTime" = 61552837083
+    | |     "HIDEventServiceProperties" = {}
+`)
+	if idle, found := parseHIDIdleTime(data); !found || idle != time.Duration(61552837083) {
+		t.Errorf("parsed %s, %v (expected %s)", idle, found, time.Duration(61552837083))
+	}
+	if _, found := parseHIDIdleTime([]byte("no idle time")); found {
+		t.Error("idle time found in output without any")
+	}
+}
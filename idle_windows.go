@@ -0,0 +1,29 @@
+package main
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+var (
+	procGetLastInputInfo = user32.NewProc("GetLastInputInfo")
+	procGetTickCount     = syscall.NewLazyDLL("kernel32.dll").NewProc("GetTickCount")
+)
+
+type lastInputInfo struct {
+	Size uint32
+	Time uint32
+}
+
+// systemIdleTime returns the time since the last input of the session of
+// the controller, which never receives any when it runs as a service.
+func systemIdleTime() (time.Duration, error) {
+	info := lastInputInfo{Size: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	if r, _, err := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); r == 0 {
+		return 0, err
+	}
+	now, _, _ := procGetTickCount.Call()
+	// tick counts wrap around every 49.7 days
+	return time.Duration(uint32(now)-info.Time) * time.Millisecond, nil
+}
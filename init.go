@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const initSamplingInterval = time.Minute
+
+// starterConfig is the annotated configuration written by -init when no
+// activity is entered.
+// JSON has no comments, the "comment" keys are ignored when it is loaded.
+const starterConfig = `{
+    "comment": "Starter configuration of dad-controller, edit the rules below then check them with: dad-controller validate",
+    "version": 1,
+    "samplingInterval": "1m",
+    "rules": [
+        {
+            "comment": "Programs are regular expressions matched against the path of the processes. Schedules are keyed by day (mon, tue...), range (mon-fri), list (sat,sun), weekdays, weekend or default.",
+            "name": "Games",
+            "programs": ["Steam.steamapps.common.*.exe", "Minecraft"],
+            "schedules": {
+                "weekdays": {
+                    "comment": "1 hour a day, between 16:30 and 19:00, with a warning 5 minutes and 1 minute before being stopped",
+                    "maxDuration": "1h",
+                    "allowedPeriods": [
+                        {"begin": "16:30", "end": "19:00"}
+                    ],
+                    "warnBefore": ["5m", "1m"]
+                },
+                "weekend": {
+                    "comment": "3 hours a day, in the morning and in the afternoon",
+                    "maxDuration": "3h",
+                    "allowedPeriods": [
+                        {"begin": "09:00", "end": "12:00"},
+                        {"begin": "14:00", "end": "20:00"}
+                    ]
+                }
+            }
+        },
+        {
+            "comment": "The compact form of the schedules, days and times followed by the daily limit",
+            "name": "Videos",
+            "programs": ["vlc", "Netflix"],
+            "allow": "mon-fri 18:00-20:00 max 30m; sat,sun 10:00-21:00 max 2h"
+        }
+    ]
+}
+`
+
+// configPrompter asks the questions of the starter configuration generator,
+// asking again until the answer is valid.
+type configPrompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+func (p configPrompter) ask(question string, validate func(answer string) error) (string, error) {
+	for {
+		fmt.Fprintf(p.out, "%s ", question)
+		if !p.in.Scan() {
+			if err := p.in.Err(); err != nil {
+				return "", err
+			}
+			return "", io.ErrUnexpectedEOF
+		}
+		answer := strings.TrimSpace(p.in.Text())
+		err := validate(answer)
+		if err == nil {
+			return answer, nil
+		}
+		fmt.Fprintf(p.out, "Invalid answer : %s\n", err)
+	}
+}
+
+func validateActivityName(answer string) error {
+	if answer == "" {
+		return fmt.Errorf("the name cannot be empty")
+	}
+	return nil
+}
+
+func validatePrograms(answer string) error {
+	programs := splitPrograms(answer)
+	if len(programs) == 0 {
+		return fmt.Errorf("at least one program is expected")
+	}
+	for _, program := range programs {
+		if _, err := regexp.Compile(program); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func splitPrograms(answer string) []string {
+	var programs []string
+	for _, program := range strings.Split(answer, ",") {
+		if program = strings.TrimSpace(program); program != "" {
+			programs = append(programs, program)
+		}
+	}
+	return programs
+}
+
+func validateAllowedTimes(answer string) error {
+	_, err := parseCompactSchedule(withDailyLimit(answer, "0s"))
+	return err
+}
+
+// withDailyLimit turns allowed days and times into a compact schedule,
+// applying limit to each of its entries.
+func withDailyLimit(allowed string, limit string) string {
+	var entries []string
+	for _, entry := range strings.Split(allowed, ";") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry+" max "+limit)
+		}
+	}
+	return strings.Join(entries, "; ")
+}
+
+func validateDailyLimit(answer string) error {
+	d, err := time.ParseDuration(answer)
+	if err != nil {
+		return err
+	}
+	if d <= 0 {
+		return fmt.Errorf("the limit must be positive")
+	}
+	return nil
+}
+
+func validateYesNo(answer string) error {
+	switch strings.ToLower(answer) {
+	case "", "y", "yes", "n", "no":
+		return nil
+	}
+	return fmt.Errorf("expected yes or no")
+}
+
+// generateConfig interactively builds a starter configuration, asking for
+// the name, programs, allowed days and times and daily limit of each
+// activity. It returns nil when the name of the first activity is left
+// empty, for the annotated starter configuration.
+func generateConfig(in io.Reader, out io.Writer) (*config, error) {
+	p := configPrompter{in: bufio.NewScanner(in), out: out}
+	cfg := config{Version: configVersion, SamplingInterval: duration(initSamplingInterval)}
+
+	for {
+		question, validate := "Activity name (e.g. Games):", validateActivityName
+		if len(cfg.Activities) == 0 {
+			question, validate = "Activity name (e.g. Games), empty for an annotated example:", func(string) error { return nil }
+		}
+		name, err := p.ask(question, validate)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			return nil, nil
+		}
+		programs, err := p.ask("Programs, separated by commas (e.g. GTA5.exe, Steam.exe):", validatePrograms)
+		if err != nil {
+			return nil, err
+		}
+		allowed, err := p.ask("Allowed days and times (e.g. mon-fri 16:00-18:00; sat,sun 10:00-20:00):", validateAllowedTimes)
+		if err != nil {
+			return nil, err
+		}
+		limit, err := p.ask("Daily limit (e.g. 1h30m):", validateDailyLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		a := &activityRule{Name: name, ProcessPatterns: splitPrograms(programs), Allow: withDailyLimit(allowed, limit)}
+		if err := a.expandAllow(); err != nil {
+			return nil, err
+		}
+		a.Allow = ""
+		cfg.Activities = append(cfg.Activities, a)
+
+		another, err := p.ask("Add another activity? [y/N]", validateYesNo)
+		if err != nil {
+			return nil, err
+		}
+		if a := strings.ToLower(another); a != "y" && a != "yes" {
+			return &cfg, nil
+		}
+	}
+}
+
+// initConfig generates a starter configuration and writes it to path, the
+// annotated example one when no activity is entered, refusing to overwrite
+// an existing file.
+func initConfig(path string, in io.Reader, out io.Writer) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	cfg, err := generateConfig(in, out)
+	if err != nil {
+		return err
+	}
+	data := []byte(starterConfig)
+	if cfg != nil {
+		if data, err = json.MarshalIndent(cfg, "", "    "); err != nil {
+			return err
+		}
+		data = append(data, '\n')
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Configuration written to %s\n", path)
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInitGeneratesAValidConfiguration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	input := strings.Join([]string{
+		"Games",
+		"GTA5.exe, Steam.exe",
+		"mon-fri 16:00-18:00; sat,sun 10:00-20:00",
+		"1h30m",
+		"yes",
+		"",
+		"Minecraft",
+		"Minecraft.exe",
+		"sat 14:00-16:00",
+		"forever",
+		"45m",
+		"no",
+	}, "\n")
+	path := filepath.Join(dir, "dad-controller.json")
+	var out bytes.Buffer
+	if err := initConfig(path, strings.NewReader(input), &out); err != nil {
+		t.Fatalf("%s\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "Invalid answer : the name cannot be empty") {
+		t.Errorf("empty name should have been asked again:\n%s", out.String())
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Activities) != 2 {
+		t.Fatalf("expected 2 activities, got %d", len(cfg.Activities))
+	}
+	games := cfg.Activities[0]
+	if games.Name != "Games" || strings.Join(games.ProcessPatterns, ",") != "GTA5.exe,Steam.exe" {
+		t.Errorf("unexpected activity %+v", games)
+	}
+	monday := games.AllowedSchedules[time.Monday]
+	if monday == nil || time.Duration(monday.MaxDuration) != 90*time.Minute || len(monday.AllowedPeriods) != 1 || monday.AllowedPeriods[0] != (timePeriod{Begin: 1600, End: 1800}) {
+		t.Errorf("unexpected monday schedule %+v", monday)
+	}
+	if s := games.AllowedSchedules[time.Sunday]; s == nil || s.AllowedPeriods[0] != (timePeriod{Begin: 1000, End: 2000}) {
+		t.Errorf("unexpected sunday schedule %+v", s)
+	}
+	minecraft := cfg.Activities[1]
+	if minecraft.Name != "Minecraft" || len(minecraft.AllowedSchedules) != 1 || time.Duration(minecraft.AllowedSchedules[time.Saturday].MaxDuration) != 45*time.Minute {
+		t.Errorf("unexpected activity %+v", minecraft)
+	}
+}
+
+func TestInitDoesNotOverwriteAnExistingConfiguration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dad-controller.json")
+	if err := ioutil.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := initConfig(path, strings.NewReader(""), ioutil.Discard); err == nil {
+		t.Error("an existing configuration should not be overwritten")
+	}
+}
+
+func TestInitFailsOnIncompleteInput(t *testing.T) {
+	if _, err := generateConfig(strings.NewReader("Games\nGTA5.exe\n"), ioutil.Discard); err == nil {
+		t.Error("incomplete input should fail")
+	}
+}
+
+func TestStarterConfigurationIsValid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dad-controller.json")
+	if err := initConfig(path, strings.NewReader("\n"), ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Activities) != 2 {
+		t.Fatalf("expected 2 activities, got %d", len(cfg.Activities))
+	}
+	games, videos := cfg.Activities[0], cfg.Activities[1]
+	if s := games.AllowedSchedules[time.Wednesday]; s == nil || time.Duration(s.MaxDuration) != time.Hour || s.AllowedPeriods[0] != (timePeriod{Begin: 1630, End: 1900}) {
+		t.Errorf("unexpected wednesday schedule %+v", s)
+	}
+	if s := videos.AllowedSchedules[time.Sunday]; s == nil || time.Duration(s.MaxDuration) != 2*time.Hour {
+		t.Errorf("unexpected sunday schedule %+v", s)
+	}
+
+	if err := initConfig(path, strings.NewReader("\n"), ioutil.Discard); err == nil {
+		t.Error("an existing configuration should not be overwritten")
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// journalEntry is time credited by a scan to an activity of a user, appended
+// to the events of the state store until the state is dumped, so that it
+// survives a crash happening before that.
+type journalEntry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user,omitempty"`
+	Activity string    `json:"activity"`
+	Credited duration  `json:"credited"`
+}
+
+// journal appends entries to the events of the state store.
+func (c *dadController) journal(entries []journalEntry) {
+	store := c.stateStore()
+	if store == nil {
+		return
+	}
+	for _, e := range entries {
+		if err := store.AppendEvent(e); err != nil {
+			fmt.Fprintln(logOutput, "Failure to write to journal : ", err)
+			return
+		}
+	}
+}
+
+// replayJournal credits the events of the state store more recent than
+// since, the last control of the reloaded state, which were lost when the
+// controller stopped before dumping it, then dumps the state.
+func (c *dadController) replayJournal(since time.Time) {
+	store := c.stateStore()
+	if store == nil {
+		return
+	}
+	events, err := store.Events()
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to read journal : ", err)
+	}
+
+	replayed := 0
+	for _, e := range events {
+		if !e.Time.After(since) {
+			continue
+		}
+		ad := c.durationsOf(e.User)
+		day := dateKey(e.Time)
+		if ad[day] == nil {
+			ad[day] = make(map[string]duration)
+		}
+		ad[day][e.Activity] += e.Credited
+		c.WeeklyDuration.startAt(c.weekStart(e.Time))
+		c.MonthlyDuration.startAt(monthStart(e.Time))
+		c.WeeklyDuration.add(e.User, e.Activity, e.Credited)
+		c.MonthlyDuration.add(e.User, e.Activity, e.Credited)
+		if e.Time.After(c.LastControlTime) {
+			c.LastControlTime = e.Time
+		}
+		replayed++
+	}
+	if replayed > 0 {
+		fmt.Fprintf(logOutput, "Replayed %d entries of the journal\n", replayed)
+		c.dumpState()
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTimeCreditedSinceTheLastDumpIsReplayedFromTheJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+	ctx.WhenScanHappens()
+	ctx.controller.dumpState()
+	if _, err := os.Stat(ctx.controller.stateFile + ".journal"); !os.IsNotExist(err) {
+		t.Errorf("journal kept once the state is dumped: %v", err)
+	}
+
+	// crash before the state of the second scan is dumped
+	ctx.WhenScanHappens()
+	restarted := newDadController(time.Duration(1)*time.Minute, ctx.controller.GetTime)
+	restarted.stateFile = ctx.controller.stateFile
+	restarted.reloadStateIfExist()
+	if d := restarted.GetActivityDuration("GTA"); d != time.Duration(2)*time.Minute {
+		t.Errorf("GTA duration is %s after restart", d)
+	}
+	if !restarted.LastControlTime.Equal(ctx.currentTime) {
+		t.Errorf("last control at %s (expected %s)", restarted.LastControlTime, ctx.currentTime)
+	}
+
+	again := newDadController(time.Duration(1)*time.Minute, ctx.controller.GetTime)
+	again.stateFile = ctx.controller.stateFile
+	again.reloadStateIfExist()
+	if d := again.GetActivityDuration("GTA"); d != time.Duration(2)*time.Minute {
+		t.Errorf("GTA duration is %s after replaying the journal twice", d)
+	}
+}
+
+func TestJournalIsReplayedOverATruncatedStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+	ctx.WhenScanHappens()
+	if err := ioutil.WriteFile(ctx.controller.stateFile, []byte(`{"lastControlTime":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newDadController(time.Duration(1)*time.Minute, ctx.controller.GetTime)
+	restarted.stateFile = ctx.controller.stateFile
+	restarted.reloadStateIfExist()
+	if d := restarted.GetActivityDuration("GTA"); d != time.Duration(1)*time.Minute {
+		t.Errorf("GTA duration is %s after restart", d)
+	}
+}
@@ -0,0 +1,90 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	// hooks for tests
+	sendSignal    = syscall.Kill
+	processExists = func(pid int) bool { return syscall.Kill(pid, 0) == nil }
+
+	// sigtermGracePeriod is how long a process may take to exit after
+	// SIGTERM before being sent SIGKILL
+	sigtermGracePeriod = 3 * time.Second
+	// afterGracePeriod runs f once sigtermGracePeriod is over, the
+	// controller carrying on meanwhile
+	afterGracePeriod = func(f func()) { time.AfterFunc(sigtermGracePeriod, f) }
+)
+
+var killSignals = map[string]syscall.Signal{
+	"SIGTERM": syscall.SIGTERM,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGSTOP": syscall.SIGSTOP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGQUIT": syscall.SIGQUIT,
+}
+
+// parseKillSignal resolves a signal name such as "SIGKILL" or "kill",
+// defaulting to SIGTERM.
+func parseKillSignal(name string) (syscall.Signal, error) {
+	if name == "" {
+		return syscall.SIGTERM, nil
+	}
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig, found := killSignals[name]
+	if !found {
+		return syscall.SIGTERM, fmt.Errorf("unknown kill signal %q", name)
+	}
+	return sig, nil
+}
+
+// terminateProcess sends signal to p, SIGTERM when it is unknown. When p is sent SIGTERM and is still
+// running after sigtermGracePeriod, it is sent SIGKILL, without waiting for
+// it meanwhile.
+func terminateProcess(p runningProcess, signal string) error {
+	sig, err := parseKillSignal(signal)
+	if err != nil {
+		fmt.Fprintf(logOutput, "%s, using SIGTERM\n", err)
+	}
+	if err := sendSignal(p.Pid, sig); err != nil {
+		return err
+	}
+	if sig != syscall.SIGTERM {
+		return nil
+	}
+
+	afterGracePeriod(func() {
+		if !processExists(p.Pid) {
+			return
+		}
+		if err := sendSignal(p.Pid, syscall.SIGKILL); err != nil {
+			fmt.Fprintf(logOutput, "Failure to kill process %d : %s\n", p.Pid, err)
+		}
+	})
+	return nil
+}
+
+// closeProcess asks p to exit with SIGTERM.
+func closeProcess(p runningProcess) error {
+	return sendSignal(p.Pid, syscall.SIGTERM)
+}
+
+// suspendProcess stops p with SIGSTOP until it is sent SIGCONT.
+func suspendProcess(p runningProcess) error {
+	return sendSignal(p.Pid, syscall.SIGSTOP)
+}
+
+// resumeProcess continues p stopped by SIGSTOP.
+func resumeProcess(p runningProcess) error {
+	return sendSignal(p.Pid, syscall.SIGCONT)
+}
@@ -0,0 +1,119 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type fakeSignals struct {
+	sent          []string
+	ignoreSIGTERM bool
+	exited        map[int]bool
+}
+
+func (f *fakeSignals) install(t *testing.T) {
+	previousSend, previousExists, previousAfter := sendSignal, processExists, afterGracePeriod
+	f.exited = make(map[int]bool)
+	sendSignal = func(pid int, sig syscall.Signal) error {
+		f.sent = append(f.sent, fmt.Sprintf("%d|%s", pid, sig))
+		if sig == syscall.SIGKILL || (sig == syscall.SIGTERM && !f.ignoreSIGTERM) {
+			f.exited[pid] = true
+		}
+		return nil
+	}
+	processExists = func(pid int) bool { return !f.exited[pid] }
+	afterGracePeriod = func(f func()) { f() }
+	t.Cleanup(func() {
+		sendSignal, processExists, afterGracePeriod = previousSend, previousExists, previousAfter
+	})
+}
+
+func killWithRuleSignal(signal string) {
+	ctrl := newDadController(time.Duration(1)*time.Minute, time.Now)
+	ar := ctrl.getOrCreateActivityRule("GTA")
+	ar.KillSignal = signal
+	ctrl.kill("GTA", []runningProcess{{Pid: 1, Path: "/usr/bin/gta"}}, "test")
+}
+
+func TestConfiguredKillSignalIsUsed(t *testing.T) {
+	signals := &fakeSignals{}
+	signals.install(t)
+
+	killWithRuleSignal("SIGKILL")
+	killWithRuleSignal("stop")
+
+	expected := []string{"1|" + syscall.SIGKILL.String(), "1|" + syscall.SIGSTOP.String()}
+	if !reflect.DeepEqual(signals.sent, expected) {
+		t.Errorf("sent %q (expected %q)", signals.sent, expected)
+	}
+}
+
+func TestDefaultKillSignalIsSIGTERM(t *testing.T) {
+	signals := &fakeSignals{}
+	signals.install(t)
+
+	killWithRuleSignal("")
+
+	expected := []string{"1|" + syscall.SIGTERM.String()}
+	if !reflect.DeepEqual(signals.sent, expected) {
+		t.Errorf("sent %q (expected %q)", signals.sent, expected)
+	}
+}
+
+func TestSIGKILLIsSentWhenSIGTERMIsIgnored(t *testing.T) {
+	signals := &fakeSignals{ignoreSIGTERM: true}
+	signals.install(t)
+
+	killWithRuleSignal("SIGTERM")
+
+	expected := []string{"1|" + syscall.SIGTERM.String(), "1|" + syscall.SIGKILL.String()}
+	if !reflect.DeepEqual(signals.sent, expected) {
+		t.Errorf("sent %q (expected %q)", signals.sent, expected)
+	}
+}
+
+func TestUnknownKillSignalIsRejected(t *testing.T) {
+	if _, err := parseKillSignal("SIGNOPE"); err == nil {
+		t.Error("unknown signal accepted")
+	}
+}
+
+func TestCloseSendsSIGTERM(t *testing.T) {
+	signals := &fakeSignals{}
+	signals.install(t)
+
+	if err := closeProcess(runningProcess{Pid: 1, Path: "/usr/bin/gta"}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"1|" + syscall.SIGTERM.String()}; !reflect.DeepEqual(signals.sent, expected) {
+		t.Errorf("sent %q (expected %q)", signals.sent, expected)
+	}
+}
+
+func TestGracePeriodsOfKilledProcessesRunTogether(t *testing.T) {
+	signals := &fakeSignals{ignoreSIGTERM: true}
+	signals.install(t)
+	var pending []func()
+	afterGracePeriod = func(f func()) { pending = append(pending, f) }
+
+	ctrl := newDadController(time.Duration(1)*time.Minute, time.Now)
+	ctrl.getOrCreateActivityRule("GTA")
+	ctrl.kill("GTA", []runningProcess{{Pid: 1, Path: "/usr/bin/gta"}, {Pid: 2, Path: "/usr/bin/gta"}}, "test")
+
+	expected := []string{"1|" + syscall.SIGTERM.String(), "2|" + syscall.SIGTERM.String()}
+	if !reflect.DeepEqual(signals.sent, expected) {
+		t.Errorf("sent %q before the grace period (expected %q)", signals.sent, expected)
+	}
+	for _, f := range pending {
+		f()
+	}
+	expected = append(expected, "1|"+syscall.SIGKILL.String(), "2|"+syscall.SIGKILL.String())
+	if !reflect.DeepEqual(signals.sent, expected) {
+		t.Errorf("sent %q (expected %q)", signals.sent, expected)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+const (
+	processSuspendResume = 0x0800
+
+	wmClose = 0x0010
+)
+
+var (
+	procNtSuspendProcess = syscall.NewLazyDLL("ntdll.dll").NewProc("NtSuspendProcess")
+	procNtResumeProcess  = syscall.NewLazyDLL("ntdll.dll").NewProc("NtResumeProcess")
+)
+
+// terminateProcess stops p. Signals do not exist on Windows, signal is
+// ignored.
+func terminateProcess(p runningProcess, signal string) error {
+	cmd := exec.Command("powershell", "-Command", fmt.Sprintf("& { Stop-Process -Id %d -Force }", p.Pid))
+	return cmd.Run()
+}
+
+// closeProcess posts WM_CLOSE to the top-level windows of p, as when the
+// user closes them.
+func closeProcess(p runningProcess) error {
+	var closed int
+	err := enumWindows(func(hwnd syscall.Handle) {
+		if windowProcess(hwnd) != p.Pid {
+			return
+		}
+		if r, _, _ := procPostMessage.Call(uintptr(hwnd), wmClose, 0, 0); r != 0 {
+			closed++
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if closed == 0 {
+		return fmt.Errorf("process %d has no window to close", p.Pid)
+	}
+	return nil
+}
+
+// suspendProcess suspends every thread of p until it is resumed.
+func suspendProcess(p runningProcess) error {
+	h, err := syscall.OpenProcess(processSuspendResume, false, uint32(p.Pid))
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(h)
+
+	if status, _, _ := procNtSuspendProcess.Call(uintptr(h)); status != 0 {
+		return fmt.Errorf("NtSuspendProcess failed with status 0x%x", status)
+	}
+	return nil
+}
+
+// resumeProcess resumes every thread of p suspended by suspendProcess.
+func resumeProcess(p runningProcess) error {
+	h, err := syscall.OpenProcess(processSuspendResume, false, uint32(p.Pid))
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(h)
+
+	if status, _, _ := procNtResumeProcess.Call(uintptr(h)); status != 0 {
+		return fmt.Errorf("NtResumeProcess failed with status 0x%x", status)
+	}
+	return nil
+}
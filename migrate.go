@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// configVersion is the version of the configuration files this
+	// controller reads. Files without a version are version 1.
+	configVersion = 1
+	// stateVersion is the version of the state files this controller
+	// writes. Files without a version are version 0, written when the
+	// counters were keyed by weekday.
+	stateVersion = 1
+)
+
+// configMigrations upgrade a configuration file, decoded as is, from the
+// version following their index to the next one, configMigrations[0]
+// upgrading version 1 files to version 2.
+var configMigrations []func(raw map[string]interface{}) error
+
+// stateMigrations upgrade the state loaded from a state file from the
+// version of their index to the next one.
+var stateMigrations = []func(c *dadController){
+	(*dadController).migrateWeekdayCounters,
+}
+
+// migrateConfig upgrades the configuration file data to configVersion,
+// refusing files written for a newer controller.
+func migrateConfig(data []byte) ([]byte, error) {
+	var v struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		// reported with its location by the parsing of the configuration
+		return data, nil
+	}
+	version := v.Version
+	if version == 0 {
+		version = 1
+	}
+	if version > configVersion {
+		return data, fmt.Errorf("configuration version %d is not supported, this controller reads up to version %d", version, configVersion)
+	}
+	if version == configVersion {
+		return data, nil
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return data, err
+	}
+	for ; version < configVersion; version++ {
+		if err := configMigrations[version-1](raw); err != nil {
+			return data, fmt.Errorf("failure to upgrade the configuration from version %d : %s", version, err)
+		}
+	}
+	raw["version"] = configVersion
+	return json.Marshal(raw)
+}
+
+// migrateState upgrades the state loaded from a state file of the given
+// version to stateVersion.
+func (c *dadController) migrateState(version int) {
+	if version > stateVersion {
+		fmt.Fprintf(logOutput, "State file version %d is newer than version %d written by this controller, loading it as is\n", version, stateVersion)
+		return
+	}
+	for ; version < stateVersion; version++ {
+		stateMigrations[version](c)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStateFileIsWrittenWithItsVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	monday := time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)
+	ctrl := newDadController(time.Duration(1)*time.Minute, func() time.Time { return monday })
+	ctrl.stateFile = filepath.Join(dir, "dad-controller.state")
+	ctrl.dumpState()
+
+	content, err := ioutil.ReadFile(ctrl.stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := unsealState(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		StateVersion int `json:"stateVersion"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil || v.StateVersion != stateVersion {
+		t.Errorf("state file written with version %d: %v", v.StateVersion, err)
+	}
+}
+
+func TestStateOfTheCurrentVersionIsNotMigrated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	monday := time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)
+	// a counter keyed by "1" in a current state file is not a weekday
+	state := fmt.Sprintf(`{"stateVersion":%d,"lastControlTime":%q,"activityDuration":{"1":{"GTA":"10m0s"},"2024-10-14":{"GTA":"20m0s"}}}`, stateVersion, monday.Format(time.RFC3339Nano))
+	stateFile := filepath.Join(dir, "dad-controller.state")
+	if err := ioutil.WriteFile(stateFile, []byte(state), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := newDadController(time.Duration(1)*time.Minute, func() time.Time { return monday })
+	ctrl.stateFile = stateFile
+	ctrl.reloadStateIfExist()
+	if d := ctrl.GetActivityDuration("GTA"); d != time.Duration(20)*time.Minute {
+		t.Errorf("GTA duration is %s", d)
+	}
+	if len(ctrl.ActivityDuration) != 2 {
+		t.Errorf("counters migrated: %v", ctrl.ActivityDuration)
+	}
+}
+
+func TestConfigurationOfANewerVersionIsRefused(t *testing.T) {
+	_, err := parseConfig([]byte(`{"version": 2, "rules": [{"name": "GTA", "programs": ["gta"]}]}`))
+	if err == nil || !strings.Contains(err.Error(), "configuration version 2 is not supported") {
+		t.Errorf("unexpected error %v", err)
+	}
+	cfg, err := parseConfig([]byte(`{"version": 1, "rules": [{"name": "GTA", "programs": ["gta"]}]}`))
+	if err != nil || cfg.Version != configVersion {
+		t.Errorf("current configuration refused: %v", err)
+	}
+}
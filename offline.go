@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// resetAfterOfflineGap drops, at the first scan of now, the state left stale
+// when the controller did not run at all on the days between the previous
+// scan, done at previous, and now, e.g. when the computer was off during
+// the holidays. The counters of the days before are kept as history, they
+// do not count today.
+func (c *dadController) resetAfterOfflineGap(previous time.Time, now time.Time) {
+	if previous.IsZero() {
+		return
+	}
+	days := daysBetween(previous, now)
+	if days < 2 {
+		return
+	}
+	fmt.Fprintf(logOutput, "No scan since %s, %d days ago, resetting the sessions\n", previous.Format("2006-01-02 15:04"), days)
+	c.Sessions = nil
+}
+
+// daysBetween returns the number of changes of date from previous to now,
+// in the time zone of now.
+func daysBetween(previous time.Time, now time.Time) int {
+	previous = previous.In(now.Location())
+	from := time.Date(previous.Year(), previous.Month(), previous.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return int(to.Sub(from).Hours() / 24)
+}
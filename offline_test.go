@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionsAreResetAfterDaysWithoutScan(t *testing.T) {
+	var log bytes.Buffer
+	defer func(w io.Writer) { logOutput = w }(logOutput)
+	logOutput = &log
+
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAUserActivityDuration("S-1-5-21-1001", "GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens()
+
+	restart := time.Date(2024, time.October, 17, 20, 0, 0, 0, time.Local)
+	ctx.GivenTimeIs(restart.Add(-time.Minute)).
+		WhenScanHappens()
+	if s := ctx.controller.Sessions["|GTA"]; s == nil || !s.Start.Equal(restart) {
+		t.Errorf("session %+v should have started at %s", s, restart)
+	}
+	if d := ctx.controller.GetUserActivityDuration("S-1-5-21-1001", "GTA"); d != 0 {
+		t.Errorf("GTA duration of the user is %s today", d)
+	}
+	if !strings.Contains(log.String(), "No scan since 2024-10-14 20:01, 3 days ago") {
+		t.Errorf("gap not logged:\n%s", log.String())
+	}
+}
+
+func TestDaysBetweenCountsChangesOfDate(t *testing.T) {
+	evening := time.Date(2024, time.October, 14, 23, 59, 0, 0, time.Local)
+	for _, tc := range []struct {
+		now  time.Time
+		days int
+	}{
+		{evening.Add(time.Second), 0},
+		{evening.Add(time.Minute), 1},
+		{evening.Add(time.Duration(48) * time.Hour), 2},
+	} {
+		if days := daysBetween(evening, tc.now); days != tc.days {
+			t.Errorf("%d days between %s and %s (expected %d)", days, evening, tc.now, tc.days)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// scheduleOverride replaces the schedule of an activity on a single date,
+// after which it expires.
+type scheduleOverride struct {
+	Activity string   `json:"activity"`
+	Date     string   `json:"date"`
+	Schedule schedule `json:"schedule"`
+}
+
+// Override replaces the schedule of activity on the day of date by s.
+func (c *dadController) Override(activity string, date time.Time, s schedule) scheduleOverride {
+	o := scheduleOverride{Activity: activity, Date: date.Format("2006-01-02"), Schedule: s}
+	for i, existing := range c.Overrides {
+		if existing.Activity == o.Activity && existing.Date == o.Date {
+			c.Overrides = append(c.Overrides[:i], c.Overrides[i+1:]...)
+			break
+		}
+	}
+	c.Overrides = append(c.Overrides, o)
+	fmt.Fprintf(logOutput, "Schedule of %s overridden on %s\n", o.Activity, o.Date)
+	return o
+}
+
+// override returns the schedule replacing the one of the rule on the day of
+// date, the overrides made through the API taking precedence over the ones
+// of the configuration.
+func (c *dadController) override(a *activityRule, date time.Time) (*schedule, bool) {
+	day := date.Format("2006-01-02")
+	for i := range c.Overrides {
+		if c.Overrides[i].Activity == a.Name && c.Overrides[i].Date == day {
+			return &c.Overrides[i].Schedule, true
+		}
+	}
+	s, found := a.Overrides[day]
+	return s, found
+}
+
+// expireOverrides forgets the overrides of the days before now.
+func (c *dadController) expireOverrides(now time.Time) {
+	today := now.Format("2006-01-02")
+	var kept []scheduleOverride
+	for _, o := range c.Overrides {
+		if o.Date >= today {
+			kept = append(kept, o)
+		} else {
+			fmt.Fprintf(logOutput, "Override of %s on %s is over\n", o.Activity, o.Date)
+		}
+	}
+	c.Overrides = kept
+}
+
+// parsePeriods parses a comma separated list of HH:MM-HH:MM periods.
+func parsePeriods(value string) ([]timePeriod, error) {
+	var periods []timePeriod
+	for _, text := range strings.Split(value, ",") {
+		p, err := parseCompactPeriod(compactToken{pos: 1, text: strings.TrimSpace(text)})
+		if err != nil {
+			return nil, fmt.Errorf("invalid period %q", text)
+		}
+		periods = append(periods, p)
+	}
+	return periods, nil
+}
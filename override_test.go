@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOverrideReplacesTheScheduleOfItsDateOnly(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour)
+	christmasEve := time.Date(2024, time.December, 24, 10, 0, 0, 0, time.Local)
+	ctx.controller.Override("GTA", christmasEve, schedule{AllowedPeriods: []timePeriod{{Begin: 1400, End: 2200}}, MaxDuration: duration(time.Duration(3) * time.Hour)})
+
+	if r := ctx.controller.EffectiveScheduleFor("GTA", christmasEve); r.MaxDuration != duration(time.Duration(3)*time.Hour) || len(r.AllowedPeriods) != 1 || r.AllowedPeriods[0].Begin != 1400 {
+		t.Errorf("override should apply on its date: %+v", r)
+	}
+	if r := ctx.controller.EffectiveScheduleFor("GTA", christmasEve.AddDate(0, 0, 1)); r.MaxDuration != duration(time.Hour) {
+		t.Errorf("override should not apply the day after: %+v", r)
+	}
+
+	ctx.controller.expireOverrides(christmasEve.Add(time.Duration(14) * time.Hour))
+	if len(ctx.controller.Overrides) != 0 {
+		t.Errorf("override should have expired: %+v", ctx.controller.Overrides)
+	}
+}
+
+func TestOverrideOfTheConfiguration(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedOnlyOnSunday("GTA", "GTA.exe", time.Duration(15)*time.Minute)
+	ctx.controller.getOrCreateActivityRule("GTA").Overrides = map[string]*schedule{
+		"2024-12-24": {AllowedPeriods: []timePeriod{{Begin: 1400, End: 2200}}, MaxDuration: duration(time.Duration(3) * time.Hour)},
+	}
+
+	if r := ctx.controller.EffectiveScheduleFor("GTA", time.Date(2024, time.December, 24, 10, 0, 0, 0, time.Local)); !r.Allowed || r.MaxDuration != duration(time.Duration(3)*time.Hour) {
+		t.Errorf("override of the configuration should apply on a day GTA is not allowed: %+v", r)
+	}
+}
+
+func TestHTTPOverride(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour)
+	handler := ctx.pinnedHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/override?activity=GTA&date=2024-12-24&maxDuration=3h&periods=10:00-12:00,14:00-22:00", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST returned %d: %s", rec.Code, rec.Body.String())
+	}
+	r := ctx.controller.EffectiveScheduleFor("GTA", time.Date(2024, time.December, 24, 10, 0, 0, 0, time.Local))
+	if r.MaxDuration != duration(time.Duration(3)*time.Hour) || len(r.AllowedPeriods) != 2 {
+		t.Errorf("override resolved as %+v", r)
+	}
+
+	for _, invalid := range []string{
+		"/override?activity=Unknown&date=2024-12-24&maxDuration=3h&periods=14:00-22:00",
+		"/override?activity=GTA&date=24/12&maxDuration=3h&periods=14:00-22:00",
+		"/override?activity=GTA&date=2024-12-24&maxDuration=3h&periods=14:00",
+	} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, invalid, nil))
+		if rec.Code == http.StatusOK {
+			t.Errorf("POST %s should fail", invalid)
+		}
+	}
+}
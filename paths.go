@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+const (
+	configFileName = "dad-controller.json"
+	stateFileName  = "dad-controller.state"
+)
+
+// defaultConfigFile returns the configuration file used unless -config says
+// otherwise: the one of the working directory when there is one, as
+// installed before the flag existed, or else the one of the configuration
+// directory of the system.
+func defaultConfigFile() string {
+	if fileExists(configFileName) {
+		return configFileName
+	}
+	return filepath.Join(systemConfigDir(), configFileName)
+}
+
+// defaultStateFile returns the state file used unless -state says
+// otherwise, next to the configuration file of the working directory when
+// there is one, or else in the state directory of the system.
+func defaultStateFile() string {
+	if fileExists(configFileName) || fileExists(stateFileName) {
+		return stateFileName
+	}
+	return filepath.Join(systemStateDir(), stateFileName)
+}
+
+// resolvePaths returns the configuration and state files to use, the
+// default ones when configFile or stateFile are empty.
+func resolvePaths(configFile string, stateFile string) (string, string) {
+	if configFile == "" {
+		configFile = defaultConfigFile()
+	}
+	if stateFile == "" {
+		stateFile = defaultStateFile()
+	}
+	return configFile, stateFile
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
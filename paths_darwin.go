@@ -0,0 +1,11 @@
+package main
+
+const applicationSupport = "/Library/Application Support/dad-controller"
+
+func systemConfigDir() string {
+	return applicationSupport
+}
+
+func systemStateDir() string {
+	return applicationSupport
+}
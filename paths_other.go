@@ -0,0 +1,11 @@
+//go:build !windows && !darwin
+
+package main
+
+func systemConfigDir() string {
+	return "/etc/dad-controller"
+}
+
+func systemStateDir() string {
+	return "/var/lib/dad-controller"
+}
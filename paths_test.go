@@ -0,0 +1,40 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPathsDefaultToTheWorkingDirectoryOnlyWhenItHasAConfiguration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	configFile, stateFile := resolvePaths("", "")
+	if configFile != filepath.Join(systemConfigDir(), configFileName) || stateFile != filepath.Join(systemStateDir(), stateFileName) {
+		t.Errorf("paths are %s and %s without local configuration", configFile, stateFile)
+	}
+
+	if err := ioutil.WriteFile(configFileName, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if configFile, stateFile = resolvePaths("", ""); configFile != configFileName || stateFile != stateFileName {
+		t.Errorf("paths are %s and %s with a local configuration", configFile, stateFile)
+	}
+
+	if configFile, stateFile = resolvePaths("/opt/dad/rules.json", "/tmp/dad.state"); configFile != "/opt/dad/rules.json" || stateFile != "/tmp/dad.state" {
+		t.Errorf("paths are %s and %s when given", configFile, stateFile)
+	}
+}
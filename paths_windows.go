@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+func programData() string {
+	if dir := os.Getenv("ProgramData"); dir != "" {
+		return filepath.Join(dir, "dad-controller")
+	}
+	return `C:\ProgramData\dad-controller`
+}
+
+func systemConfigDir() string {
+	return programData()
+}
+
+func systemStateDir() string {
+	return programData()
+}
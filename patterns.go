@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+const (
+	// patternRegex patterns are regular expressions searched in the path
+	patternRegex = "regex"
+	// patternGlob patterns such as "gta*.exe" match the whole file name, or
+	// the whole path when they hold a path separator
+	patternGlob = "glob"
+	// patternExact patterns are file names, or paths when they hold a path
+	// separator
+	patternExact = "exact"
+)
+
+// defaultCaseSensitive tells whether patterns are case sensitive unless
+// their rule says otherwise, which they are not on Windows.
+var defaultCaseSensitive = runtime.GOOS != "windows"
+
+func (a *activityRule) caseSensitive() bool {
+	if a.CaseSensitive != nil {
+		return *a.CaseSensitive
+	}
+	return defaultCaseSensitive
+}
+
+// compilePattern compiles a program pattern of the rule according to its
+// pattern type.
+func (a *activityRule) compilePattern(pattern string) (*regexp.Regexp, error) {
+	var expr string
+	switch a.PatternType {
+	case "", patternRegex:
+		expr = pattern
+	case patternGlob:
+		expr = anchorFileName(pattern, globToRegexp(pattern))
+	case patternExact:
+		expr = anchorFileName(pattern, regexp.QuoteMeta(pattern))
+	default:
+		return nil, fmt.Errorf("unknown patternType %q, expected %s, %s or %s", a.PatternType, patternRegex, patternGlob, patternExact)
+	}
+	if !a.caseSensitive() {
+		expr = "(?i)" + expr
+	}
+	return regexp.Compile(expr)
+}
+
+// anchorFileName anchors expr to the whole path when pattern holds a path
+// separator, to the whole file name otherwise.
+func anchorFileName(pattern string, expr string) string {
+	if strings.ContainsAny(pattern, `/\`) {
+		return "^" + expr + "$"
+	}
+	return `(^|[/\\])` + expr + "$"
+}
+
+// globToRegexp translates the * and ? wildcards of a glob, which do not
+// match path separators.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(`[^/\\]*`)
+		case '?':
+			b.WriteString(`[^/\\]`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// compilePatterns compiles the program and window title patterns of the
+// rule once for all scans, skipping and reporting the invalid ones.
+func (a *activityRule) compilePatterns() error {
+	a.patterns, a.titlePatterns, a.patternErr = nil, nil, nil
+	for _, pattern := range a.ProcessPatterns {
+		regex, err := a.compilePattern(pattern)
+		if err != nil {
+			if a.patternErr == nil {
+				a.patternErr = fmt.Errorf("invalid pattern %q for activity [%s] : %s", pattern, a.Name, err)
+			}
+			continue
+		}
+		a.patterns = append(a.patterns, regex)
+	}
+	for _, pattern := range a.TitlePatterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			if a.patternErr == nil {
+				a.patternErr = fmt.Errorf("invalid title pattern %q for activity [%s] : %s", pattern, a.Name, err)
+			}
+			continue
+		}
+		a.titlePatterns = append(a.titlePatterns, regex)
+	}
+	return a.patternErr
+}
+
+// compiledPatterns returns the compiled program and window title patterns,
+// compiling them first for the rules not loaded from the configuration.
+func (a *activityRule) compiledPatterns() ([]*regexp.Regexp, []*regexp.Regexp) {
+	if a.patternErr == nil && (len(a.patterns) != len(a.ProcessPatterns) || len(a.titlePatterns) != len(a.TitlePatterns)) {
+		if err := a.compilePatterns(); err != nil {
+			fmt.Fprintln(logOutput, err)
+		}
+	}
+	return a.patterns, a.titlePatterns
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPatternTypes(t *testing.T) {
+	sensitive, insensitive := true, false
+	for _, tc := range []struct {
+		patternType   string
+		caseSensitive *bool
+		pattern       string
+		path          string
+		expected      bool
+	}{
+		{"", &sensitive, "GTA.exe", `C:\Games\GTA.exe`, true},
+		{"regex", &sensitive, "^C:.*Steam", `C:\Games\Steam\steam.exe`, true},
+		{"glob", &sensitive, "gta*.exe", `C:\Games\gta5.exe`, true},
+		{"glob", &sensitive, "gta*.exe", `C:\Games\gta5.exe.bak`, false},
+		{"glob", &sensitive, "gta*.exe", `C:\Games\mygta5.exe`, false},
+		{"glob", &sensitive, "gta?.exe", `/usr/games/gta5.exe`, true},
+		{"glob", &sensitive, `C:\Games\*.exe`, `C:\Games\gta5.exe`, true},
+		{"glob", &sensitive, `C:\Games\*.exe`, `C:\Games\Rockstar\gta5.exe`, false},
+		{"glob", &sensitive, "gta*.exe", `C:\Games\GTA5.exe`, false},
+		{"glob", &insensitive, "gta*.exe", `C:\Games\GTA5.exe`, true},
+		{"exact", &sensitive, "minecraft.exe", `C:\Games\minecraft.exe`, true},
+		{"exact", &sensitive, "minecraft.exe", `C:\Games\minecraft.exe.lnk`, false},
+		{"exact", &sensitive, "minecraft.exe", `C:\Games\minecraftXexe`, false},
+		{"exact", &insensitive, `c:\games\minecraft.exe`, `C:\Games\Minecraft.exe`, true},
+	} {
+		a := activityRule{Name: "test", PatternType: tc.patternType, CaseSensitive: tc.caseSensitive}
+		regex, err := a.compilePattern(tc.pattern)
+		if err != nil {
+			t.Errorf("%s pattern %q : %s", tc.patternType, tc.pattern, err)
+			continue
+		}
+		if matched := regex.MatchString(tc.path); matched != tc.expected {
+			t.Errorf("%s pattern %q matching %q is %v (expected %v)", tc.patternType, tc.pattern, tc.path, matched, tc.expected)
+		}
+	}
+}
+
+func TestPatternsAreCaseInsensitiveByDefaultOnWindows(t *testing.T) {
+	defer func(previous bool) { defaultCaseSensitive = previous }(defaultCaseSensitive)
+	defaultCaseSensitive = false
+
+	a := activityRule{Name: "GTA", ProcessPatterns: []string{"gta5.exe"}}
+	if matched := a.matchingProcesses([]runningProcess{{Pid: 1, Path: `C:\Games\GTA5.EXE`}}); len(matched) != 1 {
+		t.Errorf("matched %+v", matched)
+	}
+}
+
+func TestUnknownPatternTypeIsRejected(t *testing.T) {
+	if _, err := parseConfig([]byte(`{"rules": [{"name": "GTA", "programs": ["gta*"], "patternType": "wildcard"}]}`)); err == nil {
+		t.Error("configuration with an unknown pattern type should be invalid")
+	}
+}
+
+func TestInvalidPatternIsReportedWithItsRule(t *testing.T) {
+	_, err := parseConfig([]byte(`{"rules": [{"name": "GTA", "programs": ["gta(.exe"]}]}`))
+	if err == nil || !strings.Contains(err.Error(), "GTA") || !strings.Contains(err.Error(), "gta(.exe") {
+		t.Errorf("expected an error naming the rule and the pattern, got %v", err)
+	}
+}
+
+func TestConfigurationWithInvalidPatternsIsNotApplied(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "dad-controller.json")
+	if err := ioutil.WriteFile(configFile, []byte(`{"samplingInterval": "1h", "rules": [{"name": "GTA", "programs": ["gta"]}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := newDadControllerWithConfigFile(configFile)
+
+	if err := ioutil.WriteFile(configFile, []byte(`{"samplingInterval": "1h", "rules": [{"name": "Minecraft", "programs": ["minecraft["]}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(configFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.reloadConfIfNeeded()
+
+	if len(ctrl.Activities) != 1 || ctrl.Activities[0].Name != "GTA" {
+		t.Errorf("configuration with invalid patterns should not replace the current one, got %v", ctrl.Activities)
+	}
+}
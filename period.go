@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// jsonTimePeriod is how a timePeriod is written in the configuration, its
+// bounds being "HH:MM" strings or, as formerly, HHMM integers such as 2130.
+type jsonTimePeriod struct {
+	Begin       json.RawMessage `json:"begin"`
+	End         json.RawMessage `json:"end"`
+	MaxDuration duration        `json:"maxDuration,omitempty"`
+}
+
+func (p timePeriod) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Begin       string   `json:"begin"`
+		End         string   `json:"end"`
+		MaxDuration duration `json:"maxDuration,omitempty"`
+	}{formatClockTime(p.Begin), formatClockTime(p.End), p.MaxDuration})
+}
+
+func (p *timePeriod) UnmarshalJSON(b []byte) error {
+	var v jsonTimePeriod
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	begin, err := parseClockTime(v.Begin)
+	if err != nil {
+		return fmt.Errorf("invalid begin of period %s : %s", b, err)
+	}
+	end, err := parseClockTime(v.End)
+	if err != nil {
+		return fmt.Errorf("invalid end of period %s : %s", b, err)
+	}
+	if begin == 2400 {
+		return fmt.Errorf("invalid period %s : a period cannot begin at 24:00", b)
+	}
+	*p = timePeriod{Begin: begin, End: end, MaxDuration: v.MaxDuration}
+	return nil
+}
+
+// parseClockTime parses a time of the day, "20:00" or 2000, into HHMM, the
+// way times are parsed in compact schedules.
+func parseClockTime(raw json.RawMessage) (int, error) {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return 0, err
+	}
+
+	var text string
+	switch value := v.(type) {
+	case float64:
+		hhmm := int(value)
+		if float64(hhmm) != value {
+			return 0, fmt.Errorf("%v is not a HHMM time", value)
+		}
+		text = fmt.Sprintf("%d:%02d", hhmm/100, hhmm%100)
+	case string:
+		text = value
+	default:
+		return 0, fmt.Errorf("%s is not a time of the day", raw)
+	}
+
+	hhmm, err := parseCompactTime(text, 1)
+	if err != nil {
+		msg := err.Error()
+		if ce, ok := err.(*compactScheduleError); ok {
+			msg = ce.Msg
+		}
+		return 0, fmt.Errorf("%s is not a valid time of the day : %s", raw, msg)
+	}
+	return hhmm, nil
+}
+
+func formatClockTime(hhmm int) string {
+	return fmt.Sprintf("%02d:%02d", hhmm/100, hhmm%100)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimePeriodFormats(t *testing.T) {
+	for _, tc := range []struct {
+		json     string
+		expected timePeriod
+	}{
+		{`{"begin": "20:00", "end": "21:30"}`, timePeriod{Begin: 2000, End: 2130}},
+		{`{"begin": 2000, "end": 2130}`, timePeriod{Begin: 2000, End: 2130}},
+		{`{"begin": "8:15", "end": "24:00", "maxDuration": "1h"}`, timePeriod{Begin: 815, End: 2400, MaxDuration: duration(time.Hour)}},
+	} {
+		var p timePeriod
+		if err := json.Unmarshal([]byte(tc.json), &p); err != nil {
+			t.Errorf("%s: %s", tc.json, err)
+		} else if p != tc.expected {
+			t.Errorf("%s parsed as %+v (expected %+v)", tc.json, p, tc.expected)
+		}
+	}
+}
+
+func TestInvalidTimePeriodsAreRejected(t *testing.T) {
+	for _, invalid := range []string{
+		`{"begin": 2075, "end": 2130}`,
+		`{"begin": "20:00", "end": "25:00"}`,
+		`{"begin": "20:60", "end": "21:00"}`,
+		`{"begin": "20h", "end": "21:00"}`,
+		`{"begin": "24:00", "end": "01:00"}`,
+		`{"begin": true, "end": "21:00"}`,
+	} {
+		var p timePeriod
+		if err := json.Unmarshal([]byte(invalid), &p); err == nil {
+			t.Errorf("%s should be invalid, parsed as %+v", invalid, p)
+		}
+	}
+}
+
+func TestTimePeriodIsWrittenAsHHMM(t *testing.T) {
+	data, err := json.Marshal(timePeriod{Begin: 830, End: 2130})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"begin":"08:30","end":"21:30"}` {
+		t.Errorf("period written as %s", data)
+	}
+}
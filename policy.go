@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// actionNone lets the next policy of the chain decide
+	actionNone action = iota
+	// actionAllow lets the activity run, skipping the remaining policies
+	actionAllow
+	// actionKill kills the running processes of the activity
+	actionKill
+	// actionWarn lets the activity run despite a kill decision, counting a
+	// violation of its rule instead
+	actionWarn
+	// actionSuspend freezes the running processes of the activity instead
+	// of killing them, until the activity is allowed again
+	actionSuspend
+	// actionLock locks the session running the activity
+	actionLock
+	// actionLogoff ends the session running the activity
+	actionLogoff
+	// actionRemind warns the user of the activity that it is about to be
+	// stopped, letting it run
+	actionRemind
+)
+
+type (
+	action int
+
+	// decisionContext is everything a policy knows about an activity when
+	// deciding what to do with its running processes.
+	decisionContext struct {
+		Activity string
+		Rule     *activityRule
+		// User owns Processes, empty when unknown
+		User      string
+		Processes []runningProcess
+		// Schedule is the schedule of the day, nil when the activity has none
+		Schedule *schedule
+		// Used is the time spent on the activity today, Allowed the maximum
+		// duration it is allowed for
+		Used    time.Duration
+		Allowed time.Duration
+		// Pool is the budget shared with other activities, nil when the
+		// activity has none, PoolUsed the time spent on them all today
+		Pool     *budgetPool
+		PoolUsed time.Duration
+		// WeekUsed and MonthUsed are the time spent on the activity this
+		// week and this month
+		WeekUsed  time.Duration
+		MonthUsed time.Duration
+		// Session is the length of the session in progress on the activity,
+		// SessionEnd the end of the last one when none is
+		Session    time.Duration
+		SessionEnd time.Time
+		Now        time.Time
+	}
+
+	// Policy decides whether the running processes of an activity must be
+	// killed. Custom policies are consulted in order before the default ones
+	// and the first one returning an action other than actionNone wins.
+	Policy interface {
+		Decide(ctx decisionContext) (action, string)
+	}
+
+	// PolicyFunc adapts a function to the Policy interface.
+	PolicyFunc func(ctx decisionContext) (action, string)
+
+	// enforcementAction is an action decided by a scan for the running
+	// processes of an activity.
+	enforcementAction struct {
+		Activity  string           `json:"activity"`
+		User      string           `json:"user,omitempty"`
+		Processes []runningProcess `json:"processes"`
+		Action    action           `json:"action"`
+		Reason    string           `json:"reason"`
+		// threshold of the WarnBefore durations reminded of
+		threshold time.Duration
+	}
+)
+
+func (a action) String() string {
+	switch a {
+	case actionAllow:
+		return "allow"
+	case actionKill:
+		return "kill"
+	case actionWarn:
+		return "warn"
+	case actionSuspend:
+		return "suspend"
+	case actionLock:
+		return "lock"
+	case actionLogoff:
+		return "logoff"
+	case actionRemind:
+		return "remind"
+	default:
+		return "none"
+	}
+}
+
+func (a action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// parseAction returns the action a rule can take named name.
+func parseAction(name string) (action, bool) {
+	for _, a := range []action{actionWarn, actionKill, actionSuspend, actionLock, actionLogoff} {
+		if a.String() == name {
+			return a, true
+		}
+	}
+	return actionNone, false
+}
+
+func (f PolicyFunc) Decide(ctx decisionContext) (action, string) {
+	return f(ctx)
+}
+
+func (c *dadController) defaultPolicies() []Policy {
+	return []Policy{
+		PolicyFunc(c.lockdownPolicy),
+		PolicyFunc(denyPeriodPolicy),
+		PolicyFunc(allowedDayPolicy),
+		PolicyFunc(maxDurationPolicy),
+		PolicyFunc(weeklyDurationPolicy),
+		PolicyFunc(monthlyDurationPolicy),
+		PolicyFunc(maxSessionDurationPolicy),
+		PolicyFunc(cooldownPolicy),
+		PolicyFunc(poolPolicy),
+		PolicyFunc(spendableWindowPolicy),
+		PolicyFunc(allowedPeriodPolicy),
+	}
+}
+
+// decide runs the custom policies then the default ones, allowing the
+// activity when none of them takes a decision.
+func (c *dadController) decide(ctx decisionContext) (action, string) {
+	for _, policies := range [][]Policy{c.Policies, c.defaultPolicies()} {
+		for _, p := range policies {
+			if a, reason := p.Decide(ctx); a != actionNone {
+				return a, reason
+			}
+		}
+	}
+	return actionAllow, ""
+}
+
+func (c *dadController) lockdownPolicy(ctx decisionContext) (action, string) {
+	if ctx.Now.Before(c.LockdownUntil) {
+		return actionKill, "Lockdown in progress"
+	}
+	return actionNone, ""
+}
+
+func denyPeriodPolicy(ctx decisionContext) (action, string) {
+	if ctx.Schedule == nil {
+		return actionNone, ""
+	}
+	dayTime := ctx.Now.Hour()*100 + ctx.Now.Minute()
+	for _, dp := range ctx.Schedule.DenyPeriods {
+		if dayTime >= dp.Begin && dayTime < dp.End {
+			return actionKill, "Blocked during restricted hours"
+		}
+	}
+	return actionNone, ""
+}
+
+func allowedDayPolicy(ctx decisionContext) (action, string) {
+	if ctx.Schedule == nil {
+		return actionKill, "Activity not allowed to be done on this day"
+	}
+	return actionNone, ""
+}
+
+func maxDurationPolicy(ctx decisionContext) (action, string) {
+	if ctx.Used > ctx.Allowed {
+		return actionKill, "Activity duration above threshold for this day"
+	}
+	return actionNone, ""
+}
+
+func spendableWindowPolicy(ctx decisionContext) (action, string) {
+	if ctx.Schedule == nil || ctx.Schedule.SpendableWindow == nil {
+		return actionNone, ""
+	}
+	dayTime := ctx.Now.Hour()*100 + ctx.Now.Minute()
+	if !ctx.Schedule.SpendableWindow.contains(dayTime) {
+		return actionKill, "Activity not allowed to be done outside of its spendable window"
+	}
+	return actionNone, ""
+}
+
+func allowedPeriodPolicy(ctx decisionContext) (action, string) {
+	if ctx.Schedule == nil {
+		return actionNone, ""
+	}
+	if ctx.Schedule.SpendableWindow != nil && len(ctx.Schedule.AllowedPeriods) == 0 {
+		// time can be spent anytime within the spendable window
+		return actionNone, ""
+	}
+	dayTime := ctx.Now.Hour()*100 + ctx.Now.Minute()
+	for _, ap := range ctx.Schedule.AllowedPeriods {
+		if dayTime >= ap.Begin && dayTime < ap.End {
+			return actionNone, ""
+		}
+	}
+	return actionKill, "Activity not allowed to be done during this time range"
+}
+
+// enforcedAction returns the action taken when the activity of the rule is
+// not allowed.
+func (a *activityRule) enforcedAction() action {
+	if decision, ok := parseAction(a.Action); ok {
+		return decision
+	}
+	if a.WarnOnly {
+		return actionWarn
+	}
+	if a.Suspend {
+		return actionSuspend
+	}
+	return actionKill
+}
+
+// enforcedDecision returns the action enforced on the rule a when the
+// policies decided decision: the action of the rule when they decided to
+// kill, the one they decided when it is another enforcement, actionAllow
+// otherwise, the actions which cannot be decided by a policy being ignored.
+func enforcedDecision(a *activityRule, decision action) action {
+	switch decision {
+	case actionKill:
+		return a.enforcedAction()
+	case actionWarn, actionSuspend, actionLock, actionLogoff:
+		return decision
+	case actionNone, actionAllow:
+		return actionAllow
+	}
+	fmt.Fprintf(logOutput, "Ignoring the %s action decided for %s, policies cannot decide it\n", decision, a.Name)
+	return actionAllow
+}
+
+// decisionContext returns what the policies decide on for the rule a and
+// the processes of user at now, the processes aside, so that the status
+// reports what the enforcement decides.
+func (c *dadController) decisionContext(a *activityRule, user string, now time.Time) decisionContext {
+	resolved := c.effectiveSchedule(a, now)
+	ctx := decisionContext{Activity: a.Name, Rule: a, User: user, Now: now}
+	if sameDay(now, c.LastControlTime) {
+		ctx.Used = time.Duration(c.durationsOf(user)[dateKey(now)][a.Name])
+	}
+	ctx.Used += c.continuationUsage(resolved, user, a.Name, now)
+	if resolved.Allowed {
+		ctx.Schedule = &resolved.schedule
+		ctx.Allowed = resolved.maxDurationAt(now, c.PeriodOverlap) + c.banked(user, a.Name)
+	}
+	c.addPool(&ctx)
+	c.addPeriodUsage(&ctx)
+	c.addSession(&ctx)
+	return ctx
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// budgetPool is a maximum duration per day shared by the activities whose
+// rule references it, e.g. "video games" for GTA, Fortnite and Minecraft,
+// so that switching from one to another does not reset the clock.
+type budgetPool struct {
+	Name        string   `json:"name"`
+	MaxDuration duration `json:"maxDuration"`
+}
+
+func (c *dadController) pool(name string) *budgetPool {
+	for _, p := range c.Pools {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// poolUsed returns the time spent by user on the activities of the pool on
+// the given day.
+func (c *dadController) poolUsed(user string, pool *budgetPool, day string) time.Duration {
+	var used time.Duration
+	durations := c.durationsOf(user)[day]
+	for _, a := range c.rulesOf(user) {
+		if a.Pool == pool.Name {
+			used += time.Duration(durations[a.Name])
+		}
+	}
+	return used
+}
+
+// addPool sets the pool of the activity in ctx along with the time spent on
+// it today.
+func (c *dadController) addPool(ctx *decisionContext) {
+	if ctx.Rule == nil || ctx.Rule.Pool == "" {
+		return
+	}
+	ctx.Pool = c.pool(ctx.Rule.Pool)
+	if ctx.Pool != nil && sameDay(ctx.Now, c.LastControlTime) {
+		ctx.PoolUsed = c.poolUsed(ctx.User, ctx.Pool, dateKey(ctx.Now))
+	}
+}
+
+func poolPolicy(ctx decisionContext) (action, string) {
+	if ctx.Pool != nil && ctx.PoolUsed > time.Duration(ctx.Pool.MaxDuration) {
+		return actionKill, fmt.Sprintf("Time shared by %s above threshold for this day", ctx.Pool.Name)
+	}
+	return actionNone, ""
+}
+
+// checkPools reports the rules referencing a pool which does not exist.
+func (cfg *config) checkPools() error {
+	for _, a := range cfg.allRules() {
+		if a.Pool == "" {
+			continue
+		}
+		found := false
+		for _, p := range cfg.Pools {
+			found = found || p.Name == a.Pool
+		}
+		if !found {
+			return fmt.Errorf("unknown pool %q for activity [%s]", a.Pool, a.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPoolIsSharedByItsActivities(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAnActivityRuleAllowedEveryTime("Minecraft", "Minecraft.exe", time.Duration(1)*time.Hour).
+		GivenAnActivityDuration("GTA", time.Duration(25)*time.Minute).
+		GivenAnActivityDuration("Minecraft", time.Duration(5)*time.Minute).
+		GivenARunningProcess("C:\\Minecraft.exe", 1)
+	ctx.controller.Pools = []*budgetPool{{Name: "video games", MaxDuration: duration(time.Duration(30) * time.Minute)}}
+	ctx.controller.Activities[0].Pool = "video games"
+	ctx.controller.Activities[1].Pool = "video games"
+
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("Minecraft", time.Duration(6)*time.Minute).
+		ThenProcessIsKilled("Minecraft", 1, "C:\\Minecraft.exe", "Time shared by video games above threshold for this day")
+}
+
+func TestUnknownPoolIsRejected(t *testing.T) {
+	if _, err := parseConfig([]byte(`{"pools": [{"name": "video games", "maxDuration": "1h"}], "rules": [{"name": "GTA", "programs": ["GTA.exe"], "pool": "games"}]}`)); err == nil {
+		t.Error("configuration referencing an unknown pool should be invalid")
+	}
+}
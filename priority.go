@@ -0,0 +1,21 @@
+package main
+
+import "sort"
+
+// Modes of mapping of the processes to the rules: a process is counted in
+// every activity whose rule matches it, or only in the first one by
+// decreasing priority, then by order in the configuration.
+const (
+	ruleMatchingAll   = "all"
+	ruleMatchingFirst = "first"
+)
+
+// byPriority returns rules by decreasing priority, rules of equal priority
+// keeping their order in the configuration.
+func byPriority(rules []*activityRule) []*activityRule {
+	rules = append([]*activityRule(nil), rules...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		return rules[i].Priority > rules[j].Priority
+	})
+	return rules
+}
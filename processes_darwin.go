@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const nativeProcessProvider = "ps"
+
+// psProvider lists processes with ps and signals them.
+type psProvider struct{}
+
+func init() {
+	registerProcessProvider("ps", func() ProcessProvider { return psProvider{} })
+}
+
+// List lists processes with ps, which reports the full path of their
+// executable on macOS, owned by the uid of the process. Parent pids, start
+// times and command lines are listed separately as the latter cannot be told apart
+// from paths with spaces.
+func (psProvider) List() ([]runningProcess, error) {
+	fmt.Fprintln(logOutput, "Scanning running processes ...")
+	data, err := exec.Command("ps", "-axww", "-o", "pid=,uid=,user=,comm=").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	processes := parsePsOutput(data)
+	if data, err := exec.Command("ps", "-axww", "-o", "pid=,ppid=,time=,lstart=,args=").Output(); err == nil {
+		details := parsePsDetails(data)
+		for i := range processes {
+			d := details[processes[i].Pid]
+			processes[i].CommandLine = d.CommandLine
+			processes[i].StartTime = d.StartTime
+			processes[i].ParentPid = d.ParentPid
+			processes[i].CPUTime = d.CPUTime
+		}
+	}
+	fmt.Fprintf(logOutput, "Found %d running processes\n", len(processes))
+
+	return processes, nil
+}
+
+func (psProvider) Kill(p runningProcess, signal string) error {
+	return terminateProcess(p, signal)
+}
+
+func (psProvider) Close(p runningProcess) error {
+	return closeProcess(p)
+}
+
+func (psProvider) Suspend(p runningProcess) error {
+	return suspendProcess(p)
+}
+
+func (psProvider) Resume(p runningProcess) error {
+	return resumeProcess(p)
+}
+
+func (psProvider) LockSession(p runningProcess) error {
+	return lockSession(p)
+}
+
+func (psProvider) Logoff(p runningProcess) error {
+	return logoffSession(p)
+}
+
+// parsePsOutput parses lines of pid, uid, user name and executable path,
+// the path possibly holding spaces.
+func parsePsOutput(data []byte) []runningProcess {
+	var processes []runningProcess
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		uid := strings.SplitN(strings.TrimSpace(fields[1]), " ", 2)
+		if len(uid) != 2 {
+			continue
+		}
+		name := strings.SplitN(strings.TrimSpace(uid[1]), " ", 2)
+		if len(name) != 2 {
+			continue
+		}
+		path := strings.TrimSpace(name[1])
+		if !strings.HasPrefix(path, "/") {
+			// kernel and zombie processes have no executable path
+			continue
+		}
+		processes = append(processes, runningProcess{Pid: pid, Path: path, UserID: uid[0], User: name[0]})
+	}
+	return processes
+}
+
+// parsePsDetails parses lines of pid, parent pid, CPU time, start time and
+// command line, the start time being made of five fields such as
+// "Mon Oct 14 16:05:12 2024". The CPU and start times are left zero when
+// they cannot be parsed.
+func parsePsDetails(data []byte) map[int]runningProcess {
+	details := make(map[int]runningProcess)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 9 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		ppid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		p := runningProcess{Pid: pid, ParentPid: ppid, CommandLine: strings.Join(fields[8:], " ")}
+		if cpu, err := parsePsTime(fields[2]); err == nil {
+			p.CPUTime = cpu
+		}
+		if start, err := time.ParseInLocation("Mon Jan 2 15:04:05 2006", strings.Join(fields[3:8], " "), time.Local); err == nil {
+			p.StartTime = start
+		}
+		details[pid] = p
+	}
+	return details
+}
+
+// parsePsTime parses a CPU time of ps such as "1:02.53", "1:02:03.00" or
+// "2-01:02:03", the seconds being the last field.
+func parsePsTime(value string) (time.Duration, error) {
+	var days int
+	if parts := strings.SplitN(value, "-", 2); len(parts) == 2 {
+		d, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return 0, err
+		}
+		days, value = d, parts[1]
+	}
+
+	fields := strings.Split(value, ":")
+	seconds, err := strconv.ParseFloat(fields[len(fields)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	total := time.Duration(days)*24*time.Hour + time.Duration(seconds*float64(time.Second))
+	unit := time.Minute
+	for i := len(fields) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return 0, err
+		}
+		total += time.Duration(n) * unit
+		unit *= 60
+	}
+	return total, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePsOutput(t *testing.T) {
+	data := []byte(`    1     0 root     /sbin/launchd
+  412   501 tom      /Applications/Minecraft.app/Contents/MacOS/launcher
+ 1337   501 tom      /Applications/Google Chrome.app/Contents/MacOS/Google Chrome
+ 2001   501 tom      (zombie)
+`)
+	expected := []runningProcess{
+		{Pid: 1, Path: "/sbin/launchd", UserID: "0", User: "root"},
+		{Pid: 412, Path: "/Applications/Minecraft.app/Contents/MacOS/launcher", UserID: "501", User: "tom"},
+		{Pid: 1337, Path: "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome", UserID: "501", User: "tom"},
+	}
+	if processes := parsePsOutput(data); !reflect.DeepEqual(processes, expected) {
+		t.Errorf("parsed %+v (expected %+v)", processes, expected)
+	}
+}
+
+func TestParsePsDetails(t *testing.T) {
+	data := []byte(`    1     0  12:03.50 Mon Oct 14 09:05:12 2024     /sbin/launchd
+  412   408   0:01.25 Tue Oct  1 16:00:00 2024     /usr/bin/java -cp minecraft.jar  net.minecraft.client.Main
+`)
+	expected := map[int]runningProcess{
+		1:   {Pid: 1, CPUTime: time.Duration(723500) * time.Millisecond, CommandLine: "/sbin/launchd", StartTime: time.Date(2024, time.October, 14, 9, 5, 12, 0, time.Local)},
+		412: {Pid: 412, ParentPid: 408, CPUTime: time.Duration(1250) * time.Millisecond, CommandLine: "/usr/bin/java -cp minecraft.jar net.minecraft.client.Main", StartTime: time.Date(2024, time.October, 1, 16, 0, 0, 0, time.Local)},
+	}
+	if details := parsePsDetails(data); !reflect.DeepEqual(details, expected) {
+		t.Errorf("parsed %+v (expected %+v)", details, expected)
+	}
+}
+
+func TestParsePsTime(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"0:00.00":    0,
+		"1:02.50":    time.Minute + time.Duration(2500)*time.Millisecond,
+		"1:02:03.00": time.Hour + 2*time.Minute + 3*time.Second,
+		"2-01:02:03": 49*time.Hour + 2*time.Minute + 3*time.Second,
+		"123:45.00":  123*time.Minute + 45*time.Second,
+	} {
+		if d, err := parsePsTime(value); err != nil || d != expected {
+			t.Errorf("parsed %q into %s, %v (expected %s)", value, d, err, expected)
+		}
+	}
+}
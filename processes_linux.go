@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	nativeProcessProvider = "proc"
+
+	// clockTicks is the USER_HZ unit of the start times of /proc/<pid>/stat,
+	// 100 on every Linux architecture
+	clockTicks = 100
+)
+
+// procRoot is where the proc filesystem is mounted, a hook for tests.
+var procRoot = "/proc"
+
+// procProvider lists processes from /proc and signals them.
+type procProvider struct{}
+
+func init() {
+	registerProcessProvider("proc", func() ProcessProvider { return procProvider{} })
+}
+
+// List lists the processes whose executable is readable in /proc, owned by
+// the uid of the process. Kernel threads and, unless running as root,
+// processes of other users have no readable executable and are skipped.
+func (procProvider) List() ([]runningProcess, error) {
+	fmt.Fprintln(logOutput, "Scanning running processes ...")
+	entries, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	bootTime, err := readBootTime()
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to read boot time, start times are unknown : ", err)
+	}
+
+	var processes []runningProcess
+	users := make(map[string]string)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		path, err := os.Readlink(filepath.Join(procRoot, entry.Name(), "exe"))
+		if err != nil {
+			continue
+		}
+
+		p := runningProcess{Pid: pid, Path: strings.TrimSuffix(path, " (deleted)")}
+		if cmdline, err := ioutil.ReadFile(filepath.Join(procRoot, entry.Name(), "cmdline")); err == nil {
+			p.CommandLine = parseCmdline(cmdline)
+		}
+		if stat, err := ioutil.ReadFile(filepath.Join(procRoot, entry.Name(), "stat")); err == nil {
+			if s, err := parseStat(stat); err == nil {
+				p.ParentPid = s.ppid
+				p.CPUTime = time.Duration(s.cpuTicks) * time.Second / clockTicks
+				if !bootTime.IsZero() {
+					p.StartTime = bootTime.Add(time.Duration(s.startTicks) * time.Second / clockTicks)
+				}
+			}
+		}
+		if stat, ok := entry.Sys().(*syscall.Stat_t); ok {
+			p.UserID = strconv.FormatUint(uint64(stat.Uid), 10)
+			p.User = lookupUser(users, p.UserID)
+		}
+		processes = append(processes, p)
+	}
+
+	fmt.Fprintf(logOutput, "Found %d running processes\n", len(processes))
+
+	return processes, nil
+}
+
+// lookupUser returns the name of the user uid, remembering it in names.
+func lookupUser(names map[string]string, uid string) string {
+	name, found := names[uid]
+	if !found {
+		if u, err := user.LookupId(uid); err == nil {
+			name = u.Username
+		}
+		names[uid] = name
+	}
+	return name
+}
+
+func (procProvider) Kill(p runningProcess, signal string) error {
+	return terminateProcess(p, signal)
+}
+
+func (procProvider) Close(p runningProcess) error {
+	return closeProcess(p)
+}
+
+func (procProvider) Suspend(p runningProcess) error {
+	return suspendProcess(p)
+}
+
+func (procProvider) Resume(p runningProcess) error {
+	return resumeProcess(p)
+}
+
+func (procProvider) LockSession(p runningProcess) error {
+	return lockSession(p)
+}
+
+func (procProvider) Logoff(p runningProcess) error {
+	return logoffSession(p)
+}
+
+// parseCmdline joins with spaces the NUL separated arguments of
+// /proc/<pid>/cmdline.
+func parseCmdline(data []byte) string {
+	args := strings.Split(strings.TrimRight(string(data), "\x00"), "\x00")
+	return strings.TrimSpace(strings.Join(args, " "))
+}
+
+// readBootTime reads the boot time from the btime line of /proc/stat.
+func readBootTime() (time.Time, error) {
+	data, err := ioutil.ReadFile(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "btime" {
+			seconds, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return time.Unix(seconds, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no btime in %s", filepath.Join(procRoot, "stat"))
+}
+
+// procStat holds the fields of /proc/<pid>/stat used by the controller, in
+// clock ticks.
+type procStat struct {
+	ppid int
+	// cpuTicks is the user and system time of the process
+	cpuTicks uint64
+	// startTicks is the start time of the process since boot
+	startTicks uint64
+}
+
+// parseStat parses /proc/<pid>/stat. Fields are counted after the command
+// name, which is between parentheses and may hold spaces.
+func parseStat(data []byte) (procStat, error) {
+	stat := string(data)
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return procStat{}, fmt.Errorf("invalid stat %q", stat)
+	}
+	// ppid is the 4th field, utime and stime the 14th and 15th, starttime
+	// the 22nd, which are the 2nd, 12th, 13th and 20th after the command name
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 20 {
+		return procStat{}, fmt.Errorf("invalid stat %q", stat)
+	}
+	var s procStat
+	var err error
+	if s.ppid, err = strconv.Atoi(fields[1]); err != nil {
+		return procStat{}, err
+	}
+	for _, field := range fields[11:13] {
+		ticks, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return procStat{}, err
+		}
+		s.cpuTicks += ticks
+	}
+	if s.startTicks, err = strconv.ParseUint(fields[19], 10, 64); err != nil {
+		return procStat{}, err
+	}
+	return s, nil
+}
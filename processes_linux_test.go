@@ -0,0 +1,96 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGetRunningProcessesReadsProc(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(root string) { procRoot = root }(procRoot)
+	procRoot = dir
+
+	for pid, exe := range map[string]string{
+		"12":   "/usr/games/minetest",
+		"345":  "/opt/steam/steam (deleted)",
+		"7":    "",
+		"self": "/usr/bin/bash",
+	} {
+		if err := os.Mkdir(filepath.Join(dir, pid), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if exe != "" {
+			if err := os.Symlink(exe, filepath.Join(dir, pid, "exe")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if pid == "12" {
+			if err := ioutil.WriteFile(filepath.Join(dir, pid, "cmdline"), []byte("minetest\x00--go\x00"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			stat := "12 (mine test) S 1 12 12 0 -1 4194560 1 0 0 0 300 50 0 0 20 0 1 0 1250 0 0"
+			if err := ioutil.WriteFile(filepath.Join(dir, pid, "stat"), []byte(stat), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "uptime"), []byte("1.0 1.0"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "stat"), []byte("cpu  1 2 3 4\nbtime 1700000000\nprocesses 42\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	uid := strconv.Itoa(os.Getuid())
+	var name string
+	if u, err := user.LookupId(uid); err == nil {
+		name = u.Username
+	}
+	expected := []runningProcess{
+		{Pid: 12, Path: "/usr/games/minetest", UserID: uid, User: name, CommandLine: "minetest --go", StartTime: time.Unix(1700000012, 500000000), ParentPid: 1, CPUTime: time.Duration(3500) * time.Millisecond},
+		{Pid: 345, Path: "/opt/steam/steam", UserID: uid, User: name},
+	}
+	processes, err := procProvider{}.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(processes, expected) {
+		t.Errorf("found %+v (expected %+v)", processes, expected)
+	}
+}
+
+func TestGetRunningProcessesFindsItself(t *testing.T) {
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	processes, err := procProvider{}.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, found := findProcess(processes, os.Getpid()); !found {
+		t.Errorf("%s (pid %d) not found", self, os.Getpid())
+	}
+}
+
+func TestParseCmdline(t *testing.T) {
+	for data, expected := range map[string]string{
+		"java\x00-jar\x00minecraft.jar\x00": "java -jar minecraft.jar",
+		"/usr/bin/bash":                     "/usr/bin/bash",
+		"":                                  "",
+	} {
+		if cmdline := parseCmdline([]byte(data)); cmdline != expected {
+			t.Errorf("parsed %q into %q (expected %q)", data, cmdline, expected)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+//go:build !linux && !darwin && !windows
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+)
+
+// listProcessesScript lists processes with their owner SID, start and CPU
+// times, in nanoseconds for the latter, parent and command line, the last two
+// only known to PowerShell 7. Owners are only
+// visible to elevated sessions, processes are listed without them otherwise.
+const listProcessesScript = `& {
+	try { $processes = Get-Process -IncludeUserName -ErrorAction Stop } catch { $processes = Get-Process }
+	$sids = @{}
+	$processes | ?{$_.Path -ne $null} | %{
+		$sid = $null
+		if ($_.UserName) {
+			if (-not $sids.ContainsKey($_.UserName)) {
+				try {
+					$sids[$_.UserName] = (New-Object System.Security.Principal.NTAccount($_.UserName)).Translate([System.Security.Principal.SecurityIdentifier]).Value
+				} catch {
+					$sids[$_.UserName] = $null
+				}
+			}
+			$sid = $sids[$_.UserName]
+		}
+		$start = $null
+		try { $start = $_.StartTime.ToUniversalTime().ToString("o") } catch {}
+		[pscustomobject]@{Id = $_.Id; Path = $_.Path; UserID = $sid; UserName = $_.UserName; CommandLine = $_.CommandLine; StartTime = $start; ParentId = $_.Parent.Id; CPUTime = $_.TotalProcessorTime.Ticks * 100}
+	} | convertto-json
+}`
+
+const nativeProcessProvider = "powershell"
+
+// powershellProvider lists processes with PowerShell, wherever it is
+// installed, and signals them.
+type powershellProvider struct{}
+
+func init() {
+	registerProcessProvider("powershell", func() ProcessProvider { return powershellProvider{} })
+}
+
+func (powershellProvider) List() ([]runningProcess, error) {
+	fmt.Fprintln(logOutput, "Scanning running processes ...")
+	cmd := exec.Command("powershell", "-Command", listProcessesScript)
+
+	cmdOut, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(cmdOut)
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []runningProcess
+	if err := json.Unmarshal(data, &processes); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(logOutput, "Found %d running processes\n", len(processes))
+
+	return processes, nil
+}
+
+func (powershellProvider) Kill(p runningProcess, signal string) error {
+	return terminateProcess(p, signal)
+}
+
+func (powershellProvider) Close(p runningProcess) error {
+	return closeProcess(p)
+}
+
+func (powershellProvider) Suspend(p runningProcess) error {
+	return suspendProcess(p)
+}
+
+func (powershellProvider) Resume(p runningProcess) error {
+	return resumeProcess(p)
+}
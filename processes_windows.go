@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	nativeProcessProvider = "toolhelp"
+
+	processQueryLimitedInformation = 0x1000
+
+	processCommandLineInformation = 60
+	statusInfoLengthMismatch      = 0xC0000004
+)
+
+var (
+	procQueryFullProcessImageName = syscall.NewLazyDLL("kernel32.dll").NewProc("QueryFullProcessImageNameW")
+	procNtQueryInformationProcess = syscall.NewLazyDLL("ntdll.dll").NewProc("NtQueryInformationProcess")
+	procGetPackageFullName        = syscall.NewLazyDLL("kernel32.dll").NewProc("GetPackageFullName")
+)
+
+// unicodeString is the UNICODE_STRING returned by NtQueryInformationProcess,
+// Length counting bytes.
+type unicodeString struct {
+	Length        uint16
+	MaximumLength uint16
+	Buffer        *uint16
+}
+
+// toolhelpProvider lists processes from toolhelp snapshots.
+type toolhelpProvider struct{}
+
+func init() {
+	registerProcessProvider("toolhelp", func() ProcessProvider { return toolhelpProvider{} })
+}
+
+// List lists processes from a toolhelp snapshot, with their executable path,
+// parent pid, owner SID, Store package, command line, start and CPU times.
+// Owners of processes of other users are only visible to elevated sessions,
+// they are listed without them otherwise. Processes whose executable path
+// cannot be queried are skipped.
+func (toolhelpProvider) List() ([]runningProcess, error) {
+	fmt.Fprintln(logOutput, "Scanning running processes ...")
+	snapshot, err := syscall.CreateToolhelp32Snapshot(syscall.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CloseHandle(snapshot)
+
+	var processes []runningProcess
+	var entry syscall.ProcessEntry32
+	entry.Size = uint32(unsafe.Sizeof(entry))
+	for err = syscall.Process32First(snapshot, &entry); err == nil; err = syscall.Process32Next(snapshot, &entry) {
+		if p, ok := queryProcess(entry.ProcessID); ok {
+			p.ParentPid = int(entry.ParentProcessID)
+			processes = append(processes, p)
+		}
+	}
+
+	fmt.Fprintf(logOutput, "Found %d running processes\n", len(processes))
+
+	return processes, nil
+}
+
+func (toolhelpProvider) Kill(p runningProcess, signal string) error {
+	return terminateProcess(p, signal)
+}
+
+func (toolhelpProvider) Close(p runningProcess) error {
+	return closeProcess(p)
+}
+
+func (toolhelpProvider) Suspend(p runningProcess) error {
+	return suspendProcess(p)
+}
+
+func (toolhelpProvider) Resume(p runningProcess) error {
+	return resumeProcess(p)
+}
+
+func (toolhelpProvider) LockSession(p runningProcess) error {
+	return lockSession(p)
+}
+
+func (toolhelpProvider) Logoff(p runningProcess) error {
+	return logoffSession(p)
+}
+
+func queryProcess(pid uint32) (runningProcess, bool) {
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, pid)
+	if err != nil {
+		return runningProcess{}, false
+	}
+	defer syscall.CloseHandle(h)
+
+	buf := make([]uint16, syscall.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	if r, _, _ := procQueryFullProcessImageName.Call(uintptr(h), 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size))); r == 0 {
+		return runningProcess{}, false
+	}
+
+	p := runningProcess{Pid: int(pid), Path: syscall.UTF16ToString(buf[:size])}
+	p.UserID, p.User = processOwner(h)
+	p.CommandLine = processCommandLine(h)
+	p.Package = processPackage(h)
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(h, &creation, &exit, &kernel, &user); err == nil {
+		p.StartTime = time.Unix(0, creation.Nanoseconds())
+		// FILETIME durations count 100ns intervals
+		p.CPUTime = time.Duration((int64(kernel.HighDateTime)<<32|int64(kernel.LowDateTime))+(int64(user.HighDateTime)<<32|int64(user.LowDateTime))) * 100
+	}
+	return p, true
+}
+
+// processPackage returns the full name of the package of a Store app, empty
+// for the other processes and before Windows 8.
+func processPackage(h syscall.Handle) string {
+	if procGetPackageFullName.Find() != nil {
+		return ""
+	}
+	var length uint32
+	if r, _, _ := procGetPackageFullName.Call(uintptr(h), uintptr(unsafe.Pointer(&length)), 0); r != uintptr(syscall.ERROR_INSUFFICIENT_BUFFER) || length == 0 {
+		// APPMODEL_ERROR_NO_PACKAGE for processes which are not Store apps
+		return ""
+	}
+	buf := make([]uint16, length)
+	if r, _, _ := procGetPackageFullName.Call(uintptr(h), uintptr(unsafe.Pointer(&length)), uintptr(unsafe.Pointer(&buf[0]))); r != 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf)
+}
+
+// processCommandLine returns the command line of the process, empty when it
+// cannot be queried (before Windows 8.1).
+func processCommandLine(h syscall.Handle) string {
+	var size uint32
+	status, _, _ := procNtQueryInformationProcess.Call(uintptr(h), processCommandLineInformation, 0, 0, uintptr(unsafe.Pointer(&size)))
+	if status != statusInfoLengthMismatch || size < uint32(unsafe.Sizeof(unicodeString{})) {
+		return ""
+	}
+
+	buf := make([]byte, size)
+	status, _, _ = procNtQueryInformationProcess.Call(uintptr(h), processCommandLineInformation, uintptr(unsafe.Pointer(&buf[0])), uintptr(size), uintptr(unsafe.Pointer(&size)))
+	if status != 0 {
+		return ""
+	}
+	s := (*unicodeString)(unsafe.Pointer(&buf[0]))
+	if s.Buffer == nil || s.Length == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(unsafe.Slice(s.Buffer, s.Length/2))
+}
+
+// processOwner returns the SID and the DOMAIN\name account of the owner of
+// the process, empty when they cannot be queried.
+func processOwner(h syscall.Handle) (string, string) {
+	var token syscall.Token
+	if err := syscall.OpenProcessToken(h, syscall.TOKEN_QUERY, &token); err != nil {
+		return "", ""
+	}
+	defer token.Close()
+
+	user, err := token.GetTokenUser()
+	if err != nil {
+		return "", ""
+	}
+	sid, err := user.User.Sid.String()
+	if err != nil {
+		return "", ""
+	}
+	account, domain, _, err := user.User.Sid.LookupAccount("")
+	if err != nil {
+		return sid, ""
+	}
+	return sid, domain + `\` + account
+}
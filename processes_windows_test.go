@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGetRunningProcessesFindsItself(t *testing.T) {
+	self, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	processes, err := toolhelpProvider{}.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, found := findProcess(processes, os.Getpid())
+	if !found {
+		t.Fatalf("%s (pid %d) not found", self, os.Getpid())
+	}
+	if !strings.EqualFold(p.Path, self) {
+		t.Errorf("path is %s (expected %s)", p.Path, self)
+	}
+	if !strings.HasPrefix(p.UserID, "S-1-") {
+		t.Errorf("owner is %q, expected a SID", p.UserID)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import "time"
+
+// processSession is a run of a process counted in an activity, from Start to
+// End, the time of the last scan seeing it.
+type processSession struct {
+	Activity string    `json:"activity"`
+	User     string    `json:"user,omitempty"`
+	Path     string    `json:"path"`
+	Pid      int       `json:"pid"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	// Duration is the time the process ran during the session
+	Duration duration `json:"duration"`
+}
+
+// trackProcessSession extends the session of p in activity when it was
+// seen at the previous scan, done at previous, or starts a new one having
+// lasted d so far.
+func (c *dadController) trackProcessSession(activity string, user string, p runningProcess, d time.Duration, previous time.Time, now time.Time) {
+	for i := len(c.ProcessSessions) - 1; i >= 0; i-- {
+		s := c.ProcessSessions[i]
+		if s.Activity == activity && s.User == user && s.Pid == p.Pid && s.Path == p.Path && s.End.Equal(previous) {
+			s.End = now
+			s.Duration += duration(d)
+			return
+		}
+	}
+	c.ProcessSessions = append(c.ProcessSessions, &processSession{
+		Activity: activity,
+		User:     user,
+		Path:     p.Path,
+		Pid:      p.Pid,
+		Start:    now.Add(-d),
+		End:      now,
+		Duration: duration(d),
+	})
+}
+
+// forgetProcessSessions drops the sessions which did not end on the day of
+// now.
+func (c *dadController) forgetProcessSessions(now time.Time) {
+	var kept []*processSession
+	for _, s := range c.ProcessSessions {
+		if sameDay(s.End, now) {
+			kept = append(kept, s)
+		}
+	}
+	c.ProcessSessions = kept
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEachRunOfAProcessIsRecorded(t *testing.T) {
+	at := func(hour int, min int) time.Time {
+		return time.Date(2024, time.October, 14, hour, min, 0, 0, time.Local)
+	}
+	ctx := NewTest(t).
+		GivenTimeIs(at(14, 1)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(2)*time.Hour).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		WhenScanHappens().
+		GivenNoRunningProcess().
+		WhenScanHappens().
+		GivenTimeIs(at(19, 29)).
+		GivenARunningProcessStartedAt("C:\\GTA.exe", 2, at(19, 29)).
+		WhenScanHappens()
+
+	expected := []processSession{
+		{Activity: "GTA", Path: "C:\\GTA.exe", Pid: 1, Start: at(14, 1), End: at(14, 3), Duration: duration(time.Duration(2) * time.Minute)},
+		{Activity: "GTA", Path: "C:\\GTA.exe", Pid: 2, Start: at(19, 29), End: at(19, 30), Duration: duration(time.Duration(1) * time.Minute)},
+	}
+	sessions := ctx.controller.ProcessSessions
+	if len(sessions) != len(expected) {
+		t.Fatalf("%d sessions recorded (expected %d)", len(sessions), len(expected))
+	}
+	for i, s := range sessions {
+		if *s != expected[i] {
+			t.Errorf("session %d is %+v (expected %+v)", i, *s, expected[i])
+		}
+	}
+}
+
+func TestRunsOfTheDayBeforeAreForgotten(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 23, 58, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(2)*time.Hour).
+		GivenARunningProcess("C:\\GTA.exe", 1).
+		WhenScanHappens().
+		WhenScanHappens()
+
+	if sessions := ctx.controller.ProcessSessions; len(sessions) != 1 || !sessions[0].Start.Equal(time.Date(2024, time.October, 14, 23, 59, 0, 0, time.Local)) {
+		t.Errorf("sessions are %v", sessions)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// profileUserPrefix prefixes the name of the active profile to make up the
+// user identifier its time is counted under.
+const profileUserPrefix = "profile:"
+
+// userProfile is the rule set applying to the processes of an account, e.g.
+// "tom" or "HOME-PC\\Tom", instead of the rules of the configuration.
+type userProfile struct {
+	Rules []*activityRule `json:"rules"`
+}
+
+// allRules returns the rules of the configuration followed by the ones of
+// the profiles of the accounts, then of the named profiles.
+func (cfg *config) allRules() []*activityRule {
+	rules := append([]*activityRule(nil), cfg.Activities...)
+	for _, account := range sortedProfiles(cfg.Users) {
+		rules = append(rules, cfg.Users[account].Rules...)
+	}
+	for _, name := range sortedProfiles(cfg.Profiles) {
+		rules = append(rules, cfg.Profiles[name].Rules...)
+	}
+	return rules
+}
+
+func sortedProfiles(profiles map[string]*userProfile) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// rememberAccounts records the account names of the owners of processes.
+func (c *dadController) rememberAccounts(processes []runningProcess) {
+	for _, p := range processes {
+		if p.UserID == "" || p.User == "" {
+			continue
+		}
+		if c.accounts == nil {
+			c.accounts = make(map[string]string)
+		}
+		c.accounts[p.UserID] = p.User
+	}
+}
+
+// rulesOf returns the rules applying to the processes of user, those of the
+// named profile user stands for, or of the profile of its account when it
+// has one.
+func (c *dadController) rulesOf(user string) []*activityRule {
+	if strings.HasPrefix(user, profileUserPrefix) {
+		if p, found := c.Profiles[strings.TrimPrefix(user, profileUserPrefix)]; found {
+			return p.Rules
+		}
+		return c.Activities
+	}
+	if account := c.accounts[user]; account != "" {
+		for _, name := range sortedProfiles(c.Users) {
+			if accountMatches(account, name) {
+				return c.Users[name].Rules
+			}
+		}
+	}
+	return c.Activities
+}
+
+// ruleOf returns the rule of activity applying to the processes of user,
+// nil when there is none.
+func (c *dadController) ruleOf(user string, activity string) *activityRule {
+	for _, a := range c.rulesOf(user) {
+		if a.Name == activity {
+			return a
+		}
+	}
+	return nil
+}
+
+// ownerOf returns the user owning the processes of rp, which the controller
+// enforces per user, empty when unknown.
+func ownerOf(rp []runningProcess) string {
+	if len(rp) == 0 {
+		return ""
+	}
+	return rp[0].UserID
+}
+
+// hasActivity tells whether activity is the name of a rule, of any account
+// or profile.
+func (c *dadController) hasActivity(activity string) bool {
+	for _, a := range c.allRules() {
+		if a.Name == activity {
+			return true
+		}
+	}
+	return false
+}
+
+// activityNames returns the names of the activities of all the rules, once
+// each, in the order of the configuration.
+func (c *dadController) activityNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, a := range c.allRules() {
+		if !seen[a.Name] {
+			seen[a.Name] = true
+			names = append(names, a.Name)
+		}
+	}
+	return names
+}
+
+// SwitchProfile makes the named profile active, the processes of every
+// account but the parent ones following its rules and being counted under
+// it, until another one is. An empty name switches back to the accounts.
+func (c *dadController) SwitchProfile(name string) error {
+	if _, found := c.Profiles[name]; name != "" && !found {
+		return fmt.Errorf("unknown profile %s", name)
+	}
+	c.ActiveProfile = name
+	fmt.Fprintf(logOutput, "Active profile: %q\n", name)
+	return nil
+}
+
+// asActiveProfile returns processes as owned by the active profile, if any.
+func (c *dadController) asActiveProfile(processes []runningProcess) []runningProcess {
+	if _, found := c.Profiles[c.ActiveProfile]; !found {
+		return processes
+	}
+	owned := make([]runningProcess, len(processes))
+	for i, p := range processes {
+		p.UserID = profileUserPrefix + c.ActiveProfile
+		owned[i] = p
+	}
+	return owned
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProfileRulesApplyToTheProcessesOfTheirAccount(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAUserActivityDuration(`HOME-PC\Tom`, "GTA", time.Duration(90)*time.Minute).
+		GivenAUserActivityDuration(`HOME-PC\Lea`, "GTA", time.Duration(90)*time.Minute).
+		GivenARunningProcessOfAccount("C:\\GTA.exe", 1, `HOME-PC\Tom`).
+		GivenARunningProcessOfAccount("C:\\GTA.exe", 2, `HOME-PC\Lea`)
+	everyDay := []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	teen := &activityRule{Name: "GTA", ProcessPatterns: []string{"GTA.exe"}, AllowedSchedules: make(map[time.Weekday]*schedule)}
+	teen.AddAllowedPeriod(everyDay, 0, 2359)
+	teen.SetMaximumAllowedDurationPerDay(everyDay, time.Duration(2)*time.Hour)
+	ctx.controller.Users = map[string]*userProfile{"tom": {Rules: []*activityRule{teen}}}
+
+	ctx.WhenScanHappens()
+	if !reflect.DeepEqual(ctx.killedProcesses, []string{"2|C:\\GTA.exe"}) {
+		t.Errorf("only Lea should be over the limit, killed %q", ctx.killedProcesses)
+	}
+	ctx.ThenUserActivityExecutionDurationShouldBe(`HOME-PC\Tom`, "GTA", time.Duration(91)*time.Minute)
+}
+
+func TestProfileRulesAreValidated(t *testing.T) {
+	invalid := `{"users": {"tom": {"rules": [{"name": "GTA", "programs": ["GTA(.exe"]}]}}}`
+	if _, err := parseConfig([]byte(invalid)); err == nil {
+		t.Errorf("%s should be invalid", invalid)
+	}
+}
+
+func TestActiveProfileIsCountedAndEnforcedSeparately(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(2)*time.Hour).
+		GivenARunningProcessOfAccount("C:\\GTA.exe", 1, `HOME-PC\Family`)
+	emma := &activityRule{Name: "GTA", ProcessPatterns: []string{"GTA.exe"}}
+	ctx.controller.Profiles = map[string]*userProfile{"Emma": {Rules: []*activityRule{emma}}}
+	handler := ctx.pinnedHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/profile?name=Emma", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST returned %d: %s", rec.Code, rec.Body.String())
+	}
+	// GTA is never allowed to Emma
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity not allowed to be done on this day").
+		ThenUserActivityExecutionDurationShouldBe("profile:Emma", "GTA", time.Minute)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/profile", nil))
+	ctx.WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenUserActivityExecutionDurationShouldBe(`HOME-PC\Family`, "GTA", time.Minute)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/profile?name=Noah", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("switching to an unknown profile returned %d", rec.Code)
+	}
+}
+
+func TestProfileCannotBeSwitchedWithoutPIN(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(2)*time.Hour)
+	ctx.controller.Profiles = map[string]*userProfile{"Emma": {Rules: []*activityRule{{Name: "GTA", ProcessPatterns: []string{"GTA.exe"}}}}}
+	handler := ctx.controller.httpHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/profile?name=Emma", nil))
+	if rec.Code != http.StatusForbidden || ctx.controller.ActiveProfile != "" {
+		t.Errorf("switching profile without PIN returned %d, active profile %q", rec.Code, ctx.controller.ActiveProfile)
+	}
+
+	ctx.controller.PIN = testPIN
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/profile?name=Emma&pin=0000", nil))
+	if rec.Code != http.StatusForbidden || ctx.controller.ActiveProfile != "" {
+		t.Errorf("switching profile with a wrong PIN returned %d, active profile %q", rec.Code, ctx.controller.ActiveProfile)
+	}
+}
+
+// givenTomsProfile gives the account HOME-PC\Tom a profile made of rules,
+// each allowed every day from begin to end for up to max.
+func (ctx *TestContext) givenTomsProfile(rules ...*activityRule) *TestContext {
+	ctx.controller.Users = map[string]*userProfile{"tom": {Rules: rules}}
+	return ctx
+}
+
+func profileRule(name string, program string, max time.Duration, begin int, end int) *activityRule {
+	everyDay := []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday}
+	a := &activityRule{Name: name, ProcessPatterns: []string{program}, AllowedSchedules: make(map[time.Weekday]*schedule)}
+	a.AddAllowedPeriod(everyDay, begin, end)
+	a.SetMaximumAllowedDurationPerDay(everyDay, max)
+	return a
+}
+
+func TestProfileOnlyActivityIsKilledAsItsRuleSays(t *testing.T) {
+	fortnite := profileRule("Fortnite", "Fortnite.exe", time.Duration(15)*time.Minute, 0, 2359)
+	fortnite.CloseTimeout = duration(time.Duration(30) * time.Second)
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		givenTomsProfile(fortnite).
+		GivenAUserActivityDuration(`HOME-PC\Tom`, "Fortnite", time.Duration(20)*time.Minute).
+		GivenARunningProcessOfAccount("C:\\Fortnite.exe", 1, `HOME-PC\Tom`)
+
+	ctx.WhenScanHappens().
+		ThenNoProcessKilled()
+	if expected := []string{"1|C:\\Fortnite.exe"}; !reflect.DeepEqual(ctx.closedProcesses, expected) {
+		t.Errorf("closed %q (expected %q)", ctx.closedProcesses, expected)
+	}
+}
+
+func TestPeriodEndOfAProfileOnlyActivityIsEnforcedAtTheBoundary(t *testing.T) {
+	boundary := time.Date(2024, time.October, 14, 21, 0, 0, 0, time.Local)
+	NewTest(t).
+		GivenTimeIs(boundary.Add(time.Duration(-75)*time.Second)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		givenTomsProfile(profileRule("Fortnite", "Fortnite.exe", time.Duration(15)*time.Hour, 2000, 2100)).
+		GivenARunningProcessOfAccount("C:\\Fortnite.exe", 1, `HOME-PC\Tom`).
+		WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenATimerShouldBeArmedAt(boundary)
+}
+
+func TestProfileOnlyActivitiesAreSplitByTheirWeight(t *testing.T) {
+	youtube := profileRule("YouTube", "firefox.exe", time.Duration(1)*time.Hour, 0, 2359)
+	youtube.Weight = 3
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		givenTomsProfile(profileRule("Browsing", "firefox.exe", time.Duration(1)*time.Hour, 0, 2359), youtube).
+		GivenARunningProcessOfAccount("C:\\firefox.exe", 1, `HOME-PC\Tom`)
+	ctx.controller.SharedProcessAttribution = sharedProcessWeighted
+
+	ctx.WhenScanHappens().
+		ThenUserActivityExecutionDurationShouldBe(`HOME-PC\Tom`, "Browsing", time.Duration(15)*time.Second).
+		ThenUserActivityExecutionDurationShouldBe(`HOME-PC\Tom`, "YouTube", time.Duration(45)*time.Second)
+}
+
+func TestProfileOnlyWarnOnlyRuleNotifiesAboveItsThreshold(t *testing.T) {
+	monday := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.Local)
+	fortnite := profileRule("Fortnite", "Fortnite.exe", time.Duration(15)*time.Minute, 0, 2359)
+	fortnite.WarnOnly = true
+	fortnite.MaxWeeklyViolations = 1
+	ctx := NewTest(t).
+		GivenTimeIs(monday).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		givenTomsProfile(fortnite).
+		GivenARunningProcessOfAccount("C:\\Fortnite.exe", 1, `HOME-PC\Tom`)
+
+	for _, day := range []time.Time{monday, monday.AddDate(0, 0, 1)} {
+		ctx.GivenTimeIs(day).
+			WhenScanHappens().
+			GivenAUserActivityDuration(`HOME-PC\Tom`, "Fortnite", time.Duration(20)*time.Minute).
+			WhenScanHappens().
+			ThenNoProcessKilled()
+	}
+	ctx.ThenParentShouldHaveBeenNotified("Fortnite exceeded its limits on 2 days this week")
+}
+
+func TestProfileOnlyActivityIsKnownToTheHTTPAPI(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		givenTomsProfile(profileRule("Fortnite", "Fortnite.exe", time.Duration(1)*time.Hour, 0, 2359))
+	handler := ctx.pinnedHandler()
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/credit?activity=Fortnite&minutes=30", nil),
+		httptest.NewRequest(http.MethodPost, "/override?activity=Fortnite&date=2024-12-24&maxDuration=3h&periods=10:00-12:00", nil),
+		httptest.NewRequest(http.MethodGet, `/history?activity=Fortnite&user=HOME-PC\Tom`, nil),
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s %s returned %d: %s", r.Method, r.URL, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProcessProvider enumerates the running processes and acts on them. Each
+// platform registers its native provider, which is used unless the
+// configuration selects another registered one by name.
+type ProcessProvider interface {
+	List() ([]runningProcess, error)
+	// Kill terminates p, with signal on the platforms having signals
+	Kill(p runningProcess, signal string) error
+	// Close asks p to exit on its own
+	Close(p runningProcess) error
+	// Suspend freezes p without terminating it
+	Suspend(p runningProcess) error
+	// Resume lets p run again after being suspended
+	Resume(p runningProcess) error
+}
+
+var processProviders = make(map[string]func() ProcessProvider)
+
+func registerProcessProvider(name string, newProvider func() ProcessProvider) {
+	processProviders[name] = newProvider
+}
+
+// newProcessProvider returns the provider registered as name, the native
+// provider of the platform when name is empty.
+func newProcessProvider(name string) (ProcessProvider, error) {
+	if name == "" {
+		name = nativeProcessProvider
+	}
+	newProvider, found := processProviders[name]
+	if !found {
+		names := make([]string, 0, len(processProviders))
+		for n := range processProviders {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown process provider %q, expected %s", name, strings.Join(names, " or "))
+	}
+	return newProvider(), nil
+}
+
+func nativeProvider() ProcessProvider {
+	p, err := newProcessProvider("")
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNativeProcessProviderIsRegistered(t *testing.T) {
+	p, err := newProcessProvider("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	named, err := newProcessProvider(nativeProcessProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != named {
+		t.Errorf("default provider %T is not the native one %T", p, named)
+	}
+}
+
+func TestUnknownProcessProviderIsRejected(t *testing.T) {
+	if _, err := newProcessProvider("gopsutil"); err == nil || !strings.Contains(err.Error(), nativeProcessProvider) {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	cfg, err := parseConfig([]byte(`{"processProvider": "gopsutil"}`))
+	if err == nil {
+		t.Error("configuration with an unknown provider should be invalid")
+	}
+	if cfg.ProcessProvider != "" {
+		t.Errorf("unknown provider %q should fall back to the native one", cfg.ProcessProvider)
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// remoteConfigFileName is the local copy of a configuration served over
+	// HTTPS, next to the state file, applied when the server cannot be
+	// reached
+	remoteConfigFileName = "dad-controller.remote.json"
+	// remoteConfigInterval is how often the remote configuration is checked
+	// for changes
+	remoteConfigInterval = 5 * time.Minute
+	// maxRemoteConfigSize bounds the size of a remote configuration
+	maxRemoteConfigSize = 1 << 20
+)
+
+// remoteConfig keeps a local copy of a configuration served over HTTPS,
+// which the controller applies as its configuration file, so that the
+// configuration of several computers can be edited in one place.
+type remoteConfig struct {
+	url       string
+	localFile string
+	client    *http.Client
+	// signed is set when the signature of the configuration, served next to
+	// it, must be fetched too
+	signed bool
+	// fetchedAt is when the remote configuration was last checked
+	fetchedAt time.Time
+}
+
+// remoteConfigValidators are the cache validators of the local copy, saved
+// next to it for the server to tell whether it changed since.
+type remoteConfigValidators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+func isRemoteConfig(configFile string) bool {
+	return strings.HasPrefix(configFile, "https://")
+}
+
+// localConfigFile returns the configuration file the controller applies:
+// configFile itself, or the local copy of the configuration it serves over
+// HTTPS, refreshed first, along with what keeps it up to date.
+func localConfigFile(configFile string, stateFile string) (string, *remoteConfig) {
+	if !isRemoteConfig(configFile) {
+		return configFile, nil
+	}
+	r := &remoteConfig{
+		url:       configFile,
+		localFile: filepath.Join(filepath.Dir(stateFile), remoteConfigFileName),
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+	r.signed = fileExists(keyFile(stateFile))
+	r.refresh()
+	return r.localFile, r
+}
+
+// refreshIfDue refreshes the local copy when it was not checked for
+// remoteConfigInterval.
+func (r *remoteConfig) refreshIfDue() {
+	if time.Since(r.fetchedAt) >= remoteConfigInterval {
+		r.refresh()
+	}
+}
+
+// refresh fetches the remote configuration, replacing the local copy when it
+// changed, and keeps the local copy when it cannot be fetched.
+func (r *remoteConfig) refresh() {
+	r.fetchedAt = time.Now()
+	changed, err := r.fetch()
+	if err != nil {
+		fmt.Fprintf(logOutput, "Failure to fetch the configuration from %s, keeping the local copy : %s\n", r.url, err)
+		return
+	}
+	if changed {
+		fmt.Fprintf(logOutput, "Fetched a new configuration from %s\n", r.url)
+	}
+}
+
+// fetch downloads the remote configuration unless it did not change since
+// the local copy was, telling whether the local copy was replaced.
+func (r *remoteConfig) fetch() (bool, error) {
+	var validators remoteConfigValidators
+	if fileExists(r.localFile) {
+		if data, err := ioutil.ReadFile(r.validatorsFile()); err == nil {
+			json.Unmarshal(data, &validators)
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, r.url, nil)
+	if err != nil {
+		return false, err
+	}
+	if validators.ETag != "" {
+		req.Header.Set("If-None-Match", validators.ETag)
+	}
+	if validators.LastModified != "" {
+		req.Header.Set("If-Modified-Since", validators.LastModified)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		return false, nil
+	case http.StatusOK:
+	default:
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize+1))
+	if err != nil {
+		return false, err
+	}
+	if len(data) > maxRemoteConfigSize {
+		return false, fmt.Errorf("configuration larger than %d bytes", maxRemoteConfigSize)
+	}
+	if _, err := parseConfigIn(data, filepath.Dir(r.localFile)); err != nil {
+		return false, fmt.Errorf("invalid configuration : %s", err)
+	}
+	if r.signed {
+		signature, err := r.fetchSignature()
+		if err != nil {
+			return false, err
+		}
+		if err := replaceFile(signatureFile(r.localFile), signature); err != nil {
+			return false, err
+		}
+	}
+	if err := replaceFile(r.localFile, data); err != nil {
+		return false, err
+	}
+
+	validators = remoteConfigValidators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+	if data, err := json.Marshal(validators); err == nil {
+		replaceFile(r.validatorsFile(), data)
+	}
+	return true, nil
+}
+
+// fetchSignature downloads the signature of the remote configuration,
+// served next to it.
+func (r *remoteConfig) fetchSignature() ([]byte, error) {
+	resp, err := r.client.Get(signatureFile(r.url))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s for the signature", resp.Status)
+	}
+	return ioutil.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize))
+}
+
+func (r *remoteConfig) validatorsFile() string {
+	return r.localFile + ".cache"
+}
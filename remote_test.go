@@ -0,0 +1,60 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoteConfigurationIsCachedLocally(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	body := `{"rules": [{"name": "GTA", "programs": ["gta"]}]}`
+	etag := `"1"`
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	r := &remoteConfig{url: server.URL + "/dad-controller.json", localFile: filepath.Join(dir, remoteConfigFileName), client: server.Client()}
+	if changed, err := r.fetch(); err != nil || !changed {
+		t.Fatalf("first fetch: changed %v, %v", changed, err)
+	}
+	if data, _ := ioutil.ReadFile(r.localFile); string(data) != body {
+		t.Errorf("unexpected local copy %q", data)
+	}
+	if changed, err := r.fetch(); err != nil || changed {
+		t.Errorf("unchanged configuration fetched again: changed %v, %v", changed, err)
+	}
+
+	// an invalid configuration does not replace the local copy
+	body, etag = `{"rules": [{"name": "GTA", "programs": ["gta(.exe"]}]}`, `"2"`
+	if _, err := r.fetch(); err == nil {
+		t.Error("invalid configuration accepted")
+	}
+	server.Close()
+	if _, err := r.fetch(); err == nil {
+		t.Error("unreachable server not reported")
+	}
+	cfg, err := loadConfig(r.localFile)
+	if err != nil || len(cfg.Activities) != 1 || cfg.Activities[0].Name != "GTA" {
+		t.Errorf("local copy not kept: %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+}
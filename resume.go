@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Policies of crediting the time elapsed since the previous scan when it is
+// longer than the maximum credited interval, which is mostly the computer
+// resuming from sleep or hibernation with the activities still running: the
+// maximum credited interval is credited, or nothing at all.
+const (
+	resumeCap     = "cap"
+	resumeDiscard = "discard"
+)
+
+// creditAfterResume returns the time to credit when elapsed, the time since
+// the previous scan, is longer than max.
+func (c *dadController) creditAfterResume(elapsed time.Duration, max time.Duration) time.Duration {
+	if c.ResumePolicy == resumeDiscard {
+		fmt.Fprintf(logOutput, "%s elapsed since the previous scan, resuming from sleep, crediting nothing\n", elapsed.Round(time.Second))
+		return 0
+	}
+	fmt.Fprintf(logOutput, "%s elapsed since the previous scan, resuming from sleep, crediting %s only\n", elapsed.Round(time.Second), max)
+	return max
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeSpentAsleepIsCappedOrDiscarded(t *testing.T) {
+	for policy, expected := range map[string]time.Duration{
+		"":            time.Duration(6) * time.Minute,
+		resumeCap:     time.Duration(6) * time.Minute,
+		resumeDiscard: time.Duration(1) * time.Minute,
+	} {
+		ctx := NewTest(t).
+			GivenTimeIs(time.Date(2024, time.October, 14, 1, 0, 0, 0, time.Local)).
+			GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+			GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(2)*time.Hour).
+			GivenARunningProcess("C:\\GTA.exe", 1).
+			WhenScanHappens()
+		ctx.controller.ResumePolicy = policy
+
+		ctx.GivenTimeIs(time.Date(2024, time.October, 14, 8, 0, 0, 0, time.Local)).
+			WhenScanHappens()
+		if d := ctx.controller.GetActivityDuration("GTA"); d != expected {
+			t.Errorf("GTA duration is %s with policy %q (expected %s)", d, policy, expected)
+		}
+	}
+}
+
+func TestUnknownResumePolicyIsRejected(t *testing.T) {
+	if _, err := parseConfig([]byte(`{"resumePolicy": "ignore"}`)); err == nil {
+		t.Error("unknown resume policy accepted")
+	}
+}
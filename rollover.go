@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// rollOver banks, at the first scan of now, the time left unused on the day
+// before by the activities whose rule has a rollover cap, for it to be
+// spent on top of the maximum duration of today. Nothing is carried over
+// when the controller did not run the day before, done at previous.
+func (c *dadController) rollOver(previous time.Time, now time.Time) {
+	banked := c.Banked
+	c.Banked = nil
+	yesterday := now.AddDate(0, 0, -1)
+	if previous.IsZero() || !sameDay(yesterday, previous) {
+		return
+	}
+
+	users := []string{""}
+	for user := range c.UserActivityDuration {
+		users = append(users, user)
+	}
+	for _, user := range users {
+		for _, a := range c.rulesOf(user) {
+			if a.RolloverCap <= 0 {
+				continue
+			}
+			resolved := c.effectiveSchedule(a, yesterday)
+			if !resolved.Allowed {
+				continue
+			}
+			allowed := time.Duration(resolved.MaxDuration) + time.Duration(banked[user][a.Name])
+			unused := allowed - time.Duration(c.durationsOf(user)[dateKey(yesterday)][a.Name])
+			if unused <= 0 {
+				continue
+			}
+			unused = minDuration(unused, time.Duration(a.RolloverCap))
+			if c.Banked == nil {
+				c.Banked = make(map[string]map[string]duration)
+			}
+			if c.Banked[user] == nil {
+				c.Banked[user] = make(map[string]duration)
+			}
+			c.Banked[user][a.Name] = duration(unused)
+			fmt.Fprintf(logOutput, "%s of %s unused yesterday carried over\n", unused, a.Name)
+		}
+	}
+}
+
+// banked returns the time carried over from yesterday by user on activity.
+func (c *dadController) banked(user string, activity string) time.Duration {
+	return time.Duration(c.Banked[user][activity])
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUnusedTimeIsCarriedOverUpToTheCap(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 23, 59, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenAnActivityDuration("GTA", time.Duration(40)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].RolloverCap = duration(time.Duration(15) * time.Minute)
+
+	ctx.WhenScanHappens()
+	if banked := ctx.controller.banked("", "GTA"); banked != time.Duration(15)*time.Minute {
+		t.Fatalf("banked %s, expected the cap", banked)
+	}
+
+	ctx.GivenAnActivityDuration("GTA", time.Duration(70)*time.Minute).
+		WhenScanHappens().
+		ThenNoProcessKilled()
+}
+
+func TestNothingIsCarriedOverAfterADayWithoutControl(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour)
+	ctx.controller.Activities[0].RolloverCap = duration(time.Duration(15) * time.Minute)
+
+	ctx.GivenTimeIs(time.Date(2024, time.October, 16, 20, 0, 0, 0, time.Local)).
+		WhenScanHappens()
+	if banked := ctx.controller.banked("", "GTA"); banked != 0 {
+		t.Errorf("banked %s, expected nothing", banked)
+	}
+}
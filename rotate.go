@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRotationMaxSize  = 10 * 1024 * 1024
+	defaultRotationMaxFiles = 5
+
+	// rotationRetryDelay is how long a file whose rotation failed is
+	// appended to before its rotation is tried again
+	rotationRetryDelay = time.Minute
+)
+
+type (
+	// rotationPolicy describes when a log file is rotated and how many
+	// rotated files are kept. Zero values fall back to the defaults.
+	rotationPolicy struct {
+		MaxSize  int64    `json:"maxSize"`
+		MaxAge   duration `json:"maxAge"`
+		MaxFiles int      `json:"maxFiles"`
+	}
+
+	// rotatingFile is an io.Writer appending to path. Once the file grows
+	// above MaxSize bytes or is older than MaxAge, it is renamed path.1
+	// (shifting older files to path.2, path.3, ...) and a fresh file is
+	// opened. Only MaxFiles rotated files are kept.
+	rotatingFile struct {
+		path   string
+		policy rotationPolicy
+		now    func() time.Time
+
+		mu       sync.Mutex
+		file     *os.File
+		size     int64
+		openedAt time.Time
+		retryAt  time.Time
+	}
+)
+
+func (p rotationPolicy) maxSize() int64 {
+	if p.MaxSize <= 0 {
+		return defaultRotationMaxSize
+	}
+	return p.MaxSize
+}
+
+func (p rotationPolicy) maxFiles() int {
+	if p.MaxFiles <= 0 {
+		return defaultRotationMaxFiles
+	}
+	return p.MaxFiles
+}
+
+func newRotatingFile(path string, policy rotationPolicy, now func() time.Time) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, policy: policy, now: now}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file = file
+	r.size = stat.Size()
+	r.openedAt = r.now()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if r.size > 0 && r.needsRotation(int64(len(p))) {
+		if err := r.rotate(); err != nil {
+			// logOutput may be r, the failure is reported on the standard error
+			fmt.Fprintf(os.Stderr, "Failure to rotate %s : %s\n", r.path, err)
+			r.retryAt = r.now().Add(rotationRetryDelay)
+			if r.file == nil {
+				return 0, err
+			}
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) needsRotation(pending int64) bool {
+	if r.now().Before(r.retryAt) {
+		return false
+	}
+	if r.size+pending > r.policy.maxSize() {
+		return true
+	}
+	return r.policy.MaxAge > 0 && r.now().Sub(r.openedAt) >= time.Duration(r.policy.MaxAge)
+}
+
+// rotate renames the file and opens a fresh one, reopening the file when it
+// could not be renamed so that it is appended to until the next try.
+func (r *rotatingFile) rotate() error {
+	err := r.file.Close()
+	if err == nil {
+		err = r.shift()
+	}
+	if openErr := r.open(); openErr != nil {
+		r.file = nil
+		return openErr
+	}
+	return err
+}
+
+// shift renames path to path.1, shifting the rotated files and dropping the
+// oldest one.
+func (r *rotatingFile) shift() error {
+	maxFiles := r.policy.maxFiles()
+	os.Remove(fmt.Sprintf("%s.%d", r.path, maxFiles))
+	for i := maxFiles - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", r.path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", r.path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return os.Rename(r.path, r.path+".1")
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func countLogFiles(t *testing.T, dir string) int {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(files)
+}
+
+func TestRotatingFileRotatesPastSizeThreshold(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	r, err := newRotatingFile(path, rotationPolicy{MaxSize: 100, MaxFiles: 3}, time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	line := strings.Repeat("x", 49) + "\n"
+	for i := 0; i < 4; i++ {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countLogFiles(t, dir); n != 2 {
+		t.Errorf("found %d files after first rotation (expected 2)", n)
+	}
+
+	for i := 0; i < 20; i++ {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := countLogFiles(t, dir); n != 4 {
+		t.Errorf("found %d files (expected current file plus 3 rotated ones)", n)
+	}
+	if _, err := os.Stat(path + ".4"); !os.IsNotExist(err) {
+		t.Errorf("%s.4 should have been removed", path)
+	}
+}
+
+func TestRotatingFileRotatesPastMaxAge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	path := filepath.Join(dir, "dad-controller.log")
+	r, err := newRotatingFile(path, rotationPolicy{MaxAge: duration(24 * time.Hour)}, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	r.Write([]byte("day 1\n"))
+	now = now.Add(25 * time.Hour)
+	r.Write([]byte("day 2\n"))
+
+	if n := countLogFiles(t, dir); n != 2 {
+		t.Errorf("found %d files (expected 2)", n)
+	}
+	data, _ := ioutil.ReadFile(path + ".1")
+	if string(data) != "day 1\n" {
+		t.Errorf("rotated file contains %q", string(data))
+	}
+}
+
+func TestRotatingFileKeepsAppendingWhenRotationFails(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	// a directory which is not empty can be neither removed nor replaced
+	if err := os.MkdirAll(filepath.Join(path+".1", "busy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)
+	r, err := newRotatingFile(path, rotationPolicy{MaxSize: 100, MaxFiles: 1}, func() time.Time { return now })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	line := strings.Repeat("x", 49) + "\n"
+	for i := 0; i < 4; i++ {
+		if _, err := r.Write([]byte(line)); err != nil {
+			t.Fatalf("writing while the rotation fails: %s", err)
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != strings.Repeat(line, 4) {
+		t.Errorf("wrote %q (expected every line kept)", data)
+	}
+
+	os.RemoveAll(path + ".1")
+	now = now.Add(rotationRetryDelay)
+	if _, err := r.Write([]byte(line)); err != nil {
+		t.Fatalf("writing after a failed rotation: %s", err)
+	}
+	if data, err = ioutil.ReadFile(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != strings.Repeat(line, 4) {
+		t.Errorf("rotated %q (expected the lines written before the retry)", data)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// run scans the running processes every sampling interval, reloading the
+// configuration when it changes, and fetching it again when it is remote,
+// until Stop is called. The state is saved
+// before returning.
+func (c *dadController) run() {
+	watching := c.watchConfig()
+	lastScan := time.Now()
+	for !c.isStopping() {
+		if c.remote != nil {
+			c.remote.refreshIfDue()
+		}
+		if !watching {
+			c.reloadConf()
+		}
+		lastScan = c.scanAfter(lastScan)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dumpState()
+	fmt.Fprintln(logOutput, "Controller stopped")
+}
+
+// reloadConf reloads the configuration when it changed, waking the scan
+// loop up for it to wait the new sampling interval.
+func (c *dadController) reloadConf() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.SamplingInterval
+	c.reloadConfIfNeeded()
+	if c.SamplingInterval != previous {
+		c.samplingIntervalUpdated()
+	}
+}
+
+// Stop ends the scan loop of run, interrupting the wait for the next scan.
+func (c *dadController) Stop() {
+	c.stopOnce.Do(func() { close(c.stopping) })
+}
+
+func (c *dadController) isStopping() bool {
+	select {
+	case <-c.stopping:
+		return true
+	default:
+		return false
+	}
+}
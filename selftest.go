@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"time"
+)
+
+type (
+	// selfTestProviders are the parts of the enforcement pipeline exercised
+	// by the self test, injectable for tests.
+	selfTestProviders struct {
+		// StartProcess launches a harmless process, returning its pid and a
+		// function releasing it once killed
+		StartProcess func() (int, func(), error)
+		Processes    ProcessProvider
+		// KillTimeout is how long the killed process may take to disappear
+		KillTimeout time.Duration
+	}
+
+	selfTestStep struct {
+		Name string
+		Err  error
+	}
+)
+
+func defaultSelfTestProviders() selfTestProviders {
+	return selfTestProviders{
+		StartProcess: startThrowawayProcess,
+		Processes:    nativeProvider(),
+		KillTimeout:  10 * time.Second,
+	}
+}
+
+func startThrowawayProcess() (int, func(), error) {
+	cmd := exec.Command("sleep", "300")
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("ping", "-n", "300", "127.0.0.1")
+	}
+	if err := cmd.Start(); err != nil {
+		return 0, nil, err
+	}
+	return cmd.Process.Pid, func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}, nil
+}
+
+func findProcess(processes []runningProcess, pid int) (runningProcess, bool) {
+	for _, p := range processes {
+		if p.Pid == pid {
+			return p, true
+		}
+	}
+	return runningProcess{}, false
+}
+
+// runSelfTest launches a throwaway process and checks that it is
+// enumerated, matched by a rule and killed. Steps after a failed one are
+// not run.
+func runSelfTest(p selfTestProviders) []selfTestStep {
+	pid, release, err := p.StartProcess()
+	if err != nil {
+		return []selfTestStep{{Name: "launching", Err: err}}
+	}
+	defer release()
+
+	steps := []selfTestStep{{Name: "launching"}}
+
+	processes, err := p.Processes.List()
+	process, found := findProcess(processes, pid)
+	if err == nil && !found {
+		err = fmt.Errorf("process %d not found among %d running processes", pid, len(processes))
+	}
+	steps = append(steps, selfTestStep{Name: "enumeration", Err: err})
+	if err != nil {
+		return steps
+	}
+
+	rule := activityRule{Name: "selftest", ProcessPatterns: []string{regexp.QuoteMeta(filepath.Base(process.Path)) + "$"}}
+	matching := rule.matchingProcesses([]runningProcess{process})
+	if len(matching) == 0 {
+		err = fmt.Errorf("pattern %s does not match %s", rule.ProcessPatterns[0], process.Path)
+	}
+	steps = append(steps, selfTestStep{Name: "matching", Err: err})
+	if err != nil {
+		return steps
+	}
+
+	for _, m := range matching {
+		if err := p.Processes.Kill(m, ""); err != nil {
+			return append(steps, selfTestStep{Name: "killing", Err: err})
+		}
+	}
+	deadline := time.Now().Add(p.KillTimeout)
+	for {
+		processes, err = p.Processes.List()
+		if err != nil {
+			break
+		}
+		if _, found := findProcess(processes, pid); !found {
+			break
+		}
+		if time.Now().After(deadline) {
+			err = fmt.Errorf("process %d still running %s after being killed", pid, p.KillTimeout)
+			break
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return append(steps, selfTestStep{Name: "killing", Err: err})
+}
+
+// reportSelfTest prints the outcome of each step and returns whether all of
+// them passed.
+func reportSelfTest(w io.Writer, steps []selfTestStep) bool {
+	passed := true
+	for _, s := range steps {
+		if s.Err != nil {
+			passed = false
+			fmt.Fprintf(w, "[FAIL] %s : %s\n", s.Name, s.Err)
+		} else {
+			fmt.Fprintf(w, "[PASS] %s\n", s.Name)
+		}
+	}
+	return passed
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeSelfTestSystem struct {
+	processes []runningProcess
+	released  bool
+	killWorks bool
+	listErr   error
+}
+
+func (s *fakeSelfTestSystem) List() ([]runningProcess, error) {
+	return s.processes, s.listErr
+}
+
+func (s *fakeSelfTestSystem) Kill(p runningProcess, signal string) error {
+	if s.killWorks {
+		s.processes = nil
+	}
+	return nil
+}
+
+func (s *fakeSelfTestSystem) Close(p runningProcess) error {
+	return nil
+}
+
+func (s *fakeSelfTestSystem) Suspend(p runningProcess) error {
+	return nil
+}
+
+func (s *fakeSelfTestSystem) Resume(p runningProcess) error {
+	return nil
+}
+
+func (s *fakeSelfTestSystem) providers() selfTestProviders {
+	return selfTestProviders{
+		StartProcess: func() (int, func(), error) {
+			s.processes = append(s.processes, runningProcess{Pid: 42, Path: "/usr/bin/sleep"})
+			return 42, func() { s.released = true }, nil
+		},
+		Processes:   s,
+		KillTimeout: time.Millisecond,
+	}
+}
+
+func selfTestReport(steps []selfTestStep) (bool, string) {
+	var out bytes.Buffer
+	passed := reportSelfTest(&out, steps)
+	return passed, out.String()
+}
+
+func TestSelfTestPasses(t *testing.T) {
+	system := &fakeSelfTestSystem{killWorks: true}
+	passed, report := selfTestReport(runSelfTest(system.providers()))
+
+	expected := "[PASS] launching\n[PASS] enumeration\n[PASS] matching\n[PASS] killing\n"
+	if !passed || report != expected {
+		t.Errorf("self test reported\n%s", report)
+	}
+	if !system.released {
+		t.Error("throwaway process not released")
+	}
+}
+
+func TestSelfTestReportsKillFailure(t *testing.T) {
+	system := &fakeSelfTestSystem{}
+	passed, report := selfTestReport(runSelfTest(system.providers()))
+
+	expected := "[PASS] launching\n[PASS] enumeration\n[PASS] matching\n[FAIL] killing : process 42 still running 1ms after being killed\n"
+	if passed || report != expected {
+		t.Errorf("self test reported\n%s", report)
+	}
+}
+
+func TestSelfTestReportsEnumerationFailure(t *testing.T) {
+	system := &fakeSelfTestSystem{listErr: errors.New("powershell not found")}
+	passed, report := selfTestReport(runSelfTest(system.providers()))
+
+	expected := "[PASS] launching\n[FAIL] enumeration : powershell not found\n"
+	if passed || report != expected {
+		t.Errorf("self test reported\n%s", report)
+	}
+
+	system = &fakeSelfTestSystem{}
+	providers := system.providers()
+	providers.StartProcess = func() (int, func(), error) { return 42, func() {}, nil }
+	passed, report = selfTestReport(runSelfTest(providers))
+
+	expected = "[PASS] launching\n[FAIL] enumeration : process 42 not found among 0 running processes\n"
+	if passed || report != expected {
+		t.Errorf("self test reported\n%s", report)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !windows || !svc
+
+package main
+
+import "errors"
+
+// runAsServiceIfNeeded is only able to run the controller as a service in
+// Windows builds with the svc tag, which depend on golang.org/x/sys.
+func runAsServiceIfNeeded(configFile string, stateFile string) bool {
+	return false
+}
+
+func controlService(command string, configFile string, stateFile string) error {
+	return errors.New("the Windows service is only available in Windows builds with the svc tag")
+}
@@ -0,0 +1,145 @@
+//go:build windows && svc
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const serviceName = "dad-controller"
+
+// controllerService runs the controller under the service control manager,
+// with the configuration and state files given by the flags, if any.
+type controllerService struct {
+	configFile string
+	stateFile  string
+}
+
+// runAsServiceIfNeeded runs the controller as a service and returns true
+// when the process was started by the service control manager.
+func runAsServiceIfNeeded(configFile string, stateFile string) bool {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false
+	}
+	if err := svc.Run(serviceName, controllerService{configFile: configFile, stateFile: stateFile}); err != nil {
+		fmt.Fprintln(logOutput, "Failure to run the service : ", err)
+		os.Exit(1)
+	}
+	return true
+}
+
+func (s controllerService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+
+	// services start in the system directory, the configuration and state
+	// files are next to the executable unless in ProgramData
+	if exe, err := os.Executable(); err == nil {
+		os.Chdir(filepath.Dir(exe))
+	}
+	configFile, stateFile := resolvePaths(s.configFile, s.stateFile)
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		fmt.Fprintln(logOutput, "Failure to create the state directory : ", err)
+	}
+	configFile, remote := localConfigFile(configFile, stateFile)
+	ctrl := newDadControllerWithFiles(configFile, stateFile)
+	ctrl.remote = remote
+	ctrl.reloadStateIfExist()
+	if ctrl.HTTPListen != "" {
+		go ctrl.serveHTTP(ctrl.HTTPListen)
+	}
+	if ctrl.WatchProcessStarts {
+		go ctrl.watchProcessStarts()
+	}
+	done := make(chan struct{})
+	go func() {
+		ctrl.run()
+		close(done)
+	}()
+
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for {
+		select {
+		case r := <-requests:
+			switch r.Cmd {
+			case svc.Interrogate:
+				status <- r.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				ctrl.Stop()
+				<-done
+				return false, 0
+			}
+		case <-done:
+			return false, 1
+		}
+	}
+}
+
+// controlService installs, uninstalls, starts or stops the service, which
+// runs under the SYSTEM account, starts at boot and is restarted when it
+// is killed. The service is installed with the configuration and state
+// files given, if any.
+func controlService(command string, configFile string, stateFile string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	if command == "install" {
+		exe, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		var args []string
+		for _, f := range []struct{ name, path string }{{"-config", configFile}, {"-state", stateFile}} {
+			if f.path == "" {
+				continue
+			}
+			if isRemoteConfig(f.path) {
+				args = append(args, f.name, f.path)
+				continue
+			}
+			path, err := filepath.Abs(f.path)
+			if err != nil {
+				return err
+			}
+			args = append(args, f.name, path)
+		}
+		s, err := m.CreateService(serviceName, exe, mgr.Config{
+			DisplayName: "Dad Controller",
+			Description: "Enforces the time allowed on games and applications",
+			StartType:   mgr.StartAutomatic,
+		}, args...)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: 5 * time.Second}
+		return s.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, uint32((24 * time.Hour).Seconds()))
+	}
+
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	switch command {
+	case "uninstall":
+		return s.Delete()
+	case "start":
+		return s.Start()
+	case "stop":
+		_, err := s.Control(svc.Stop)
+		return err
+	default:
+		return fmt.Errorf("unknown service command %q, expected install, uninstall, start or stop", command)
+	}
+}
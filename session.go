@@ -0,0 +1,44 @@
+package main
+
+import "fmt"
+
+// sessionController is implemented by the process providers able to lock or
+// end the session of the owner of a process.
+type sessionController interface {
+	// LockSession locks the session of the owner of p
+	LockSession(p runningProcess) error
+	// Logoff ends the session of the owner of p, closing all its programs
+	Logoff(p runningProcess) error
+}
+
+// lockSession locks the session running the processes of activity, killing
+// them instead when the session cannot be locked.
+func (c *dadController) lockSession(activity string, rp []runningProcess, reason string) {
+	fmt.Fprintf(logOutput, "Locking the session of activity %s\n", activity)
+	s, ok := c.Processes.(sessionController)
+	if !ok {
+		fmt.Fprintln(logOutput, "Sessions cannot be locked by this process provider, killing instead")
+		c.kill(activity, rp, reason)
+		return
+	}
+	if err := s.LockSession(rp[0]); err != nil {
+		fmt.Fprintf(logOutput, "Failure to lock the session of process %d, killing instead : %s\n", rp[0].Pid, err)
+		c.kill(activity, rp, reason)
+	}
+}
+
+// logoff ends the session running the processes of activity, killing them
+// instead when the session cannot be ended.
+func (c *dadController) logoff(activity string, rp []runningProcess, reason string) {
+	fmt.Fprintf(logOutput, "Logging off the session of activity %s\n", activity)
+	s, ok := c.Processes.(sessionController)
+	if !ok {
+		fmt.Fprintln(logOutput, "Sessions cannot be ended by this process provider, killing instead")
+		c.kill(activity, rp, reason)
+		return
+	}
+	if err := s.Logoff(rp[0]); err != nil {
+		fmt.Fprintf(logOutput, "Failure to log off the session of process %d, killing instead : %s\n", rp[0].Pid, err)
+		c.kill(activity, rp, reason)
+	}
+}
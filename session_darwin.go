@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// lockSession puts the display to sleep, locking the session when a password
+// is required on wake.
+func lockSession(p runningProcess) error {
+	return exec.Command("pmset", "displaysleepnow").Run()
+}
+
+// logoffSession stops the GUI domain of the owner of p, ending its session.
+func logoffSession(p runningProcess) error {
+	if p.UserID == "" {
+		return errors.New("owner of the process unknown")
+	}
+	return exec.Command("launchctl", "bootout", "gui/"+p.UserID).Run()
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// lockSession locks the graphical sessions through logind, which does not
+// lock the sessions of a single user.
+func lockSession(p runningProcess) error {
+	return exec.Command("loginctl", "lock-sessions").Run()
+}
+
+// logoffSession terminates the sessions of the owner of p through logind.
+func logoffSession(p runningProcess) error {
+	if p.UserID == "" {
+		return errors.New("owner of the process unknown")
+	}
+	return exec.Command("loginctl", "terminate-user", p.UserID).Run()
+}
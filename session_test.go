@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testRuleAction(t *testing.T, ruleAction string, sessions []string, killed int) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].Action = ruleAction
+
+	ctx.WhenScanHappens()
+	if !reflect.DeepEqual(ctx.sessions, sessions) {
+		t.Errorf("action %q: expected sessions %q, got %q", ruleAction, sessions, ctx.sessions)
+	}
+	if len(ctx.killedProcesses) != killed {
+		t.Errorf("action %q: expected %d processes killed, got %q", ruleAction, killed, ctx.killedProcesses)
+	}
+}
+
+func TestRuleActions(t *testing.T) {
+	testRuleAction(t, "", nil, 1)
+	testRuleAction(t, "kill", nil, 1)
+	testRuleAction(t, "warn", nil, 0)
+	testRuleAction(t, "lock", []string{"lock|1"}, 0)
+	testRuleAction(t, "logoff", []string{"logoff|1"}, 0)
+}
+
+func TestLockFallsBackToKillWithoutSessionSupport(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].Action = "lock"
+	ctx.controller.Processes = struct{ ProcessProvider }{ctx.controller.Processes}
+
+	ctx.WhenScanHappens()
+	if !reflect.DeepEqual(ctx.killedProcesses, []string{"1|C:\\GTA.exe"}) {
+		t.Errorf("the process should have been killed, got %q", ctx.killedProcesses)
+	}
+}
+
+func TestUnknownActionIsRejected(t *testing.T) {
+	if _, err := parseConfig([]byte(`{"rules": [{"name": "GTA", "programs": ["GTA.exe"], "action": "shutdown"}]}`)); err == nil {
+		t.Error("configuration with an unknown action should be invalid")
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	procLockWorkStation      = user32.NewProc("LockWorkStation")
+	procProcessIdToSessionId = syscall.NewLazyDLL("kernel32.dll").NewProc("ProcessIdToSessionId")
+	procWTSLogoffSession     = syscall.NewLazyDLL("wtsapi32.dll").NewProc("WTSLogoffSession")
+)
+
+// lockSession locks the session of the controller, which must run in the
+// interactive session rather than as a service.
+func lockSession(p runningProcess) error {
+	if r, _, err := procLockWorkStation.Call(); r == 0 {
+		return err
+	}
+	return nil
+}
+
+// logoffSession logs off the Remote Desktop Services session running p.
+func logoffSession(p runningProcess) error {
+	var session uint32
+	if r, _, err := procProcessIdToSessionId.Call(uintptr(p.Pid), uintptr(unsafe.Pointer(&session))); r == 0 {
+		return err
+	}
+	// WTS_CURRENT_SERVER_HANDLE, without waiting for the logoff to complete
+	if r, _, err := procWTSLogoffSession.Call(0, uintptr(session), 0); r == 0 {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// activitySession is a sitting of a user on an activity, the activity being
+// credited at every scan since Start, up to LastSeen.
+type activitySession struct {
+	Start    time.Time `json:"start"`
+	LastSeen time.Time `json:"lastSeen"`
+	// Duration is the time credited during the session
+	Duration duration `json:"duration"`
+}
+
+// trackSession extends the session of user on the activity of a when it was
+// credited at the previous scan, done at previous, or starts a new one once
+// the cooldown following the previous session has elapsed.
+func (c *dadController) trackSession(a *activityRule, user string, credited duration, previous time.Time, now time.Time) {
+	if c.Sessions == nil {
+		c.Sessions = make(map[string]*activitySession)
+	}
+	key := user + "|" + a.Name
+	s, found := c.Sessions[key]
+	if found && !s.LastSeen.Equal(previous) && now.Before(s.LastSeen.Add(time.Duration(a.Cooldown))) {
+		// still on a break, the session stays over
+		return
+	}
+	if !found || !s.LastSeen.Equal(previous) {
+		s = &activitySession{Start: now}
+		c.Sessions[key] = s
+	}
+	s.LastSeen = now
+	s.Duration += credited
+}
+
+// addSession sets the length of the session in ctx, 0 when the activity was
+// not credited at the last scan, the last session having ended then.
+func (c *dadController) addSession(ctx *decisionContext) {
+	s, found := c.Sessions[ctx.User+"|"+ctx.Activity]
+	if !found {
+		return
+	}
+	if s.LastSeen.Equal(c.LastControlTime) {
+		ctx.Session = time.Duration(s.Duration)
+	} else {
+		ctx.SessionEnd = s.LastSeen
+	}
+}
+
+func maxSessionDurationPolicy(ctx decisionContext) (action, string) {
+	if ctx.Rule != nil && ctx.Rule.MaxSessionDuration > 0 && ctx.Session > time.Duration(ctx.Rule.MaxSessionDuration) {
+		return actionKill, "Activity duration above threshold for a single session"
+	}
+	return actionNone, ""
+}
+
+func cooldownPolicy(ctx decisionContext) (action, string) {
+	if ctx.Rule == nil || ctx.Rule.Cooldown <= 0 || ctx.SessionEnd.IsZero() {
+		return actionNone, ""
+	}
+	if end := ctx.SessionEnd.Add(time.Duration(ctx.Rule.Cooldown)); ctx.Now.Before(end) {
+		return actionKill, fmt.Sprintf("Take a break, activity allowed again at %s", end.Format("15:04"))
+	}
+	return actionNone, ""
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxSessionDurationIsEnforced(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(10)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(3)*time.Hour).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].MaxSessionDuration = duration(time.Duration(30) * time.Minute)
+
+	for i := 0; i < 3; i++ {
+		ctx.WhenScanHappens().ThenNoProcessKilled()
+	}
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for a single session")
+
+	// a scan without the activity ends the session
+	ctx.GivenNoRunningProcess().
+		WhenScanHappens().
+		GivenARunningProcess("C:\\GTA.exe", 2).
+		WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenActivityExecutionDurationShouldBe("GTA", time.Duration(50)*time.Minute)
+}
+
+func TestActivityIsBlockedDuringTheCooldownFollowingASession(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(10)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(3)*time.Hour).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].MaxSessionDuration = duration(time.Duration(20) * time.Minute)
+	ctx.controller.Activities[0].Cooldown = duration(time.Duration(30) * time.Minute)
+
+	ctx.WhenScanHappens().
+		WhenScanHappens().
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for a single session")
+
+	// the session ended at 16:30, relaunching is killed until 17:00
+	ctx.GivenNoRunningProcess().
+		WhenScanHappens().
+		GivenARunningProcess("C:\\GTA.exe", 2).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 2, "C:\\GTA.exe", "Take a break, activity allowed again at 17:00").
+		GivenNoRunningProcess().
+		GivenARunningProcess("C:\\GTA.exe", 3).
+		WhenScanHappens().
+		ThenNoProcessKilled()
+}
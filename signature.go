@@ -0,0 +1,150 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// keyFileName is the file, next to the state file, holding the secret the
+// configuration is signed with. Once it exists, only a configuration signed
+// by the sign command is applied, so that a child able to edit the
+// configuration file cannot grant himself more time.
+const keyFileName = "dad-controller.key"
+
+// signatureFile returns the file holding the signature of the configuration
+// file.
+func signatureFile(configFile string) string {
+	return configFile + ".sig"
+}
+
+// keyFile returns the file holding the signing secret of the controller
+// using stateFile.
+func keyFile(stateFile string) string {
+	return filepath.Join(filepath.Dir(stateFile), keyFileName)
+}
+
+// readSigningKey returns the signing secret held by path, nil when there is
+// none.
+func readSigningKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) == 0 {
+		return nil, fmt.Errorf("invalid signing key in %s", path)
+	}
+	return key, nil
+}
+
+// refuseConfigWithoutKey drops the configuration applied without checking
+// its signature, the signing key having been in use before, and refuses any
+// other one until the key is back.
+func (c *dadController) refuseConfigWithoutKey() {
+	fmt.Fprintln(logOutput, "The configuration was signed but the signing key is missing, no configuration will be applied")
+	c.signingKey = []byte{}
+	c.ConfigSigned = true
+	c.config = config{SamplingInterval: duration(minSamplingInterval)}
+	c.confLastModTime = time.Time{}
+}
+
+// createSigningKey returns the signing secret held by path, generating it
+// first, readable by its owner only, when there is none.
+func createSigningKey(path string) ([]byte, error) {
+	key, err := readSigningKey(path)
+	if key != nil || err != nil {
+		return key, err
+	}
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// configMAC returns the HMAC-SHA256 of the configuration file data, parsed
+// into cfg, and of its rule files, so that none of them can be changed or
+// added without signing the configuration again.
+func configMAC(key []byte, data []byte, cfg *config, dir string) ([]byte, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	files, err := cfg.fragments(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		fragment, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(mac, "\x00%s\x00%d\x00", filepath.Base(file), len(fragment))
+		mac.Write(fragment)
+	}
+	return mac.Sum(nil), nil
+}
+
+// checkSignature tells whether the configuration file data, parsed into cfg,
+// is signed with the signing secret of the controller, if any.
+func (c *dadController) checkSignature(data []byte, cfg *config) error {
+	if c.signingKey == nil {
+		return nil
+	}
+	if len(c.signingKey) == 0 {
+		return fmt.Errorf("the signing key could not be read")
+	}
+	signature, err := ioutil.ReadFile(signatureFile(c.configFile))
+	if err != nil {
+		return fmt.Errorf("the configuration is not signed : %s", err)
+	}
+	expected, err := configMAC(c.signingKey, data, cfg, filepath.Dir(c.configFile))
+	if err != nil {
+		return err
+	}
+	actual, err := hex.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil || !hmac.Equal(actual, expected) {
+		return fmt.Errorf("the signature of the configuration is invalid, it must be signed again")
+	}
+	return nil
+}
+
+// signConfig checks the configuration file and signs it with the secret
+// held by keyPath, generated on the first use.
+func signConfig(w io.Writer, configFile string, keyPath string) error {
+	data, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(configFile)
+	cfg, err := parseConfigIn(data, dir)
+	if err != nil {
+		return err
+	}
+	key, err := createSigningKey(keyPath)
+	if err != nil {
+		return err
+	}
+	mac, err := configMAC(key, data, cfg, dir)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(signatureFile(configFile), []byte(hex.EncodeToString(mac)+"\n"), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Configuration signed in %s\n", signatureFile(configFile))
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOnlySignedConfigurationsAreApplied(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "dad-controller.json")
+	stateFile := filepath.Join(dir, "dad-controller.state")
+	ioutil.WriteFile(configFile, []byte(`{"rules": [{"name": "GTA", "programs": ["gta"], "allow": "mon-sun 16:00-18:00 max 1h"}]}`), 0644)
+	if err := signConfig(ioutil.Discard, configFile, keyFile(stateFile)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := newDadControllerWithFiles(configFile, stateFile)
+	if len(ctrl.Activities) != 1 || time.Duration(ctrl.Activities[0].AllowedSchedules[time.Monday].MaxDuration) != time.Hour {
+		t.Fatalf("signed configuration not applied: %+v", ctrl.Activities)
+	}
+
+	// edited without signing it again
+	later := time.Now().Add(time.Minute)
+	ioutil.WriteFile(configFile, []byte(`{"rules": [{"name": "GTA", "programs": ["gta"], "allow": "mon-sun 00:00-24:00 max 24h"}]}`), 0644)
+	os.Chtimes(configFile, later, later)
+	ctrl.reloadConfIfNeeded()
+	if time.Duration(ctrl.Activities[0].AllowedSchedules[time.Monday].MaxDuration) != time.Hour {
+		t.Error("unsigned configuration applied")
+	}
+
+	// signed by the parent afterwards
+	if err := signConfig(ioutil.Discard, configFile, keyFile(stateFile)); err != nil {
+		t.Fatal(err)
+	}
+	later = later.Add(time.Minute)
+	os.Chtimes(signatureFile(configFile), later, later)
+	ctrl.reloadConfIfNeeded()
+	if time.Duration(ctrl.Activities[0].AllowedSchedules[time.Monday].MaxDuration) != 24*time.Hour {
+		t.Error("configuration signed again not applied")
+	}
+}
+
+func TestAddedRuleFileInvalidatesTheSignature(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "dad-controller.json")
+	stateFile := filepath.Join(dir, "dad-controller.state")
+	os.Mkdir(filepath.Join(dir, "rules.d"), 0755)
+	ioutil.WriteFile(configFile, []byte(`{"configDir": "rules.d", "rules": [{"name": "GTA", "programs": ["gta"]}]}`), 0644)
+	if err := signConfig(ioutil.Discard, configFile, keyFile(stateFile)); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "rules.d", "roblox.json"), []byte(`{"name": "Roblox", "programs": ["roblox"]}`), 0644)
+
+	ctrl := newDadControllerWithFiles(configFile, stateFile)
+	if len(ctrl.Activities) != 0 {
+		t.Errorf("configuration with an unsigned rule file applied: %+v", ctrl.Activities)
+	}
+}
+
+func TestConfigurationIsRefusedOnceTheSigningKeyIsDeleted(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFile := filepath.Join(dir, "dad-controller.json")
+	stateFile := filepath.Join(dir, "dad-controller.state")
+	ioutil.WriteFile(configFile, []byte(`{"rules": [{"name": "GTA", "programs": ["gta"], "allow": "mon-sun 16:00-18:00 max 1h"}]}`), 0644)
+	if err := signConfig(ioutil.Discard, configFile, keyFile(stateFile)); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := newDadControllerWithFiles(configFile, stateFile)
+	if len(ctrl.Activities) != 1 {
+		t.Fatalf("signed configuration not applied: %+v", ctrl.Activities)
+	}
+	ctrl.dumpState()
+
+	// the signature left next to the configuration
+	os.Remove(keyFile(stateFile))
+	if ctrl := newDadControllerWithFiles(configFile, stateFile); len(ctrl.Activities) != 0 {
+		t.Errorf("configuration applied without the signing key: %+v", ctrl.Activities)
+	}
+
+	// the signature deleted as well, the state remembering it was required
+	os.Remove(signatureFile(configFile))
+	ctrl = newDadControllerWithFiles(configFile, stateFile)
+	ctrl.reloadStateIfExist()
+	if len(ctrl.Activities) != 0 {
+		t.Errorf("configuration applied once the signing key and the signature are deleted: %+v", ctrl.Activities)
+	}
+	ctrl.reloadConfIfNeeded()
+	if len(ctrl.Activities) != 0 {
+		t.Errorf("unsigned configuration applied again: %+v", ctrl.Activities)
+	}
+}
@@ -0,0 +1,196 @@
+//go:build sqlite
+
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSupported tells that state files ending in .db are SQLite databases
+// in this build.
+const sqliteSupported = true
+
+// sqliteSchema keeps, besides the state of the controller, its counters per
+// day and its process sessions, which are never forgotten so that they can
+// be queried by reporting tools while the controller runs.
+const sqliteSchema = `
+PRAGMA journal_mode = WAL;
+CREATE TABLE IF NOT EXISTS state (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS counters (
+	day TEXT NOT NULL,
+	user TEXT NOT NULL,
+	activity TEXT NOT NULL,
+	duration INTEGER NOT NULL,
+	PRIMARY KEY (day, user, activity)
+);
+CREATE TABLE IF NOT EXISTS sessions (
+	activity TEXT NOT NULL,
+	user TEXT NOT NULL,
+	path TEXT NOT NULL,
+	pid INTEGER NOT NULL,
+	start TEXT NOT NULL,
+	end TEXT NOT NULL,
+	duration INTEGER NOT NULL,
+	PRIMARY KEY (activity, user, pid, start)
+);
+CREATE TABLE IF NOT EXISTS events (
+	data TEXT NOT NULL
+);
+`
+
+// sqliteStateStore keeps the state in a SQLite database, opened when first
+// used.
+type sqliteStateStore struct {
+	path string
+	db   *sql.DB
+}
+
+func newSQLiteStateStore(path string) StateStore {
+	return &sqliteStateStore{path: path}
+}
+
+// open opens the database, creating its tables when needed.
+func (s *sqliteStateStore) open() (*sql.DB, error) {
+	if s.db != nil {
+		return s.db, nil
+	}
+	db, err := sql.Open("sqlite", s.path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.db = db
+	return db, nil
+}
+
+func (s *sqliteStateStore) Load() ([]byte, error) {
+	if !fileExists(s.path) {
+		return nil, nil
+	}
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	var data string
+	err = db.QueryRow("SELECT data FROM state WHERE id = 1").Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return []byte(data), err
+}
+
+// SaveCounters saves the state along with its counters and process
+// sessions, in one transaction.
+func (s *sqliteStateStore) SaveCounters(state []byte) error {
+	var v struct {
+		ActivityDuration     map[string]map[string]duration            `json:"activityDuration"`
+		UserActivityDuration map[string]map[string]map[string]duration `json:"userActivityDuration"`
+		ProcessSessions      []*processSession                         `json:"processSessions"`
+	}
+	if err := json.Unmarshal(state, &v); err != nil {
+		return err
+	}
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT INTO state (id, data) VALUES (1, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data", string(state)); err != nil {
+		return err
+	}
+	counter, err := tx.Prepare("INSERT INTO counters (day, user, activity, duration) VALUES (?, ?, ?, ?) ON CONFLICT (day, user, activity) DO UPDATE SET duration = excluded.duration")
+	if err != nil {
+		return err
+	}
+	defer counter.Close()
+	save := func(user string, durations map[string]map[string]duration) error {
+		for day, ad := range durations {
+			for activity, d := range ad {
+				if _, err := counter.Exec(day, user, activity, int64(d)); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := save("", v.ActivityDuration); err != nil {
+		return err
+	}
+	for user, durations := range v.UserActivityDuration {
+		if err := save(user, durations); err != nil {
+			return err
+		}
+	}
+
+	session, err := tx.Prepare("INSERT INTO sessions (activity, user, path, pid, start, end, duration) VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (activity, user, pid, start) DO UPDATE SET end = excluded.end, duration = excluded.duration")
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	for _, p := range v.ProcessSessions {
+		if _, err := session.Exec(p.Activity, p.User, p.Path, p.Pid, p.Start.Format(time.RFC3339Nano), p.End.Format(time.RFC3339Nano), int64(p.Duration)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("DELETE FROM events"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStateStore) AppendEvent(e journalEntry) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("INSERT INTO events (data) VALUES (?)", string(data))
+	return err
+}
+
+func (s *sqliteStateStore) Events() ([]journalEntry, error) {
+	if !fileExists(s.path) {
+		return nil, nil
+	}
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query("SELECT data FROM events ORDER BY rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []journalEntry
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var e journalEntry
+		if err := json.Unmarshal([]byte(data), &e); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
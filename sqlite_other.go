@@ -0,0 +1,31 @@
+//go:build !sqlite
+
+package main
+
+// sqliteSupported tells that state files can only be SQLite databases in
+// builds with the sqlite tag, which depend on modernc.org/sqlite.
+const sqliteSupported = false
+
+func newSQLiteStateStore(path string) StateStore {
+	return unsupportedStateStore{}
+}
+
+// unsupportedStateStore is the store of SQLite state files in builds
+// without the sqlite tag, failing to load or save anything.
+type unsupportedStateStore struct{}
+
+func (unsupportedStateStore) Load() ([]byte, error) {
+	return nil, errSQLiteUnsupported
+}
+
+func (unsupportedStateStore) SaveCounters(state []byte) error {
+	return errSQLiteUnsupported
+}
+
+func (unsupportedStateStore) AppendEvent(e journalEntry) error {
+	return errSQLiteUnsupported
+}
+
+func (unsupportedStateStore) Events() ([]journalEntry, error) {
+	return nil, errSQLiteUnsupported
+}
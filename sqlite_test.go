@@ -0,0 +1,44 @@
+//go:build sqlite
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStateIsSavedInASQLiteDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(2)*time.Hour).
+		GivenAnActivityDuration("GTA", time.Duration(15)*time.Minute)
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.db")
+	ctx.controller.dumpState()
+	ctx.controller.dumpState()
+
+	restarted := newDadController(time.Duration(1)*time.Minute, ctx.controller.GetTime)
+	restarted.stateFile = ctx.controller.stateFile
+	restarted.reloadStateIfExist()
+	if d := restarted.GetActivityDuration("GTA"); d != time.Duration(15)*time.Minute {
+		t.Errorf("GTA duration is %s after restart", d)
+	}
+
+	db, err := restarted.stateStore().(*sqliteStateStore).open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d int64
+	if err := db.QueryRow("SELECT duration FROM counters WHERE day = '2024-10-14' AND user = '' AND activity = 'GTA'").Scan(&d); err != nil || time.Duration(d) != 15*time.Minute {
+		t.Errorf("unexpected counter %s, %v", time.Duration(d), err)
+	}
+}
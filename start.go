@@ -0,0 +1,55 @@
+package main
+
+import "fmt"
+
+// processStartWatcher is implemented by the process providers able to
+// report process starts as they happen.
+type processStartWatcher interface {
+	// WatchStarts calls started with the pid of each process started, until
+	// watching fails
+	WatchStarts(started func(pid int)) error
+}
+
+// watchProcessStarts enforces the rules as soon as a process matching one of
+// them starts, rather than at the next scan. It returns when the provider
+// cannot watch process starts.
+func (c *dadController) watchProcessStarts() {
+	c.mu.Lock()
+	w, ok := c.Processes.(processStartWatcher)
+	c.mu.Unlock()
+	if !ok {
+		fmt.Fprintln(logOutput, "Process starts cannot be watched on this platform")
+		return
+	}
+
+	fmt.Fprintln(logOutput, "Watching process starts")
+	err := w.WatchStarts(func(pid int) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.processStarted(pid)
+	})
+	fmt.Fprintln(logOutput, "Failure to watch process starts : ", err)
+}
+
+// processStarted enforces the rules right away when the process pid matches
+// one of them.
+func (c *dadController) processStarted(pid int) {
+	processes, err := c.listProcesses()
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to list running processes : ", err)
+		return
+	}
+	p, found := findProcess(processes, pid)
+	if !found {
+		return
+	}
+	c.rememberAccounts([]runningProcess{p})
+	p = c.asActiveProfile([]runningProcess{p})[0]
+	for _, a := range c.rulesOf(p.UserID) {
+		if !a.RequirePresent && a.activeOn(c.now()) && len(a.matchingProcesses([]runningProcess{p})) > 0 {
+			fmt.Fprintf(logOutput, "Process %d (%s) of activity %s started\n", p.Pid, p.Path, a.Name)
+			c.enforceNow(processes)
+			return
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeStartWatcher reports the start of the given pids then stops watching.
+type fakeStartWatcher struct {
+	ProcessProvider
+	pids []int
+}
+
+func (w fakeStartWatcher) WatchStarts(started func(pid int)) error {
+	for _, pid := range w.pids {
+		started(pid)
+	}
+	return errors.New("no more events")
+}
+
+func TestStartedProcessIsKilledWithoutWaitingForTheNextScan(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedOnlyOnSunday("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenTimeIs(time.Date(2024, time.June, 3, 10, 0, 0, 0, time.Local)).
+		GivenARunningProcess("C:\\Windows\\notepad.exe", 1).
+		GivenARunningProcess("C:\\GTA.exe", 2)
+	ctx.controller.Processes = fakeStartWatcher{ProcessProvider: ctx.controller.Processes, pids: []int{1}}
+
+	ctx.controller.watchProcessStarts()
+	ctx.ThenNoProcessKilled()
+
+	ctx.controller.Processes = fakeStartWatcher{ProcessProvider: ctx.controller.Processes, pids: []int{2}}
+	ctx.controller.watchProcessStarts()
+	ctx.ThenProcessIsKilled("GTA", 2, "C:\\GTA.exe", "Activity not allowed to be done on this day").
+		ThenActivityExecutionDurationShouldBe("GTA", 0)
+}
+
+func TestProcessStartsAreIgnoredWhenTheProviderCannotWatchThem(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedOnlyOnSunday("GTA", "GTA.exe", time.Duration(15)*time.Minute)
+
+	ctx.controller.watchProcessStarts()
+	ctx.ThenNoProcessKilled()
+}
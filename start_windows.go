@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bufio"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// watchStartsScript prints the pid of each process started, as reported by
+// the Win32_ProcessStartTrace WMI event, which requires an elevated session.
+const watchStartsScript = `& {
+	Register-CimIndicationEvent -ClassName Win32_ProcessStartTrace -SourceIdentifier DadControllerStart -ErrorAction Stop
+	while ($true) {
+		$e = Wait-Event -SourceIdentifier DadControllerStart
+		[Console]::Out.WriteLine($e.SourceEventArgs.NewEvent.ProcessID)
+		[Console]::Out.Flush()
+		Remove-Event -EventIdentifier $e.EventIdentifier
+	}
+}`
+
+// WatchStarts subscribes to the process start WMI event through PowerShell,
+// which keeps running for as long as the controller watches.
+func (toolhelpProvider) WatchStarts(started func(pid int)) error {
+	cmd := exec.Command("powershell", "-NoProfile", "-Command", watchStartsScript)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		if pid, err := strconv.Atoi(strings.TrimSpace(scanner.Text())); err == nil {
+			started(pid)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	return cmd.Wait()
+}
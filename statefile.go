@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stateChecksumPrefix starts the last line of the state file, holding the
+// SHA-256 of the JSON state preceding it.
+const stateChecksumPrefix = "\nsha256 "
+
+var errSQLiteUnsupported = errors.New("SQLite state files require a build with the sqlite tag")
+
+// isSQLiteState tells whether the state file is a SQLite database rather
+// than a JSON file, from its extension.
+func isSQLiteState(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".db", ".sqlite":
+		return true
+	}
+	return false
+}
+
+// sealState returns the content of the state file holding the JSON state
+// data, followed by its checksum.
+func sealState(data []byte) []byte {
+	sum := sha256.Sum256(data)
+	return []byte(fmt.Sprintf("%s%s%s\n", data, stateChecksumPrefix, hex.EncodeToString(sum[:])))
+}
+
+// unsealState returns the JSON state held by the content of a state file,
+// checking its checksum. State files written before the checksum was added
+// are returned as is.
+func unsealState(content []byte) ([]byte, error) {
+	idx := bytes.LastIndex(content, []byte(stateChecksumPrefix))
+	if idx < 0 {
+		return content, nil
+	}
+	data := content[:idx]
+	expected := string(bytes.TrimSpace(content[idx+len(stateChecksumPrefix):]))
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != expected {
+		return nil, fmt.Errorf("checksum mismatch, the state file is corrupted")
+	}
+	return data, nil
+}
+
+// readState returns the JSON state held by the content of a state file,
+// failing when the state is corrupted: its checksum does not match or, for
+// state files written before the checksum was added, it cannot be parsed.
+func readState(content []byte) ([]byte, error) {
+	data, err := unsealState(content)
+	if err != nil {
+		return nil, err
+	}
+	var state dadController
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("the state cannot be parsed : %s", err)
+	}
+	return data, nil
+}
+
+// replaceFile writes data to path through a temporary file of the same
+// directory, synced before being renamed over path, so that path is never
+// left half written, even by a power cut.
+func replaceFile(path string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// fileStateStore keeps the state in a JSON file, followed by its checksum,
+// and the events in a journal next to it, one JSON line each.
+type fileStateStore struct {
+	path    string
+	journal string
+}
+
+// Load returns the state of the state file, or of its most recent copy
+// which can be read when the state file is missing or corrupted.
+func (s *fileStateStore) Load() ([]byte, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s.loadBackup(), nil
+	} else if err != nil {
+		return nil, err
+	}
+	data, err = readState(data)
+	if err != nil {
+		if backup := s.loadBackup(); backup != nil {
+			fmt.Fprintf(logOutput, "Failure to check state file, backup restored : %s\n", err)
+			return backup, nil
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// SaveCounters replaces the state file, then removes the journal whose
+// events it holds.
+func (s *fileStateStore) SaveCounters(state []byte) error {
+	if err := replaceFile(s.path, sealState(state)); err != nil {
+		return err
+	}
+	if err := os.Remove(s.journal); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *fileStateStore) AppendEvent(e journalEntry) error {
+	file, err := os.OpenFile(s.journal, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(e)
+}
+
+// Events returns the events of the journal, skipping the lines it cannot
+// parse, such as a last line cut by a crash.
+func (s *fileStateStore) Events() ([]journalEntry, error) {
+	file, err := os.Open(s.journal)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []journalEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			fmt.Fprintln(logOutput, "Failure to parse journal entry : ", err)
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCorruptedStateFileIsNotLoaded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(2)*time.Hour).
+		GivenAnActivityDuration("GTA", time.Duration(15)*time.Minute)
+	ctx.controller.stateFile = filepath.Join(dir, "dad-controller.state")
+	ctx.controller.dumpState()
+
+	restarted := newDadController(time.Duration(1)*time.Minute, ctx.controller.GetTime)
+	restarted.stateFile = ctx.controller.stateFile
+	restarted.reloadStateIfExist()
+	if d := restarted.GetActivityDuration("GTA"); d != time.Duration(15)*time.Minute {
+		t.Errorf("GTA duration is %s after restart", d)
+	}
+
+	content, _ := ioutil.ReadFile(ctx.controller.stateFile)
+	ioutil.WriteFile(ctx.controller.stateFile, bytes.Replace(content, []byte("15m0s"), []byte("10m0s"), 1), 0644)
+	corrupted := newDadController(time.Duration(1)*time.Minute, ctx.controller.GetTime)
+	corrupted.stateFile = ctx.controller.stateFile
+	if corrupted.reloadState() {
+		t.Error("corrupted state file loaded")
+	}
+}
+
+func TestStateFileWithoutChecksumIsLoaded(t *testing.T) {
+	data := []byte(`{"lastControlTime":"2024-10-14T20:00:00Z"}`)
+	if unsealed, err := unsealState(data); err != nil || !bytes.Equal(unsealed, data) {
+		t.Errorf("unexpected state %q, %v", unsealed, err)
+	}
+	if unsealed, err := unsealState(sealState(data)); err != nil || !bytes.Equal(unsealed, data) {
+		t.Errorf("unexpected sealed state %q, %v", unsealed, err)
+	}
+}
+
+func TestReplaceFileLeavesNoTemporaryFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dad-controller.state")
+	for _, content := range []string{"first", "second"} {
+		if err := replaceFile(path, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "second" {
+		t.Errorf("unexpected content %q", data)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 1 {
+		t.Errorf("unexpected files %v", files)
+	}
+}
+
+func TestStateFileKindIsToldByItsExtension(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"dad-controller.state":             false,
+		"/var/lib/dad-controller/state.db": true,
+		"C:\\ProgramData\\state.SQLITE":    true,
+		"dad-controller.db.journal":        false,
+	} {
+		if isSQLiteState(path) != expected {
+			t.Errorf("%s: expected SQLite %v", path, expected)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+const maxStatusEvents = 50
+
+type (
+	// statusEvent is something worth showing the family on the dashboard: a
+	// kill, a notification of the parent or a change made through the API.
+	statusEvent struct {
+		Time    time.Time `json:"time"`
+		Message string    `json:"message"`
+	}
+
+	activityStatus struct {
+		Activity  string   `json:"activity"`
+		User      string   `json:"user,omitempty"`
+		Used      duration `json:"used"`
+		Allowed   duration `json:"allowed"`
+		Remaining duration `json:"remaining"`
+		// Blocked tells whether the activity would be killed if started now
+		Blocked bool   `json:"blocked"`
+		Reason  string `json:"reason,omitempty"`
+	}
+
+	statusReport struct {
+		Now           time.Time        `json:"now"`
+		LockdownUntil time.Time        `json:"lockdownUntil,omitempty"`
+		Activities    []activityStatus `json:"activities"`
+		Events        []statusEvent    `json:"events"`
+	}
+)
+
+// recordEvent keeps message among the recent events, dropping the oldest
+// ones beyond maxStatusEvents.
+func (c *dadController) recordEvent(message string) {
+	c.events = append(c.events, statusEvent{Time: c.now(), Message: message})
+	if len(c.events) > maxStatusEvents {
+		c.events = c.events[len(c.events)-maxStatusEvents:]
+	}
+}
+
+// notify notifies the parent of message, recording it among the recent events.
+func (c *dadController) notify(message string) {
+	c.recordEvent(message)
+	c.NotifyParent(message)
+}
+
+// status returns the time used and remaining on each activity, whether it
+// is blocked right now and the recent events, the latest first.
+func (c *dadController) status() statusReport {
+	now := c.now()
+	report := statusReport{Now: now, LockdownUntil: c.LockdownUntil, Activities: []activityStatus{}, Events: []statusEvent{}}
+
+	users := []string{""}
+	for user := range c.UserActivityDuration {
+		users = append(users, user)
+	}
+	sort.Strings(users[1:])
+
+	for _, activity := range c.activityNames() {
+		for _, user := range users {
+			a := c.ruleOf(user, activity)
+			if a == nil || a.RequirePresent || !a.activeOn(now) {
+				continue
+			}
+			ctx := c.decisionContext(a, user, now)
+			if user != "" && ctx.Used == 0 {
+				continue
+			}
+
+			s := activityStatus{Activity: a.Name, User: user, Used: duration(ctx.Used), Allowed: duration(ctx.Allowed), Remaining: duration(ctx.remaining())}
+			decision, reason := c.decide(ctx)
+			if decision = enforcedDecision(a, decision); decision != actionAllow {
+				s.Blocked = decision != actionWarn
+				s.Reason = reason
+			}
+			report.Activities = append(report.Activities, s)
+		}
+	}
+
+	for i := len(c.events) - 1; i >= 0; i-- {
+		report.Events = append(report.Events, c.events[i])
+	}
+	return report
+}
@@ -0,0 +1,59 @@
+package main
+
+// StateStore persists the state of the controller, a JSON document holding
+// its counters among others, and the events crediting time to them in
+// between two saves of the state.
+type StateStore interface {
+	// Load returns the state last saved, nil when none was
+	Load() ([]byte, error)
+	// SaveCounters saves the state and forgets the events appended before
+	SaveCounters(state []byte) error
+	// AppendEvent records time credited since the state was last saved
+	AppendEvent(e journalEntry) error
+	// Events returns the events appended since the state was last saved
+	Events() ([]journalEntry, error)
+}
+
+// newStateStore returns the store of the state file, a SQLite database or a
+// JSON file according to its extension.
+func newStateStore(stateFile string) StateStore {
+	if isSQLiteState(stateFile) {
+		return newSQLiteStateStore(stateFile)
+	}
+	return &fileStateStore{path: stateFile, journal: stateFile + ".journal"}
+}
+
+// stateStore returns the store of the state, the one of the state file
+// unless another one was set, nil when the state is not persisted.
+func (c *dadController) stateStore() StateStore {
+	if c.Store == nil && c.stateFile != "" {
+		c.Store = newStateStore(c.stateFile)
+	}
+	return c.Store
+}
+
+// memoryStateStore keeps the state in memory, for tests and -fake-now
+// demos.
+type memoryStateStore struct {
+	state  []byte
+	events []journalEntry
+}
+
+func (s *memoryStateStore) Load() ([]byte, error) {
+	return s.state, nil
+}
+
+func (s *memoryStateStore) SaveCounters(state []byte) error {
+	s.state = append([]byte(nil), state...)
+	s.events = nil
+	return nil
+}
+
+func (s *memoryStateStore) AppendEvent(e journalEntry) error {
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *memoryStateStore) Events() ([]journalEntry, error) {
+	return s.events, nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStateIsRestoredFromTheStateStore(t *testing.T) {
+	store := &memoryStateStore{}
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 20, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(1)*time.Hour).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Store = store
+	ctx.WhenScanHappens()
+	ctx.controller.dumpState()
+	if len(store.events) != 0 {
+		t.Errorf("events kept once the state is saved: %v", store.events)
+	}
+
+	// crash before the state of the second scan is saved
+	ctx.WhenScanHappens()
+	if len(store.events) != 1 {
+		t.Fatalf("expected 1 event, got %v", store.events)
+	}
+	restarted := newDadController(time.Duration(1)*time.Minute, ctx.controller.GetTime)
+	restarted.Store = store
+	restarted.reloadStateIfExist()
+	if d := restarted.GetActivityDuration("GTA"); d != time.Duration(2)*time.Minute {
+		t.Errorf("GTA duration is %s after restart", d)
+	}
+}
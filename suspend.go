@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// suspendedProcess is a process frozen by the controller, to be resumed once
+// its activity is allowed again. It is keyed on the path too, for a reused
+// pid not to be resumed.
+type suspendedProcess struct {
+	Activity string `json:"activity"`
+	Pid      int    `json:"pid"`
+	Path     string `json:"path"`
+}
+
+// suspend freezes the processes of activity instead of killing them.
+func (c *dadController) suspend(activity string, rp []runningProcess) {
+	fmt.Fprintf(logOutput, "Suspending activity %s\n", activity)
+	for _, p := range rp {
+		fmt.Fprintf(logOutput, "Suspending process %d, %s\n", p.Pid, p.Path)
+		if err := c.Processes.Suspend(p); err != nil {
+			fmt.Fprintf(logOutput, "Failure to suspend process %d : %s\n", p.Pid, err)
+			continue
+		}
+		if !c.isSuspended(p) {
+			c.Suspended = append(c.Suspended, suspendedProcess{Activity: activity, Pid: p.Pid, Path: p.Path})
+		}
+	}
+}
+
+func (c *dadController) isSuspended(p runningProcess) bool {
+	for _, s := range c.Suspended {
+		if s.Pid == p.Pid && s.Path == p.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// resumeAllowed resumes the suspended processes whose activity is allowed
+// again at now, forgetting the ones which are no longer running.
+func (c *dadController) resumeAllowed(processes []runningProcess, now time.Time) {
+	if len(c.Suspended) == 0 {
+		return
+	}
+
+	var kept []suspendedProcess
+	frozen := make(map[string][]runningProcess)
+	for _, s := range c.Suspended {
+		for _, p := range processes {
+			if s.Pid == p.Pid && s.Path == p.Path {
+				kept = append(kept, s)
+				frozen[s.Activity] = append(frozen[s.Activity], p)
+				break
+			}
+		}
+	}
+	c.Suspended = kept
+
+	denied := make(map[int]bool)
+	for _, a := range c.controlActivities(frozen, now) {
+		if a.Action == actionSuspend {
+			for _, p := range a.Processes {
+				denied[p.Pid] = true
+			}
+		}
+	}
+
+	kept = nil
+	for _, s := range c.Suspended {
+		if denied[s.Pid] {
+			kept = append(kept, s)
+			continue
+		}
+		fmt.Fprintf(logOutput, "Resuming process %d, %s\n", s.Pid, s.Path)
+		if err := c.Processes.Resume(runningProcess{Pid: s.Pid, Path: s.Path}); err != nil {
+			fmt.Fprintf(logOutput, "Failure to resume process %d : %s\n", s.Pid, err)
+			kept = append(kept, s)
+			continue
+		}
+		c.recordEvent(fmt.Sprintf("%s resumed", s.Activity))
+	}
+	c.Suspended = kept
+}
@@ -0,0 +1,70 @@
+package main
+
+import "fmt"
+
+// expandTemplates replaces the schedules of the rules referencing a template
+// by the template, overridden by what they set themselves. Templates may
+// reference other templates.
+func (cfg *config) expandTemplates() error {
+	for _, a := range cfg.allRules() {
+		schedules := []*schedule{a.HolidaySchedule, a.DefaultSchedule}
+		for _, s := range a.AllowedSchedules {
+			schedules = append(schedules, s)
+		}
+		for _, s := range a.Overrides {
+			schedules = append(schedules, s)
+		}
+		for _, s := range schedules {
+			if s == nil || s.Template == "" {
+				continue
+			}
+			if err := cfg.expandTemplate(s, nil); err != nil {
+				return fmt.Errorf("invalid schedule of activity [%s] : %s", a.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// expandTemplate merges into s the template it references, seen being the
+// templates being expanded.
+func (cfg *config) expandTemplate(s *schedule, seen []string) error {
+	name := s.Template
+	for _, n := range seen {
+		if n == name {
+			return fmt.Errorf("template %q references itself", name)
+		}
+	}
+	template, found := cfg.Templates[name]
+	if !found {
+		return fmt.Errorf("unknown template %q", name)
+	}
+
+	base := *template
+	if base.Template != "" {
+		if err := cfg.expandTemplate(&base, append(seen, name)); err != nil {
+			return err
+		}
+	}
+	if len(s.AllowedPeriods) > 0 {
+		base.AllowedPeriods = s.AllowedPeriods
+	}
+	if s.MaxDuration > 0 {
+		base.MaxDuration = s.MaxDuration
+	}
+	if s.SpendableWindow != nil {
+		base.SpendableWindow = s.SpendableWindow
+	}
+	if len(s.DenyPeriods) > 0 {
+		base.DenyPeriods = s.DenyPeriods
+	}
+	if len(s.WarnBefore) > 0 {
+		base.WarnBefore = s.WarnBefore
+	}
+	// the schedules based on the same template must not share its periods
+	base.AllowedPeriods = append([]timePeriod(nil), base.AllowedPeriods...)
+	base.DenyPeriods = append([]timePeriod(nil), base.DenyPeriods...)
+	base.Template = ""
+	*s = base
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedulesAreBasedOnTemplates(t *testing.T) {
+	cfg, err := parseConfig([]byte(`{
+		"templates": {
+			"schoolNight": {"allowedPeriods": [{"begin": "17:00", "end": "20:00"}], "maxDuration": "1h"},
+			"wednesday": {"template": "schoolNight", "maxDuration": "2h"}
+		},
+		"rules": [{
+			"name": "GTA",
+			"programs": ["GTA.exe"],
+			"schedules": {
+				"1": {"template": "schoolNight"},
+				"2": {"template": "schoolNight", "maxDuration": "30m"},
+				"3": {"template": "wednesday"}
+			}
+		}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schedules := cfg.Activities[0].AllowedSchedules
+	for day, expected := range map[time.Weekday]time.Duration{time.Monday: time.Hour, time.Tuesday: 30 * time.Minute, time.Wednesday: 2 * time.Hour} {
+		s := schedules[day]
+		if time.Duration(s.MaxDuration) != expected || len(s.AllowedPeriods) != 1 || s.AllowedPeriods[0] != (timePeriod{Begin: 1700, End: 2000}) || s.Template != "" {
+			t.Errorf("%s schedule is %+v", day, s)
+		}
+	}
+}
+
+func TestInvalidTemplatesAreRejected(t *testing.T) {
+	for _, invalid := range []string{
+		`{"rules": [{"name": "GTA", "programs": ["GTA.exe"], "schedules": {"1": {"template": "weekend"}}}]}`,
+		`{"templates": {"a": {"template": "b"}, "b": {"template": "a"}}, "rules": [{"name": "GTA", "programs": ["GTA.exe"], "schedules": {"1": {"template": "a"}}}]}`,
+	} {
+		if _, err := parseConfig([]byte(invalid)); err == nil {
+			t.Errorf("%s should be invalid", invalid)
+		}
+	}
+}
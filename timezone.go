@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	// the time zone database may be missing, on Windows in particular
+	_ "time/tzdata"
+)
+
+// loadTimeZone resolves the time zone of the configuration, the local one
+// when it has none.
+func (cfg *config) loadTimeZone() error {
+	cfg.location = nil
+	if cfg.TimeZone == "" {
+		return nil
+	}
+	loc, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		return fmt.Errorf("unknown timeZone %q, expected a zone such as Europe/Paris : %s", cfg.TimeZone, err)
+	}
+	cfg.location = loc
+	return nil
+}
+
+// now returns the current time in the time zone of the configuration, which
+// schedules are evaluated in.
+func (c *dadController) now() time.Time {
+	if c.location != nil {
+		return c.GetTime().In(c.location)
+	}
+	return c.GetTime()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func testTimeZone(t *testing.T, now time.Time, killed bool) {
+	ctx := NewTest(t).
+		GivenTimeIs(now).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryDayOnInterval("GTA", "GTA.exe", time.Duration(1)*time.Hour, 2000, 2100).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.TimeZone = "America/New_York"
+	if err := ctx.controller.loadTimeZone(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.WhenScanHappens()
+	if (len(ctx.killedProcesses) > 0) != killed {
+		t.Errorf("at %s: expected killed %t, got %q", now, killed, ctx.killedProcesses)
+	}
+}
+
+func TestSchedulesAreEvaluatedInTheConfiguredTimeZone(t *testing.T) {
+	// 20:30 and 19:30 in New York
+	testTimeZone(t, time.Date(2024, time.October, 15, 0, 30, 0, 0, time.UTC), false)
+	testTimeZone(t, time.Date(2024, time.October, 14, 23, 30, 0, 0, time.UTC), true)
+}
+
+func TestUnknownTimeZoneIsRejected(t *testing.T) {
+	if _, err := parseConfig([]byte(`{"timeZone": "Europe/Atlantis"}`)); err == nil {
+		t.Error("Europe/Atlantis should not be a time zone")
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import "fmt"
+
+// windowTitleLister is implemented by the process providers able to
+// enumerate the windows of the desktop.
+type windowTitleLister interface {
+	// WindowTitles returns the titles of the visible windows per pid
+	WindowTitles() (map[int][]string, error)
+}
+
+// foregroundReporter is implemented by the process providers able to tell
+// which process owns the foreground window.
+type foregroundReporter interface {
+	ForegroundProcess() (int, error)
+}
+
+// listProcesses lists the running processes along with the titles of their
+// windows and which one is in the foreground, when the provider can tell.
+func (c *dadController) listProcesses() ([]runningProcess, error) {
+	processes, err := c.Processes.List()
+	if err != nil {
+		return nil, err
+	}
+	c.addWindowTitles(processes)
+	c.identifyExecutables(processes)
+	c.foregroundKnown = c.markForeground(processes)
+	return processes, nil
+}
+
+func (c *dadController) addWindowTitles(processes []runningProcess) {
+	w, ok := c.Processes.(windowTitleLister)
+	if !ok {
+		return
+	}
+	titles, err := w.WindowTitles()
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to list window titles : ", err)
+		return
+	}
+	for i := range processes {
+		if processes[i].Distribution == "" {
+			processes[i].WindowTitles = titles[processes[i].Pid]
+		}
+	}
+}
+
+// markForeground flags the process owning the foreground window, returning
+// false when it is unknown.
+func (c *dadController) markForeground(processes []runningProcess) bool {
+	r, ok := c.Processes.(foregroundReporter)
+	if !ok {
+		return false
+	}
+	pid, err := r.ForegroundProcess()
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to find the foreground window : ", err)
+		return false
+	}
+	for i := range processes {
+		processes[i].Foreground = processes[i].Pid == pid && processes[i].Distribution == ""
+	}
+	return true
+}
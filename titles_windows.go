@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	user32                       = syscall.NewLazyDLL("user32.dll")
+	procEnumWindows              = user32.NewProc("EnumWindows")
+	procIsWindowVisible          = user32.NewProc("IsWindowVisible")
+	procGetWindowTextLength      = user32.NewProc("GetWindowTextLengthW")
+	procGetWindowText            = user32.NewProc("GetWindowTextW")
+	procGetWindowThreadProcessID = user32.NewProc("GetWindowThreadProcessId")
+	procPostMessage              = user32.NewProc("PostMessageW")
+	procGetForegroundWindow      = user32.NewProc("GetForegroundWindow")
+
+	// callbacks are never released, a single one is created and calls the
+	// function of the enumeration in progress
+	enumWindowsMu       sync.Mutex
+	enumWindowsFunc     func(hwnd syscall.Handle)
+	enumWindowsCallback = syscall.NewCallback(func(hwnd syscall.Handle, _ uintptr) uintptr {
+		enumWindowsFunc(hwnd)
+		return 1
+	})
+)
+
+// enumWindows calls f with each top-level window of the desktop of the
+// controller, so the windows of the other sessions are not enumerated.
+func enumWindows(f func(hwnd syscall.Handle)) error {
+	enumWindowsMu.Lock()
+	defer enumWindowsMu.Unlock()
+	enumWindowsFunc = f
+	defer func() { enumWindowsFunc = nil }()
+
+	if r, _, err := procEnumWindows.Call(enumWindowsCallback, 0); r == 0 {
+		return err
+	}
+	return nil
+}
+
+func windowProcess(hwnd syscall.Handle) int {
+	var pid uint32
+	procGetWindowThreadProcessID.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&pid)))
+	return int(pid)
+}
+
+// WindowTitles lists the titles of the visible top-level windows.
+func (toolhelpProvider) WindowTitles() (map[int][]string, error) {
+	titles := make(map[int][]string)
+	err := enumWindows(func(hwnd syscall.Handle) {
+		if visible, _, _ := procIsWindowVisible.Call(uintptr(hwnd)); visible == 0 {
+			return
+		}
+		length, _, _ := procGetWindowTextLength.Call(uintptr(hwnd))
+		if length == 0 {
+			return
+		}
+		buf := make([]uint16, length+1)
+		n, _, _ := procGetWindowText.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+		if n == 0 {
+			return
+		}
+		pid := windowProcess(hwnd)
+		titles[pid] = append(titles[pid], syscall.UTF16ToString(buf[:n]))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return titles, nil
+}
+
+// ForegroundProcess returns the pid of the process owning the foreground
+// window of the desktop of the controller, which has none when it runs as a
+// service.
+func (toolhelpProvider) ForegroundProcess() (int, error) {
+	hwnd, _, _ := procGetForegroundWindow.Call()
+	if hwnd == 0 {
+		return 0, errors.New("no foreground window")
+	}
+	return windowProcess(syscall.Handle(hwnd)), nil
+}
@@ -0,0 +1,36 @@
+package main
+
+// descendants returns the processes descending from roots, parents before
+// their children. A process started before its parent is not its child but
+// reuses the pid of a child which exited.
+func descendants(processes []runningProcess, roots []runningProcess) []runningProcess {
+	children := make(map[int][]runningProcess)
+	for _, p := range processes {
+		if p.ParentPid != 0 && p.ParentPid != p.Pid {
+			children[p.ParentPid] = append(children[p.ParentPid], p)
+		}
+	}
+
+	seen := make(map[int]bool)
+	for _, p := range roots {
+		seen[p.Pid] = true
+	}
+	var results []runningProcess
+	queue := append([]runningProcess(nil), roots...)
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, child := range children[parent.Pid] {
+			if seen[child.Pid] {
+				continue
+			}
+			if !parent.StartTime.IsZero() && !child.StartTime.IsZero() && child.StartTime.Before(parent.StartTime) {
+				continue
+			}
+			seen[child.Pid] = true
+			results = append(results, child)
+			queue = append(queue, child)
+		}
+	}
+	return results
+}
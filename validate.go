@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// configErrors are the problems found in a configuration, one per line.
+type configErrors []error
+
+func (e configErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// locateJSONError tells where in data the error returned by json.Unmarshal
+// happened, for the syntax errors and values of the wrong type.
+func locateJSONError(data []byte, err error) error {
+	var offset int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return err
+	}
+	// the error happened after reading the offending byte
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset > 0 {
+		offset--
+	}
+	before := string(data[:offset])
+	line := 1 + strings.Count(before, "\n")
+	column := len(before) - strings.LastIndex(before, "\n")
+	return fmt.Errorf("line %d, column %d : %s", line, column, err)
+}
+
+// validate returns the problems of the configuration the parsing let
+// through, each one prefixed with where it is, e.g.
+// rules[0] (GTA) : schedules.monday.maxDuration.
+func (cfg *config) validate() []error {
+	var problems []error
+	report := func(location string, format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf("%s : %s", location, fmt.Sprintf(format, args...)))
+	}
+
+	if len(cfg.allRules()) == 0 && cfg.Curfew == nil && cfg.Allowlist == nil {
+		report("rules", "no rule is defined")
+	}
+	durations := map[string]duration{
+		"maxCreditedInterval": cfg.MaxCreditedInterval,
+		"idleTimeout":         cfg.IdleTimeout,
+	}
+	for _, name := range sortedDurationNames(durations) {
+		if d := durations[name]; d < 0 {
+			report(name, "negative duration %s", time.Duration(d))
+		}
+	}
+
+	validateRules := func(location string, rules []*activityRule) {
+		for i, a := range rules {
+			if a.source != "" {
+				a.validate(fmt.Sprintf("%s (%s)", a.source, a.Name), report)
+				continue
+			}
+			a.validate(fmt.Sprintf("%s[%d] (%s)", location, i, a.Name), report)
+		}
+	}
+	validateRules("rules", cfg.Activities)
+	for _, account := range sortedProfiles(cfg.Users) {
+		validateRules(fmt.Sprintf("users.%s.rules", account), cfg.Users[account].Rules)
+	}
+	for _, name := range sortedProfiles(cfg.Profiles) {
+		validateRules(fmt.Sprintf("profiles.%s.rules", name), cfg.Profiles[name].Rules)
+	}
+	return problems
+}
+
+func (a *activityRule) validate(location string, report func(location string, format string, args ...interface{})) {
+	if a.Name == "" {
+		report(location, "the rule has no name")
+	}
+	if len(a.ProcessPatterns) == 0 && len(a.TitlePatterns) == 0 && len(a.Hashes) == 0 && len(a.Publishers) == 0 {
+		report(location, "no programs, titlePatterns, hashes nor publishers to match processes with")
+	}
+	durations := map[string]duration{
+		"maxWeeklyDuration":  a.MaxWeeklyDuration,
+		"maxMonthlyDuration": a.MaxMonthlyDuration,
+		"gracePeriod":        a.GracePeriod,
+		"maxSessionDuration": a.MaxSessionDuration,
+		"cooldown":           a.Cooldown,
+		"rolloverCap":        a.RolloverCap,
+		"closeTimeout":       a.CloseTimeout,
+	}
+	for _, name := range sortedDurationNames(durations) {
+		if d := durations[name]; d < 0 {
+			report(location, "%s : negative duration %s", name, time.Duration(d))
+		}
+	}
+
+	days := make([]int, 0, len(a.AllowedSchedules))
+	for day := range a.AllowedSchedules {
+		days = append(days, int(day))
+	}
+	sort.Ints(days)
+	for _, day := range days {
+		if day < 0 || day >= len(weekdayNames) {
+			report(location, "schedules : unknown weekday %d", day)
+			continue
+		}
+		a.AllowedSchedules[time.Weekday(day)].validate(location+" : schedules."+weekdayNames[day], report)
+	}
+	if a.HolidaySchedule != nil {
+		a.HolidaySchedule.validate(location+" : holidaySchedule", report)
+	}
+	if a.DefaultSchedule != nil {
+		a.DefaultSchedule.validate(location+" : defaultSchedule", report)
+	}
+	dates := make([]string, 0, len(a.Overrides))
+	for date := range a.Overrides {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	for _, date := range dates {
+		a.Overrides[date].validate(location+" : overrides."+date, report)
+	}
+}
+
+func (s *schedule) validate(location string, report func(location string, format string, args ...interface{})) {
+	if s == nil {
+		return
+	}
+	if s.MaxDuration < 0 {
+		report(location, "maxDuration : negative duration %s", time.Duration(s.MaxDuration))
+	}
+	validatePeriods := func(name string, periods []timePeriod) {
+		for i, p := range periods {
+			p.validate(fmt.Sprintf("%s : %s[%d]", location, name, i), report)
+		}
+	}
+	validatePeriods("allowedPeriods", s.AllowedPeriods)
+	validatePeriods("denyPeriods", s.DenyPeriods)
+	if s.SpendableWindow != nil {
+		s.SpendableWindow.validate(location+" : spendableWindow", report)
+	}
+	for i, d := range s.WarnBefore {
+		if d <= 0 {
+			report(location, "warnBefore[%d] : %s is not a positive duration", i, time.Duration(d))
+		}
+	}
+}
+
+func (p timePeriod) validate(location string, report func(location string, format string, args ...interface{})) {
+	if p.Begin == p.End {
+		report(location, "the period begins and ends at %02d:%02d", p.Begin/100, p.Begin%100)
+	}
+	if p.MaxDuration < 0 {
+		report(location, "maxDuration : negative duration %s", time.Duration(p.MaxDuration))
+	}
+}
+
+func sortedDurationNames(durations map[string]duration) []string {
+	names := make([]string, 0, len(durations))
+	for name := range durations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
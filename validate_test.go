@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEveryProblemOfTheConfigurationIsReportedWithItsLocation(t *testing.T) {
+	_, err := parseConfig([]byte(`{"rules": [
+		{"name": "GTA", "programs": ["GTA.exe"], "cooldown": "-5m", "schedules": {
+			"monday": {"maxDuration": "-1h", "allowedPeriods": [{"begin": "20:00", "end": "20:00"}]}
+		}},
+		{"programs": []}
+	]}`))
+	problems, ok := err.(configErrors)
+	if !ok {
+		t.Fatalf("expected the list of problems, got %v", err)
+	}
+	expected := []string{
+		"rules[0] (GTA) : cooldown : negative duration -5m0s",
+		"rules[0] (GTA) : schedules.monday : maxDuration : negative duration -1h0m0s",
+		"rules[0] (GTA) : schedules.monday : allowedPeriods[0] : the period begins and ends at 20:00",
+		"rules[1] () : the rule has no name",
+		"rules[1] () : no programs, titlePatterns, hashes nor publishers to match processes with",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("problems are:\n%s", err)
+	}
+	for i, problem := range problems {
+		if problem.Error() != expected[i] {
+			t.Errorf("problem %d is %q (expected %q)", i, problem, expected[i])
+		}
+	}
+}
+
+func TestConfigurationWithoutRulesIsInvalid(t *testing.T) {
+	if _, err := parseConfig([]byte(`{"samplingInterval": "1m"}`)); err == nil || !strings.Contains(err.Error(), "no rule is defined") {
+		t.Errorf("expected the lack of rules to be reported, got %v", err)
+	}
+}
+
+func TestSyntaxErrorIsReportedWithItsLine(t *testing.T) {
+	_, err := parseConfig([]byte("{\n  \"rules\": [\n    {\"name\": \"GTA\",}\n  ]\n}"))
+	if err == nil || !strings.Contains(err.Error(), "line 3, column 20") {
+		t.Errorf("expected the line of the error, got %v", err)
+	}
+}
+
+func TestInvalidConfigurationDoesNotReplaceTheCurrentOne(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dad-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "dad-controller.json")
+	if err := ioutil.WriteFile(configFile, []byte(`{"samplingInterval": "1h", "rules": [{"name": "GTA", "programs": ["gta"]}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctrl := newDadControllerWithConfigFile(configFile)
+
+	if err := ioutil.WriteFile(configFile, []byte(`{"samplingInterval": "1h", "rules": [{"name": "GTA", "programs": ["gta"]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(configFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	ctrl.reloadConfIfNeeded()
+
+	if len(ctrl.Activities) != 1 || ctrl.Activities[0].Name != "GTA" {
+		t.Errorf("truncated configuration should not replace the current one, got %v", ctrl.Activities)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// activeOn tells whether the rule applies on the day of date, being enabled
+// and within its validity dates.
+func (a *activityRule) activeOn(date time.Time) bool {
+	if !a.enabled() {
+		return false
+	}
+	day := date.Format("2006-01-02")
+	return (a.ValidFrom == "" || day >= a.ValidFrom) && (a.ValidUntil == "" || day <= a.ValidUntil)
+}
+
+// checkValidity checks the validity dates of the rule.
+func (a *activityRule) checkValidity() error {
+	var from, until time.Time
+	for _, d := range []struct {
+		name  string
+		value string
+		date  *time.Time
+	}{{"validFrom", a.ValidFrom, &from}, {"validUntil", a.ValidUntil, &until}} {
+		if d.value == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", d.value)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q for activity [%s], expected a date such as 2024-12-24", d.name, d.value, a.Name)
+		}
+		*d.date = date
+	}
+	if !from.IsZero() && !until.IsZero() && until.Before(from) {
+		return fmt.Errorf("activity [%s] is valid until %s, before it is valid from %s", a.Name, a.ValidUntil, a.ValidFrom)
+	}
+	return nil
+}
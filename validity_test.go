@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func testRuleValidity(t *testing.T, validFrom string, validUntil string, killed bool) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].ValidFrom = validFrom
+	ctx.controller.Activities[0].ValidUntil = validUntil
+
+	ctx.WhenScanHappens()
+	if (len(ctx.killedProcesses) > 0) != killed {
+		t.Errorf("valid from %q until %q: expected killed %t, got %q", validFrom, validUntil, killed, ctx.killedProcesses)
+	}
+}
+
+func TestRuleAppliesWithinItsValidityDates(t *testing.T) {
+	testRuleValidity(t, "", "", true)
+	testRuleValidity(t, "2024-10-14", "2024-10-14", true)
+	testRuleValidity(t, "2024-10-01", "", true)
+	testRuleValidity(t, "2024-10-15", "", false)
+	testRuleValidity(t, "", "2024-10-13", false)
+}
+
+func TestInvalidValidityDatesAreRejected(t *testing.T) {
+	for _, invalid := range []string{
+		`{"rules": [{"name": "GTA", "programs": ["GTA.exe"], "validFrom": "friday"}]}`,
+		`{"rules": [{"name": "GTA", "programs": ["GTA.exe"], "validFrom": "2024-10-18", "validUntil": "2024-10-14"}]}`,
+	} {
+		if _, err := parseConfig([]byte(invalid)); err == nil {
+			t.Errorf("%s should be invalid", invalid)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const defaultMaxWeeklyViolations = 3
+
+// weeklyViolations counts the days of an ISO week on which a warn only rule
+// was violated.
+type weeklyViolations struct {
+	Week     string `json:"week"`
+	LastDay  string `json:"lastDay"`
+	Days     int    `json:"days"`
+	Notified bool   `json:"notified,omitempty"`
+}
+
+func (a *activityRule) maxWeeklyViolations() int {
+	if a.MaxWeeklyViolations <= 0 {
+		return defaultMaxWeeklyViolations
+	}
+	return a.MaxWeeklyViolations
+}
+
+// recordViolation counts the violation by user of the rule of activity at
+// now, once per day, and notifies the parent the first time in the week it
+// has been violated on more than its maximum number of days.
+func (c *dadController) recordViolation(user string, activity string, now time.Time) {
+	year, week := now.ISOWeek()
+	weekKey := fmt.Sprintf("%d-W%02d", year, week)
+	day := now.Format("2006-01-02")
+
+	if c.Violations == nil {
+		c.Violations = make(map[string]*weeklyViolations)
+	}
+	v, found := c.Violations[activity]
+	if !found || v.Week != weekKey {
+		v = &weeklyViolations{Week: weekKey}
+		c.Violations[activity] = v
+	}
+	if v.LastDay == day {
+		return
+	}
+	v.LastDay = day
+	v.Days++
+	fmt.Fprintf(logOutput, "%s violated on %d days of week %s\n", activity, v.Days, weekKey)
+
+	a := c.ruleOf(user, activity)
+	if a != nil && !v.Notified && v.Days > a.maxWeeklyViolations() {
+		v.Notified = true
+		c.notify(fmt.Sprintf("%s exceeded its limits on %d days this week", activity, v.Days))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func (ctx *TestContext) WhenDayIsSpentOverLimit(day time.Time) *TestContext {
+	return ctx.GivenTimeIs(day).
+		WhenScanHappens().
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		WhenScanHappens().
+		ThenNoProcessKilled().
+		WhenScanHappens().
+		ThenNoProcessKilled()
+}
+
+func TestWarnOnlyRuleNotifiesOncePerWeekAboveItsThreshold(t *testing.T) {
+	monday := time.Date(2024, time.June, 3, 10, 0, 0, 0, time.Local)
+	ctx := NewTest(t).
+		GivenTimeIs(monday).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ar := ctx.controller.getOrCreateActivityRule("GTA")
+	ar.WarnOnly = true
+	ar.MaxWeeklyViolations = 2
+
+	ctx.WhenDayIsSpentOverLimit(monday).
+		WhenDayIsSpentOverLimit(monday.AddDate(0, 0, 1)).
+		ThenParentShouldHaveBeenNotified().
+		WhenDayIsSpentOverLimit(monday.AddDate(0, 0, 2)).
+		ThenParentShouldHaveBeenNotified("GTA exceeded its limits on 3 days this week").
+		WhenDayIsSpentOverLimit(monday.AddDate(0, 0, 3)).
+		ThenParentShouldHaveBeenNotified("GTA exceeded its limits on 3 days this week")
+
+	// counting starts over the next week
+	ctx.notifications = nil
+	ctx.WhenDayIsSpentOverLimit(monday.AddDate(0, 0, 7)).
+		WhenDayIsSpentOverLimit(monday.AddDate(0, 0, 8)).
+		ThenParentShouldHaveBeenNotified().
+		WhenDayIsSpentOverLimit(monday.AddDate(0, 0, 9)).
+		ThenParentShouldHaveBeenNotified("GTA exceeded its limits on 3 days this week")
+}
+
+func TestWarnOnlyRuleDecidesToWarn(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(15)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(20)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.getOrCreateActivityRule("GTA").WarnOnly = true
+
+	ctx.WhenScanHappens().
+		ThenNoProcessKilled().
+		ThenActionsShouldBe(enforcementAction{
+			Activity:  "GTA",
+			Processes: []runningProcess{{Pid: 1, Path: "C:\\GTA.exe"}},
+			Action:    actionWarn,
+			Reason:    "Activity duration above threshold for this day",
+		})
+}
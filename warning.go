@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// remaining returns the time left on the activity before one of its maximum
+// durations is reached.
+func (ctx decisionContext) remaining() time.Duration {
+	remaining := ctx.Allowed - ctx.Used
+	if ctx.Pool != nil {
+		remaining = minDuration(remaining, time.Duration(ctx.Pool.MaxDuration)-ctx.PoolUsed)
+	}
+	if ctx.Rule != nil && ctx.Rule.MaxWeeklyDuration > 0 {
+		remaining = minDuration(remaining, time.Duration(ctx.Rule.MaxWeeklyDuration)-ctx.WeekUsed)
+	}
+	if ctx.Rule != nil && ctx.Rule.MaxMonthlyDuration > 0 {
+		remaining = minDuration(remaining, time.Duration(ctx.Rule.MaxMonthlyDuration)-ctx.MonthUsed)
+	}
+	if ctx.Rule != nil && ctx.Rule.MaxSessionDuration > 0 {
+		remaining = minDuration(remaining, time.Duration(ctx.Rule.MaxSessionDuration)-ctx.Session)
+	}
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+func minDuration(d1 time.Duration, d2 time.Duration) time.Duration {
+	if d1 < d2 {
+		return d1
+	}
+	return d2
+}
+
+// reminder returns the action warning the user of the allowed activity in
+// ctx that it is about to be stopped, once the time left has fallen below
+// one of the WarnBefore durations of the schedule not warned about yet.
+func (c *dadController) reminder(ctx decisionContext) (enforcementAction, bool) {
+	if ctx.Schedule == nil || len(ctx.Schedule.WarnBefore) == 0 {
+		return enforcementAction{}, false
+	}
+	remaining := ctx.remaining()
+	if remaining <= 0 {
+		return enforcementAction{}, false
+	}
+
+	var threshold time.Duration
+	for _, before := range ctx.Schedule.WarnBefore {
+		if d := time.Duration(before); remaining <= d && (threshold == 0 || d < threshold) {
+			threshold = d
+		}
+	}
+	if warned, found := c.warned[ctx.User+"|"+ctx.Activity]; threshold == 0 || (found && warned <= threshold) {
+		return enforcementAction{}, false
+	}
+	return enforcementAction{
+		Activity:  ctx.Activity,
+		User:      ctx.User,
+		Processes: ctx.Processes,
+		Action:    actionRemind,
+		Reason:    fmt.Sprintf("%s left before %s is stopped", remaining.Round(time.Second), ctx.Activity),
+		threshold: threshold,
+	}, true
+}
+
+// remind warns the user through WarnAboutKill, remembering it was warned for
+// the threshold of a until the end of the day.
+func (c *dadController) remind(a enforcementAction) {
+	if c.warned == nil {
+		c.warned = make(map[string]time.Duration)
+	}
+	c.warned[a.User+"|"+a.Activity] = a.threshold
+	c.recordEvent(fmt.Sprintf("%s warned : %s", a.Activity, a.Reason))
+	c.WarnAboutKill(a.Activity, a.Processes, a.Reason)
+}
+
+// overLimit returns by how much the most exceeded maximum duration of the
+// activity is exceeded, 0 when none is.
+func (ctx decisionContext) overLimit() time.Duration {
+	over := ctx.Used - ctx.Allowed
+	if ctx.Pool != nil && ctx.PoolUsed-time.Duration(ctx.Pool.MaxDuration) > over {
+		over = ctx.PoolUsed - time.Duration(ctx.Pool.MaxDuration)
+	}
+	if ctx.Rule != nil && ctx.Rule.MaxWeeklyDuration > 0 && ctx.WeekUsed-time.Duration(ctx.Rule.MaxWeeklyDuration) > over {
+		over = ctx.WeekUsed - time.Duration(ctx.Rule.MaxWeeklyDuration)
+	}
+	if ctx.Rule != nil && ctx.Rule.MaxMonthlyDuration > 0 && ctx.MonthUsed-time.Duration(ctx.Rule.MaxMonthlyDuration) > over {
+		over = ctx.MonthUsed - time.Duration(ctx.Rule.MaxMonthlyDuration)
+	}
+	if ctx.Rule != nil && ctx.Rule.MaxSessionDuration > 0 && ctx.Session-time.Duration(ctx.Rule.MaxSessionDuration) > over {
+		over = ctx.Session - time.Duration(ctx.Rule.MaxSessionDuration)
+	}
+	if over < 0 {
+		return 0
+	}
+	return over
+}
+
+// withinLimits returns ctx as if no maximum duration was exceeded.
+func (ctx decisionContext) withinLimits() decisionContext {
+	ctx.Used = minDuration(ctx.Used, ctx.Allowed)
+	if ctx.Pool != nil {
+		ctx.PoolUsed = minDuration(ctx.PoolUsed, time.Duration(ctx.Pool.MaxDuration))
+	}
+	if ctx.Rule != nil && ctx.Rule.MaxWeeklyDuration > 0 {
+		ctx.WeekUsed = minDuration(ctx.WeekUsed, time.Duration(ctx.Rule.MaxWeeklyDuration))
+	}
+	if ctx.Rule != nil && ctx.Rule.MaxMonthlyDuration > 0 {
+		ctx.MonthUsed = minDuration(ctx.MonthUsed, time.Duration(ctx.Rule.MaxMonthlyDuration))
+	}
+	if ctx.Rule != nil && ctx.Rule.MaxSessionDuration > 0 {
+		ctx.Session = minDuration(ctx.Session, time.Duration(ctx.Rule.MaxSessionDuration))
+	}
+	return ctx
+}
+
+// graceReminder returns the action warning the user that the activity in
+// ctx, killed for exceeding its maximum durations, will be stopped at the
+// end of the grace period of its rule, as long as it has not ended.
+func (c *dadController) graceReminder(ctx decisionContext) (enforcementAction, bool) {
+	if ctx.Rule == nil || ctx.Rule.GracePeriod <= 0 {
+		return enforcementAction{}, false
+	}
+	over := ctx.overLimit()
+	if over <= 0 || over >= time.Duration(ctx.Rule.GracePeriod) {
+		return enforcementAction{}, false
+	}
+	if decision, _ := c.decide(ctx.withinLimits()); decision == actionKill {
+		// killed for another reason than its maximum durations
+		return enforcementAction{}, false
+	}
+	return enforcementAction{
+		Activity:  ctx.Activity,
+		User:      ctx.User,
+		Processes: ctx.Processes,
+		Action:    actionRemind,
+		Reason:    fmt.Sprintf("Time is up, %s is stopped in %s", ctx.Activity, (time.Duration(ctx.Rule.GracePeriod) - over).Round(time.Second)),
+	}, true
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUserIsWarnedBeforeTheLimit(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(30)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(13)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	for _, s := range ctx.controller.Activities[0].AllowedSchedules {
+		s.WarnBefore = []duration{duration(time.Duration(15) * time.Minute), duration(time.Duration(5) * time.Minute), duration(time.Duration(1) * time.Minute)}
+	}
+	var warnings []string
+	ctx.controller.WarnAboutKill = func(activity string, rp []runningProcess, reason string) {
+		warnings = append(warnings, reason)
+	}
+
+	ctx.WhenScanHappens().
+		WhenScanHappens().
+		WhenScanHappens()
+	ctx.GivenAnActivityDuration("GTA", time.Duration(26)*time.Minute).
+		WhenScanHappens().
+		WhenScanHappens()
+
+	expected := []string{"15m0s left before GTA is stopped", "3m0s left before GTA is stopped"}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("warnings are %q (expected %q)", warnings, expected)
+	}
+	if len(ctx.killedProcesses) != 0 {
+		t.Errorf("no process should be killed yet, killed %q", ctx.killedProcesses)
+	}
+}
+
+func TestActivityIsOnlyKilledAfterItsGracePeriod(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(30)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(30)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].GracePeriod = duration(time.Duration(3) * time.Minute)
+	var warnings []string
+	ctx.controller.WarnAboutKill = func(activity string, rp []runningProcess, reason string) {
+		warnings = append(warnings, reason)
+	}
+
+	ctx.WhenScanHappens().
+		WhenScanHappens()
+	if len(ctx.killedProcesses) != 0 {
+		t.Errorf("no process should be killed during the grace period, killed %q", ctx.killedProcesses)
+	}
+	expected := []string{"Time is up, GTA is stopped in 2m0s", "Time is up, GTA is stopped in 1m0s"}
+	if !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("warnings are %q (expected %q)", warnings, expected)
+	}
+
+	ctx.WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Activity duration above threshold for this day")
+}
+
+func TestGracePeriodDoesNotDelayOtherKills(t *testing.T) {
+	ctx := NewTest(t).
+		GivenTimeIs(time.Date(2024, time.October, 14, 16, 0, 0, 0, time.Local)).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("GTA", "GTA.exe", time.Duration(30)*time.Minute).
+		GivenAnActivityDuration("GTA", time.Duration(30)*time.Minute).
+		GivenARunningProcess("C:\\GTA.exe", 1)
+	ctx.controller.Activities[0].GracePeriod = duration(time.Duration(3) * time.Minute)
+
+	ctx.WhenLockdownStartsFor(time.Hour).
+		WhenScanHappens().
+		ThenProcessIsKilled("GTA", 1, "C:\\GTA.exe", "Lockdown in progress")
+}
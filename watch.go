@@ -0,0 +1,72 @@
+//go:build fsnotify
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// configReloadDelay is how long the configuration file must be left alone
+// before it is reloaded, editors saving it in several steps.
+const configReloadDelay = 500 * time.Millisecond
+
+// watchConfig reloads the configuration as soon as its file changes, until
+// Stop is called, returning whether its file could be watched.
+func (c *dadController) watchConfig() bool {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Fprintln(logOutput, "Failure to watch configuration file : ", err)
+		return false
+	}
+	// the directory is watched, editors replacing the file rather than
+	// writing to it
+	if err := watcher.Add(filepath.Dir(c.configFile)); err != nil {
+		watcher.Close()
+		fmt.Fprintln(logOutput, "Failure to watch configuration file : ", err)
+		return false
+	}
+	fragmentsDir := c.fragmentsDir(filepath.Dir(c.configFile))
+	if fragmentsDir != "" {
+		if err := watcher.Add(fragmentsDir); err != nil {
+			fmt.Fprintln(logOutput, "Failure to watch rule files : ", err)
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+		var reload *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				inFragments := fragmentsDir != "" && filepath.Dir(event.Name) == filepath.Clean(fragmentsDir)
+				name := filepath.Clean(event.Name)
+				if name != filepath.Clean(c.configFile) && name != filepath.Clean(signatureFile(c.configFile)) && !inFragments {
+					continue
+				}
+				if reload != nil {
+					reload.Stop()
+				}
+				reload = time.AfterFunc(configReloadDelay, c.reloadConf)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				fmt.Fprintln(logOutput, "Failure to watch configuration file : ", err)
+			case <-c.stopping:
+				if reload != nil {
+					reload.Stop()
+				}
+				return
+			}
+		}
+	}()
+	fmt.Fprintf(logOutput, "Watching %s for changes\n", c.configFile)
+	return true
+}
@@ -0,0 +1,10 @@
+//go:build !fsnotify
+
+package main
+
+// watchConfig is only able to watch the configuration file in builds with
+// the fsnotify tag, which depend on github.com/fsnotify/fsnotify. The scan
+// loop checks whether it changed before each scan instead.
+func (c *dadController) watchConfig() bool {
+	return false
+}
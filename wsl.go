@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// listWSLProcessesScript lists the pid and executable path of the processes
+// of a WSL distribution, run as root to see the processes of every user.
+const listWSLProcessesScript = `for d in /proc/[0-9]*; do e=$(readlink "$d/exe" 2>/dev/null) && echo "${d#/proc/} $e"; done`
+
+// parseWSLDistributions parses the names output by "wsl.exe -l -q", which
+// is UTF-16 unless WSL_UTF8 is set.
+func parseWSLDistributions(data []byte) []string {
+	if bytes.IndexByte(data, 0) >= 0 && len(data)%2 == 0 {
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+		}
+		data = []byte(string(utf16.Decode(units)))
+	}
+
+	var names []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if name := strings.Trim(line, "\r\x00\ufeff "); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseWSLProcesses parses the lines of pid and executable path output by
+// listWSLProcessesScript in distribution.
+func parseWSLProcesses(distribution string, data []byte) []runningProcess {
+	var processes []runningProcess
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		path := strings.TrimSuffix(fields[1], " (deleted)")
+		processes = append(processes, runningProcess{Pid: pid, Path: path, Distribution: distribution})
+	}
+	return processes
+}
+
+// wslSignal returns the name of the signal sent with kill inside WSL, TERM
+// by default.
+func wslSignal(signal string) string {
+	signal = strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	if signal == "" {
+		return "TERM"
+	}
+	return signal
+}
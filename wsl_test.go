@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+func TestParseWSLDistributions(t *testing.T) {
+	expected := []string{"Ubuntu", "Debian"}
+
+	var utf16Output []byte
+	for _, u := range utf16.Encode([]rune("Ubuntu\r\nDebian\r\n")) {
+		utf16Output = append(utf16Output, byte(u), byte(u>>8))
+	}
+	for _, data := range [][]byte{utf16Output, []byte("Ubuntu\nDebian\n")} {
+		if names := parseWSLDistributions(data); !reflect.DeepEqual(names, expected) {
+			t.Errorf("parsed %q into %q (expected %q)", data, names, expected)
+		}
+	}
+}
+
+func TestParseWSLProcesses(t *testing.T) {
+	data := []byte(`1 /init
+412 /usr/games/mgba-qt
+533 /usr/bin/retroarch (deleted)
+`)
+	expected := []runningProcess{
+		{Pid: 1, Path: "/init", Distribution: "Ubuntu"},
+		{Pid: 412, Path: "/usr/games/mgba-qt", Distribution: "Ubuntu"},
+		{Pid: 533, Path: "/usr/bin/retroarch", Distribution: "Ubuntu"},
+	}
+	if processes := parseWSLProcesses("Ubuntu", data); !reflect.DeepEqual(processes, expected) {
+		t.Errorf("parsed %+v (expected %+v)", processes, expected)
+	}
+}
+
+func TestWSLSignal(t *testing.T) {
+	for signal, expected := range map[string]string{"": "TERM", "SIGKILL": "KILL", "stop": "STOP"} {
+		if s := wslSignal(signal); s != expected {
+			t.Errorf("%q resolved to %q (expected %q)", signal, s, expected)
+		}
+	}
+}
+
+func (ctx *TestContext) givenAWSLProcess(path string, pid int) *TestContext {
+	ctx.runningProcesses = append(ctx.runningProcesses, runningProcess{Path: path, Pid: pid, Distribution: "Ubuntu"})
+	return ctx
+}
+
+func TestWSLProcessSharingThePidOfAWindowsOneIsKilledToo(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Browsing", "firefox", time.Duration(60)*time.Minute).
+		GivenAnActivityDuration("Browsing", time.Duration(2)*time.Hour).
+		GivenARunningProcess("C:\\firefox.exe", 1).
+		givenAWSLProcess("/usr/bin/firefox", 1).
+		WhenScanHappens()
+
+	if expected := []string{"1|C:\\firefox.exe", "1|/usr/bin/firefox"}; !reflect.DeepEqual(ctx.killedProcesses, expected) {
+		t.Errorf("killed %q (expected %q)", ctx.killedProcesses, expected)
+	}
+}
+
+func TestWSLProcessSharingThePidOfAWindowsOneIsNotShared(t *testing.T) {
+	ctx := NewTest(t).
+		GivenADadControllerWithSamplingInterval(time.Duration(1)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Browsing", "firefox\\.exe", time.Duration(60)*time.Minute).
+		GivenAnActivityRuleAllowedEveryTime("Linux", "^/usr/bin/firefox$", time.Duration(60)*time.Minute).
+		GivenARunningProcess("C:\\firefox.exe", 1).
+		givenAWSLProcess("/usr/bin/firefox", 1)
+	ctx.controller.SharedProcessAttribution = sharedProcessSplit
+
+	ctx.WhenScanHappens().
+		ThenActivityExecutionDurationShouldBe("Browsing", time.Duration(1)*time.Minute).
+		ThenActivityExecutionDurationShouldBe("Linux", time.Duration(1)*time.Minute)
+}
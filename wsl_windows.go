@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+)
+
+// wslProvider lists the Windows processes along with the processes of the
+// running WSL distributions, which are invisible to toolhelp snapshots. The
+// distributions of a user are only visible to the controller when it runs
+// in the session of this user.
+type wslProvider struct {
+	toolhelpProvider
+}
+
+func init() {
+	registerProcessProvider("wsl", func() ProcessProvider { return wslProvider{} })
+}
+
+func (p wslProvider) List() ([]runningProcess, error) {
+	processes, err := p.toolhelpProvider.List()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := exec.Command("wsl.exe", "-l", "-q", "--running").Output()
+	if err != nil {
+		// no distribution installed or WSL missing
+		return processes, nil
+	}
+	for _, distribution := range parseWSLDistributions(data) {
+		out, err := exec.Command("wsl.exe", "-d", distribution, "-u", "root", "--", "sh", "-c", listWSLProcessesScript).Output()
+		if err != nil {
+			fmt.Fprintf(logOutput, "Failure to list the processes of WSL distribution %s : %s\n", distribution, err)
+			continue
+		}
+		found := parseWSLProcesses(distribution, out)
+		fmt.Fprintf(logOutput, "Found %d processes in WSL distribution %s\n", len(found), distribution)
+		processes = append(processes, found...)
+	}
+	return processes, nil
+}
+
+// signalWSL sends signal to a process of a WSL distribution.
+func signalWSL(p runningProcess, signal string) error {
+	return exec.Command("wsl.exe", "-d", p.Distribution, "-u", "root", "--", "kill", "-s", signal, strconv.Itoa(p.Pid)).Run()
+}
+
+func (p wslProvider) Kill(rp runningProcess, signal string) error {
+	if rp.Distribution != "" {
+		return signalWSL(rp, wslSignal(signal))
+	}
+	return p.toolhelpProvider.Kill(rp, signal)
+}
+
+func (p wslProvider) Close(rp runningProcess) error {
+	if rp.Distribution != "" {
+		return signalWSL(rp, "TERM")
+	}
+	return p.toolhelpProvider.Close(rp)
+}
+
+func (p wslProvider) Suspend(rp runningProcess) error {
+	if rp.Distribution != "" {
+		return signalWSL(rp, "STOP")
+	}
+	return p.toolhelpProvider.Suspend(rp)
+}
+
+func (p wslProvider) Resume(rp runningProcess) error {
+	if rp.Distribution != "" {
+		return signalWSL(rp, "CONT")
+	}
+	return p.toolhelpProvider.Resume(rp)
+}
+
+func (p wslProvider) Logoff(rp runningProcess) error {
+	if rp.Distribution != "" {
+		return errors.New("the session of a WSL process cannot be told")
+	}
+	return p.toolhelpProvider.Logoff(rp)
+}